@@ -0,0 +1,77 @@
+// Команда создания API ключа, нужна для первого ключа с правом apikeys:manage,
+// остальные удобнее создавать через /admin/api-keys. Ключ выводится один раз, в базе хранится только хеш.
+//
+//	go run ./cmd/apikey -name bootstrap -scopes "apikeys:manage"
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	auditpostgres "medods_test/internal/adapters/audit/postgres"
+	apikeypostgres "medods_test/internal/adapters/auth/apikey/postgres"
+	"medods_test/internal/config"
+	"medods_test/internal/core/audit"
+	"medods_test/internal/core/auth/apikey"
+	"medods_test/pkg/guidgenerator"
+	"medods_test/pkg/unikelongstring"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	name := flag.String("name", "", "человекочитаемое имя ключа")
+	scopes := flag.String("scopes", apikey.ScopeAPIKeysManage, "scope ключа через пробел")
+	ttl := flag.Duration("ttl", 0, "срок жизни ключа, по умолчанию API_KEY_DEFAULT_TTL")
+	flag.Parse()
+
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "-name is required")
+		os.Exit(2)
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	db, err := sql.Open("postgres", fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.Name,
+	))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	service := apikey.NewAPIKeyService(
+		apikeypostgres.NewPostgresAPIKeyRepository(db, &apikeypostgres.Config{Prefix: cfg.Database.Prefix}),
+		guidgenerator.GuidGenerator{},
+		unikelongstring.NewULSHelper(),
+		apikey.Config{DefaultTTL: cfg.APIKey.DefaultTTL},
+		audit.NewAuditService(auditpostgres.NewPostgresAuditRepository(db, &auditpostgres.Config{Prefix: cfg.Database.Prefix}), audit.Config{}, logger),
+		logger,
+	)
+
+	created, err := service.Create(context.Background(), apikey.CreateCommand{
+		Name:      *name,
+		Scopes:    strings.Fields(*scopes),
+		TTL:       *ttl,
+		CreatedBy: "cli",
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to create api key:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("id:", created.Key.ID)
+	fmt.Println("expires_at:", created.Key.ExpiresAt.Format("2006-01-02T15:04:05Z07:00"))
+	fmt.Println("key:", created.Secret)
+}
@@ -0,0 +1,64 @@
+// Команда проверки журнала аудита: пересчитывает цепочку хешей и ищет пропуски seq.
+// Обрезку хвоста по самой цепочке не обнаружить, поэтому last_seq и last_hash из прошлой проверки
+// стоит хранить вне базы и передавать в -anchor-seq и -anchor-hash.
+//
+//	go run ./cmd/auditverify -anchor-seq 1042 -anchor-hash 9f2c...
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	auditpostgres "medods_test/internal/adapters/audit/postgres"
+	"medods_test/internal/config"
+	"medods_test/internal/core/audit"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	anchorSeq := flag.Int64("anchor-seq", 0, "seq последней записи из прошлой проверки")
+	anchorHash := flag.String("anchor-hash", "", "hash последней записи из прошлой проверки")
+	flag.Parse()
+
+	cfg, err := config.New()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	db, err := sql.Open("postgres", fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.Name,
+	))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	service := audit.NewAuditService(
+		auditpostgres.NewPostgresAuditRepository(db, &auditpostgres.Config{Prefix: cfg.Database.Prefix}),
+		audit.Config{},
+		slog.New(slog.NewTextHandler(os.Stderr, nil)),
+	)
+
+	report, err := service.Verify(context.Background(), audit.Anchor{Seq: *anchorSeq, Hash: *anchorHash})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to verify audit log:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("checked:", report.Checked)
+	fmt.Println("last_seq:", report.LastSeq)
+	fmt.Println("last_hash:", report.LastHash)
+	for _, problem := range report.Problems {
+		fmt.Printf("seq %d: %s\n", problem.Seq, problem.Reason)
+	}
+	if len(report.Problems) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,112 @@
+// Команда регистрации OAuth клиента. Секрет выводится один раз, в базе хранится только хеш.
+//
+//	go run ./cmd/oauthclient -id partner -name "Partner app" \
+//	    -redirect-uris https://partner.example/callback -scopes "openid profile email" \
+//	    -grants authorization_code,refresh_token
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	auditpostgres "medods_test/internal/adapters/audit/postgres"
+	oauthpostgres "medods_test/internal/adapters/auth/oauth/postgres"
+	"medods_test/internal/config"
+	"medods_test/internal/core/audit"
+	"medods_test/internal/core/auth/oauth"
+	"medods_test/pkg/unikelongstring"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	id := flag.String("id", "", "client_id")
+	name := flag.String("name", "", "человекочитаемое имя клиента")
+	public := flag.Bool("public", false, "публичный клиент без секрета (SPA, мобильное приложение)")
+	redirectURIs := flag.String("redirect-uris", "", "разрешённые redirect_uri через запятую")
+	scopes := flag.String("scopes", "", "разрешённые scope через пробел")
+	postLogoutRedirectURIs := flag.String("post-logout-redirect-uris", "", "разрешённые post_logout_redirect_uri через запятую")
+	grants := flag.String("grants", oauth.GrantAuthorizationCode+","+oauth.GrantRefreshToken, "разрешённые grant_type через запятую")
+	flag.Parse()
+
+	if *id == "" {
+		fmt.Fprintln(os.Stderr, "-id is required")
+		os.Exit(2)
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	db, err := sql.Open("postgres", fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.Name,
+	))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	auditService := audit.NewAuditService(auditpostgres.NewPostgresAuditRepository(db, &auditpostgres.Config{Prefix: cfg.Database.Prefix}), audit.Config{}, logger)
+
+	conf := &oauthpostgres.Config{Prefix: cfg.Database.Prefix}
+	service := oauth.NewOAuthService(
+		oauthpostgres.NewPostgresClientRepository(db, conf),
+		oauthpostgres.NewPostgresCodeRepository(db, conf),
+		unikelongstring.NewULSHelper(),
+		nil,
+		nil,
+		nil,
+		oauth.Config{},
+		logger,
+	)
+
+	secret, err := service.RegisterClient(context.Background(), oauth.RegisterClientCommand{
+		ID:            *id,
+		Name:          *name,
+		Public:        *public,
+		RedirectURIs:  splitList(*redirectURIs, ","),
+		AllowedScopes: strings.Fields(*scopes),
+		GrantTypes:    splitList(*grants, ","),
+
+		PostLogoutRedirectURIs: splitList(*postLogoutRedirectURIs, ","),
+	})
+	event := audit.Event{
+		Action:  audit.ActionClientRegistered,
+		Actor:   "cli",
+		Subject: "client:" + *id,
+		Outcome: audit.OutcomeSuccess,
+		Reason:  "grants=" + *grants,
+	}
+	if err != nil {
+		event.Outcome, event.Reason = audit.OutcomeFailure, err.Error()
+	}
+	auditService.Record(context.Background(), event)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to register client:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("client_id:", *id)
+	if secret != "" {
+		fmt.Println("client_secret:", secret)
+	}
+}
+
+func splitList(s, sep string) []string {
+	var result []string
+	for _, item := range strings.Split(s, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
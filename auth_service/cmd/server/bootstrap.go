@@ -3,22 +3,54 @@ package main
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"log/slog"
+	audithttp "medods_test/internal/adapters/audit/http"
+	auditpostgres "medods_test/internal/adapters/audit/postgres"
+	apikeyhttp "medods_test/internal/adapters/auth/apikey/http"
+	apikeypostgres "medods_test/internal/adapters/auth/apikey/postgres"
+	federationhttp "medods_test/internal/adapters/auth/federation/http"
+	federationoidc "medods_test/internal/adapters/auth/federation/oidc"
+	federationpostgres "medods_test/internal/adapters/auth/federation/postgres"
+	oauthhttp "medods_test/internal/adapters/auth/oauth/http"
+	oauthpostgres "medods_test/internal/adapters/auth/oauth/postgres"
+	statelessauthgrpc "medods_test/internal/adapters/auth/stateless/grpc"
 	statelessauthhttp "medods_test/internal/adapters/auth/stateless/http"
 	"medods_test/internal/adapters/auth/stateless/jwthelper"
 	"medods_test/internal/adapters/auth/stateless/postgres"
+	"medods_test/internal/adapters/auth/stateless/webauthn"
+	"medods_test/internal/adapters/events"
+	natsbroker "medods_test/internal/adapters/events/nats"
+	"medods_test/internal/adapters/events/outbox"
+	outboxpostgres "medods_test/internal/adapters/events/outbox/postgres"
+	"medods_test/internal/adapters/health"
+	"medods_test/internal/adapters/logging"
+	"medods_test/internal/adapters/metrics"
+	"medods_test/internal/adapters/tracing"
 	userhttp "medods_test/internal/adapters/user/http"
+	userpostgres "medods_test/internal/adapters/user/postgres"
+	"medods_test/internal/adapters/webhook"
 	"medods_test/internal/config"
+	"medods_test/internal/core/audit"
+	"medods_test/internal/core/auth/apikey"
+	"medods_test/internal/core/auth/federation"
+	"medods_test/internal/core/auth/oauth"
 	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/user"
 	"medods_test/pkg/eventbus"
 	"medods_test/pkg/guidgenerator"
+	"medods_test/pkg/lifecycle"
+	"medods_test/pkg/router"
+	"medods_test/pkg/totp"
 	"medods_test/pkg/unikelongstring"
 	"net"
 	"net/http"
 	"os"
-	"strings"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 type App struct {
@@ -29,30 +61,55 @@ type App struct {
 	//инфраструктура
 	_db                        *sql.DB
 	_httpMux                   *http.ServeMux
+	_router                    *router.Router
+	_grpcServer                *grpc.Server
 	_authRepo                  stateless.AuthRepository
+	_mfaRepo                   stateless.MFARepository
+	_passkeyRepo               stateless.PasskeyRepository
 	_authHandler               *statelessauthhttp.Handler
 	_accessTokenAlgoHelper     stateless.AccessTokenAlgoHelper
-	_refreshTokenAlgoHelper    *unikelongstring.ULSHelper
+	_mfaPendingTokenAlgoHelper stateless.MFAPendingTokenAlgoHelper
+	_totpAlgoHelper            *totp.TOTPHelper
+	_refreshTokenAlgoHelper    *metrics.InstrumentedHasher
 	_tokenPairIDGenerator      stateless.StringIdGenerator
 	_authHttpMiddlewareFactory *statelessauthhttp.MiddlewareFactory
+	_signingKey                *jwthelper.SigningKey
+	_idTokenAlgoHelper         oauth.IDTokenAlgoHelper
 	_logger                    *slog.Logger
+	_metrics                   *metrics.Metrics
+	_tracing                   *tracing.Tracing
+	_health                    *health.Health
+	_lifecycle                 *lifecycle.Lifecycle
 
 	//логика
-	_authService *stateless.StatelessAuthService
+	_authService       *stateless.StatelessAuthService
+	_oauthService      *oauth.OAuthService
+	_federationService *federation.FederationService
+	_apiKeyService     *apikey.APIKeyService
+	_auditService      *audit.AuditService
+	_userService       *user.UserService
 
 	//шины событий
-	_userIpChangedBus *eventbus.OneCallbackBus[stateless.UserIPChangedEvent]
+	_sessionEvents *eventbus.Bus[stateless.Event]
+	_outboxRepo    *outboxpostgres.PostgresOutboxRepository
+	_eventBroker   *natsbroker.Broker
 
 	//переменные, определяющие что стартовать
 	startHttp bool
+	startGrpc bool
 
 	//переменная, предотвращающая повторный запуск
 	started bool
+
+	//ошибки сборки компонентов до Run, их возвращает первый хук запуска
+	startupErrs []error
 }
 
-func (a *App) Run(ctx context.Context) {
+// Run запускает компоненты и блокируется до отмены ctx или падения одного из них,
+// после чего останавливает всё в обратном порядке за SHUTDOWN_TIMEOUT
+func (a *App) Run(ctx context.Context) error {
 	if a.started {
-		return
+		return nil
 	}
 	a.started = true
 
@@ -62,52 +119,307 @@ func (a *App) Run(ctx context.Context) {
 
 	a._context = ctx
 
-	a._userIpChangedBus.SetCallBack(func(event stateless.UserIPChangedEvent) {
-		//запрос на эндпойнт
-		if a.config().UserIPChangedWebhookUrl != "" {
-			if err := a.doUserIPChangedWebhook(event); err != nil {
-				a.Logger().Error("failed to call UserIPChangedWebhook", "error", err)
-			} else {
-				a.Logger().Info("UserIPChangedWebhook called successfully", "user_id", event.UserID, "new_ip", event.NewIP)
+	lc := a.lifecycle()
+	a.addStartupHooks(lc)
+	a.addDatabaseHooks(lc)
+	a.addTracingHooks(lc)
+	a.addEventHooks(lc)
+	if a.brokerEnabled() {
+		a.addOutboxHooks(lc)
+	}
+	if a.config().Metrics.Port != 0 {
+		a.addMetricsHooks(lc)
+	}
+	if a.startGrpc {
+		a.addGrpcHooks(lc)
+	}
+	if a.startHttp {
+		a.addHttpHooks(lc)
+	}
+
+	if err := lc.Start(ctx); err != nil {
+		return err
+	}
+	a.Logger().Info("application started")
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		a.Logger().Info("shutting down")
+	case runErr = <-lc.Failed():
+		a.Logger().Error("component failed, shutting down", "error", runErr)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), a.config().Shutdown.Timeout)
+	defer cancel()
+	return errors.Join(runErr, lc.Stop(stopCtx))
+}
+
+// addStartupHooks первым хуком возвращает ошибки конфигурации и сборки компонентов,
+// накопленные ленивыми геттерами, чтобы запуск завершился ошибкой, а не паникой
+func (a *App) addStartupHooks(lc *lifecycle.Lifecycle) {
+	lc.Append(lifecycle.Hook{
+		Name: "config",
+		OnStart: func(ctx context.Context) error {
+			return errors.Join(a.startupErrs...)
+		},
+	})
+}
+
+// startupFailed запоминает ошибку сборки компонента. Геттер продолжает с безопасной заменой,
+// а Run не запустит ни одного компонента
+func (a *App) startupFailed(err error) {
+	a.startupErrs = append(a.startupErrs, err)
+}
+
+func (a *App) addDatabaseHooks(lc *lifecycle.Lifecycle) {
+	lc.Append(lifecycle.Hook{
+		Name: "database",
+		OnStart: func(ctx context.Context) error {
+			return a.db().PingContext(ctx)
+		},
+		OnStop: func(ctx context.Context) error {
+			return a.db().Close()
+		},
+	})
+}
+
+func (a *App) addTracingHooks(lc *lifecycle.Lifecycle) {
+	lc.Append(lifecycle.Hook{
+		Name: "tracing",
+		OnStop: func(ctx context.Context) error {
+			return a.tracing().Shutdown(ctx)
+		},
+	})
+}
+
+// addEventHooks подписчики сами выбирают нужные им события.
+// При остановке шина дожидается очереди, не успевшие за срок события попадают в dead letter
+func (a *App) addEventHooks(lc *lifecycle.Lifecycle) {
+	lc.Append(lifecycle.Hook{
+		Name: "events",
+		OnStart: func(ctx context.Context) error {
+			subscribers := []eventbus.Subscriber[stateless.Event]{
+				a.metrics(),
+				logging.NewEventLog(a.Logger()),
+				webhook.NewUserIPChanged(a.httpClient(), a.config().UserIPChangedWebhookUrl,
+					a.eventFormat(a.config().UserIPChangedWebhookFormat), a.config().Events.Source, a.metrics(), a.Logger()),
 			}
-		} else {
-			a.Logger().Warn("UserIPChangedWebhookUrl is not configured, skipping webhook call")
-		}
+			for _, subscriber := range subscribers {
+				subscriber.Subscribe(a.sessionEvents())
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return a.sessionEvents().Close(ctx)
+		},
+	})
+}
 
+// addOutboxHooks при запуске подключается к брокеру и запускает relay. При остановке relay дожидается
+// текущей пачки, неотправленное остаётся в outbox до следующего запуска
+func (a *App) addOutboxHooks(lc *lifecycle.Lifecycle) {
+	var stopRelay context.CancelFunc
+	relayDone := make(chan struct{})
+	lc.Append(lifecycle.Hook{
+		Name: "outbox",
+		OnStart: func(ctx context.Context) error {
+			cfg := a.config().Events
+			broker, err := natsbroker.Connect(ctx, natsbroker.Config{
+				URL:             cfg.NatsURL,
+				Stream:          cfg.NatsStream,
+				Subjects:        []string{outbox.Subject(cfg.SubjectPrefix, ">")},
+				DuplicateWindow: 2 * time.Minute,
+			})
+			if err != nil {
+				return err
+			}
+			a._eventBroker = broker
+
+			relay := outbox.NewRelay(a.outboxRepository(), broker, outbox.RelayConfig{
+				PollInterval: cfg.PollInterval,
+				BatchSize:    cfg.BatchSize,
+				RetryBase:    cfg.RetryBase,
+				RetryMax:     cfg.RetryMax,
+				Retention:    cfg.Retention,
+			}, a.Logger())
+			var relayCtx context.Context
+			relayCtx, stopRelay = context.WithCancel(context.WithoutCancel(ctx))
+			go func() {
+				defer close(relayDone)
+				relay.Run(relayCtx)
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopRelay()
+			select {
+			case <-relayDone:
+			case <-ctx.Done():
+			}
+			return a._eventBroker.Close()
+		},
 	})
-	if a.startHttp {
-		server := &http.Server{
-			Addr:    ":" + fmt.Sprint(a.config().Port),
-			Handler: a._httpMux,
-			BaseContext: func(net.Listener) context.Context {
-				return ctx
-			},
-		}
+}
 
-		go func() {
-			<-ctx.Done()
-			if err := server.Shutdown(context.Background()); err != nil {
-				a.Logger().Error("failed to shutdown HTTP server", "error", err)
+// addGrpcHooks регистрируется до HTTP, поэтому останавливается после него, когда /readyz уже отвечает 503.
+// Текущие вызовы дожидаются завершения, пока не истёк срок остановки
+func (a *App) addGrpcHooks(lc *lifecycle.Lifecycle) {
+	lc.Append(lifecycle.Hook{
+		Name: "grpc",
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", ":"+fmt.Sprint(a.config().GRPC.Port))
+			if err != nil {
+				return err
 			}
-		}()
+			go func() {
+				if err := a._grpcServer.Serve(listener); err != nil {
+					lc.Fail(fmt.Errorf("grpc server: %w", err))
+				}
+			}()
+			a.Logger().Info("grpc server started", "addr", listener.Addr().String())
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				a._grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				a._grpcServer.Stop()
+				return ctx.Err()
+			}
+		},
+	})
+}
 
-		go func() {
-			if err := server.ListenAndServe(); err != http.ErrServerClosed {
-				panic(err)
+// addMetricsHooks отдаёт /metrics на METRICS_PORT. Сервер останавливается после API,
+// чтобы последний опрос застал итоговые значения
+func (a *App) addMetricsHooks(lc *lifecycle.Lifecycle) {
+	a.metrics().RegisterSessionGauge(a.authRepository(), 5*time.Second)
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", a.metrics().Handler())
+	server := &http.Server{
+		Addr:    ":" + fmt.Sprint(a.config().Metrics.Port),
+		Handler: mux,
+	}
+	lc.Append(lifecycle.Hook{
+		Name: "metrics",
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
 			}
-		}()
+			go func() {
+				if err := server.Serve(listener); err != http.ErrServerClosed {
+					lc.Fail(fmt.Errorf("metrics server: %w", err))
+				}
+			}()
+			a.Logger().Info("metrics server started", "addr", listener.Addr().String())
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return server.Shutdown(ctx)
+		},
+	})
+}
+
+// addHttpHooks при остановке сначала переводит /readyz в 503 и ждёт SHUTDOWN_DRAIN_DELAY,
+// затем дожидается текущих запросов
+func (a *App) addHttpHooks(lc *lifecycle.Lifecycle) {
+	server := &http.Server{
+		Addr:    ":" + fmt.Sprint(a.config().Port),
+		Handler: logging.RequestID(a.tracing().InstrumentMux(a._httpMux, a.metrics().InstrumentMux(a._httpMux))),
+		// контекст сигнала отменяется в начале остановки, а запросы должны дорабатывать,
+		// пока их не ограничит срок server.Shutdown
+		BaseContext: func(net.Listener) context.Context {
+			return context.WithoutCancel(a._context)
+		},
 	}
+	lc.Append(lifecycle.Hook{
+		Name: "http",
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := server.Serve(listener); err != http.ErrServerClosed {
+					lc.Fail(fmt.Errorf("http server: %w", err))
+				}
+			}()
+			a.Logger().Info("http server started", "addr", listener.Addr().String())
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			a.health().SetDraining()
+			select {
+			case <-time.After(a.config().Shutdown.DrainDelay):
+			case <-ctx.Done():
+			}
+			return server.Shutdown(ctx)
+		},
+	})
 }
 
 func (a *App) AddHttp() error {
 	service := a.authService()
 
-	mux := a.httpServer()
+	mux := a.router()
+
+	a.health().RegisterRoutes(mux)
 
 	authHandler := statelessauthhttp.NewHandler(*service, *a.authMiddleware(), a.Logger())
 
 	authHandler.RegisterRoutes(mux)
 
+	passkeyHandler, err := webauthn.NewHandler(
+		*service,
+		*a.authMiddleware(),
+		postgres.NewPostgresWebAuthnCeremonyRepository(a.db(), &postgres.Config{Prefix: a.config().Database.Prefix}),
+		*a.tokenPairIDGenerator(),
+		webauthn.Config{
+			RPID:          a.config().WebAuthn.RPID,
+			RPDisplayName: a.config().WebAuthn.RPDisplayName,
+			RPOrigins:     a.config().WebAuthn.RPOrigins,
+			CeremonyTTL:   a.config().WebAuthn.CeremonyTTL,
+		},
+		a.Logger())
+	if err != nil {
+		return fmt.Errorf("failed to create passkey handler: %w", err)
+	}
+	passkeyHandler.RegisterRoutes(mux)
+
+	oauthHandler := oauthhttp.NewHandler(
+		a.oauthService(),
+		*a.authMiddleware(),
+		oauthhttp.OIDCConfig{
+			Issuer: a.config().OIDC.Issuer,
+			JWKS:   a.signingKey().JWKS(),
+		},
+		a.Logger())
+	oauthHandler.RegisterRoutes(mux)
+
+	federationService, err := a.federationService()
+	if err != nil {
+		return fmt.Errorf("failed to create federation service: %w", err)
+	}
+	federationHandler := federationhttp.NewHandler(federationService, *a.authMiddleware(), federationhttp.Config{
+		RedirectURL: a.config().Federation.RedirectURL,
+		StateTTL:    a.config().Federation.StateTTL,
+	}, a.Logger())
+	federationHandler.RegisterRoutes(mux)
+
+	apiKeyHandler := apikeyhttp.NewHandler(a.apiKeyService(), *a.authMiddleware(), a.Logger())
+	apiKeyHandler.RegisterRoutes(mux)
+
+	auditHandler := audithttp.NewHandler(a.auditService(), *a.authMiddleware(), a.Logger())
+	auditHandler.RegisterRoutes(mux)
+
 	userHandler := userhttp.NewHandler(*a.authMiddleware(), a.Logger())
 	userHandler.RegisterRoutes(mux)
 
@@ -116,6 +428,19 @@ func (a *App) AddHttp() error {
 	return nil
 }
 
+// AddGrpc настраивает gRPC сервер AuthService с той же проверкой токенов, что и в HTTP
+func (a *App) AddGrpc() error {
+	a._grpcServer = grpc.NewServer(grpc.ChainUnaryInterceptor(
+		logging.UnaryRequestID,
+		statelessauthgrpc.UnaryAuthInterceptor(a.authMiddleware(), a.Logger(), statelessauthgrpc.PublicMethods...),
+	))
+	statelessauthgrpc.NewServer(a.authService(), a.Logger()).Register(a._grpcServer)
+
+	a.startGrpc = true
+
+	return nil
+}
+
 func (a *App) httpServer() *http.ServeMux {
 	if a._httpMux == nil {
 		a._httpMux = http.NewServeMux()
@@ -123,16 +448,51 @@ func (a *App) httpServer() *http.ServeMux {
 	return a._httpMux
 }
 
+// router маршруты API регистрируются под /v1 и, пока включено, по старым путям
+func (a *App) router() *router.Router {
+	if a._router == nil {
+		a._router = router.New(a.httpServer(), router.Config{
+			Prefix:       "/v1",
+			Legacy:       a.config().HTTP.LegacyRoutes,
+			DeprecatedAt: a.config().HTTP.LegacyDeprecatedAt,
+			Sunset:       a.config().HTTP.LegacySunset,
+		})
+	}
+	return a._router
+}
+
 func (a *App) accessTokenAlgoHelper() *stateless.AccessTokenAlgoHelper {
 	if a._accessTokenAlgoHelper == nil {
-		a._accessTokenAlgoHelper = jwthelper.NewJWTAccessTokenHelper(a.config().JWT.AccessSecret)
+		cfg := a.config().JWT
+		// допустимые значения JWT_ACCESS_ALG проверяет config.Load
+		helper := jwthelper.NewJWTAccessTokenHelper(cfg.AccessSecret, cfg.AccessTTL)
+		if cfg.AccessAlg == "RS256" {
+			helper = jwthelper.NewRSAAccessTokenHelper(a.signingKey(), cfg.AccessTTL)
+		}
+		helper.Issuer = a.config().OIDC.Issuer
+		helper.Audience = cfg.AccessAudience
+		a._accessTokenAlgoHelper = helper
 	}
 	return &a._accessTokenAlgoHelper
 }
 
-func (a *App) refreshTokenAlgoHelper() *unikelongstring.ULSHelper {
+func (a *App) mfaPendingTokenAlgoHelper() stateless.MFAPendingTokenAlgoHelper {
+	if a._mfaPendingTokenAlgoHelper == nil {
+		a._mfaPendingTokenAlgoHelper = jwthelper.NewJWTMFAPendingTokenHelper(a.config().JWT.AccessSecret, a.config().MFA.PendingTTL)
+	}
+	return a._mfaPendingTokenAlgoHelper
+}
+
+func (a *App) totpAlgoHelper() *totp.TOTPHelper {
+	if a._totpAlgoHelper == nil {
+		a._totpAlgoHelper = totp.NewTOTPHelper(a.config().MFA.Issuer)
+	}
+	return a._totpAlgoHelper
+}
+
+func (a *App) refreshTokenAlgoHelper() *metrics.InstrumentedHasher {
 	if a._refreshTokenAlgoHelper == nil {
-		a._refreshTokenAlgoHelper = unikelongstring.NewULSHelper()
+		a._refreshTokenAlgoHelper = metrics.NewInstrumentedHasher(unikelongstring.NewULSHelper(), a.metrics())
 	}
 	return a._refreshTokenAlgoHelper
 }
@@ -147,29 +507,225 @@ func (a *App) tokenPairIDGenerator() *stateless.StringIdGenerator {
 func (a *App) authService() *stateless.StatelessAuthService {
 	if a._authService == nil {
 		a._authRepo = a.authRepository()
-		a._authService = stateless.NewStatelessAuthService(a._authRepo, *a.accessTokenAlgoHelper(), a.refreshTokenAlgoHelper(), *a.tokenPairIDGenerator(), a.userIPChangedBus(), a.Logger())
+		a._authService = stateless.NewStatelessAuthService(
+			a._authRepo,
+			a.mfaRepository(),
+			a.passkeyRepository(),
+			*a.accessTokenAlgoHelper(),
+			a.mfaPendingTokenAlgoHelper(),
+			a.refreshTokenAlgoHelper(),
+			a.totpAlgoHelper(),
+			*a.tokenPairIDGenerator(),
+			a.eventPublisher(),
+			a.metrics(),
+			a.tracing(),
+			a.auditService(),
+			a.Logger())
 	}
 	return a._authService
 }
 
+func (a *App) oauthService() *oauth.OAuthService {
+	if a._oauthService == nil {
+		conf := &oauthpostgres.Config{Prefix: a.config().Database.Prefix}
+		a._oauthService = oauth.NewOAuthService(
+			oauthpostgres.NewPostgresClientRepository(a.db(), conf),
+			oauthpostgres.NewPostgresCodeRepository(a.db(), conf),
+			a.refreshTokenAlgoHelper(),
+			a.authService(),
+			a.idTokenAlgoHelper(),
+			a.userService(),
+			oauth.Config{
+				CodeTTL:        a.config().OAuth.CodeTTL,
+				AccessTokenTTL: a.config().JWT.AccessTTL,
+			},
+			a.Logger())
+	}
+	return a._oauthService
+}
+
+func (a *App) federationService() (*federation.FederationService, error) {
+	if a._federationService == nil {
+		configs, err := federationoidc.LoadConfigs(a.config().Federation.ProvidersFile)
+		if err != nil {
+			return nil, err
+		}
+		providers := make(map[string]federation.Provider, len(configs))
+		for _, c := range configs {
+			providers[c.Name] = federationoidc.NewProvider(c, a.config().Federation.RedirectURL)
+		}
+
+		conf := &federationpostgres.Config{Prefix: a.config().Database.Prefix}
+		a._federationService = federation.NewFederationService(
+			providers,
+			federationpostgres.NewPostgresStateRepository(a.db(), conf),
+			federationpostgres.NewPostgresIdentityRepository(a.db(), conf),
+			a.userService(),
+			a.authService(),
+			a.refreshTokenAlgoHelper(),
+			federation.Config{StateTTL: a.config().Federation.StateTTL},
+			a.Logger())
+	}
+	return a._federationService, nil
+}
+
+func (a *App) apiKeyService() *apikey.APIKeyService {
+	if a._apiKeyService == nil {
+		a._apiKeyService = apikey.NewAPIKeyService(
+			apikeypostgres.NewPostgresAPIKeyRepository(a.db(), &apikeypostgres.Config{Prefix: a.config().Database.Prefix}),
+			*a.tokenPairIDGenerator(),
+			a.refreshTokenAlgoHelper(),
+			apikey.Config{
+				DefaultTTL:         a.config().APIKey.DefaultTTL,
+				LastUsedResolution: a.config().APIKey.LastUsedResolution,
+			},
+			a.auditService(),
+			a.Logger())
+	}
+	return a._apiKeyService
+}
+
+func (a *App) auditService() *audit.AuditService {
+	if a._auditService == nil {
+		a._auditService = audit.NewAuditService(
+			auditpostgres.NewPostgresAuditRepository(a.db(), &auditpostgres.Config{Prefix: a.config().Database.Prefix}),
+			audit.Config{
+				DefaultLimit: a.config().Audit.DefaultLimit,
+				MaxLimit:     a.config().Audit.MaxLimit,
+			},
+			a.Logger())
+	}
+	return a._auditService
+}
+
+func (a *App) userService() *user.UserService {
+	if a._userService == nil {
+		a._userService = user.NewUserService(
+			userpostgres.NewPostgresUserRepository(a.db(), &userpostgres.Config{Prefix: a.config().Database.Prefix}),
+			*a.tokenPairIDGenerator(),
+			a.Logger())
+	}
+	return a._userService
+}
+
+func (a *App) signingKey() *jwthelper.SigningKey {
+	if a._signingKey == nil {
+		key, generated, err := jwthelper.LoadSigningKey(a.config().OIDC.SigningKeyFile)
+		if err != nil {
+			a.startupFailed(fmt.Errorf("failed to load OIDC signing key: %w", err))
+			key, generated, _ = jwthelper.LoadSigningKey("")
+		}
+		if generated {
+			a.Logger().Warn("OIDC_SIGNING_KEY_FILE is not configured, using ephemeral signing key", "kid", key.KeyID)
+		}
+		a._signingKey = key
+	}
+	return a._signingKey
+}
+
+func (a *App) idTokenAlgoHelper() oauth.IDTokenAlgoHelper {
+	if a._idTokenAlgoHelper == nil {
+		a._idTokenAlgoHelper = jwthelper.NewJWTIDTokenHelper(a.signingKey(), a.config().OIDC.Issuer, a.config().OIDC.IDTokenTTL)
+	}
+	return a._idTokenAlgoHelper
+}
+
 func (a *App) authRepository() stateless.AuthRepository {
 	if a._authRepo == nil {
-		a._authRepo = postgres.NewPostgresAuthRepository(a.db(), a.refreshTokenAlgoHelper(), &postgres.Config{Prefix: a.config().Database.Prefix})
+		a._authRepo = tracing.InstrumentAuthRepository(
+			metrics.InstrumentAuthRepository(
+				postgres.NewPostgresAuthRepository(a.db(), a.refreshTokenAlgoHelper(), &postgres.Config{Prefix: a.config().Database.Prefix}),
+				a.metrics()),
+			a.tracing())
 	}
 	return a._authRepo
 }
 
-func (a *App) userIPChangedBus() *eventbus.OneCallbackBus[stateless.UserIPChangedEvent] {
-	if a._userIpChangedBus == nil {
-		a._userIpChangedBus = &eventbus.OneCallbackBus[stateless.UserIPChangedEvent]{}
+func (a *App) mfaRepository() stateless.MFARepository {
+	if a._mfaRepo == nil {
+		a._mfaRepo = postgres.NewPostgresMFARepository(a.db(), a.refreshTokenAlgoHelper(), &postgres.Config{Prefix: a.config().Database.Prefix})
+	}
+	return a._mfaRepo
+}
+
+func (a *App) passkeyRepository() stateless.PasskeyRepository {
+	if a._passkeyRepo == nil {
+		a._passkeyRepo = postgres.NewPostgresPasskeyRepository(a.db(), &postgres.Config{Prefix: a.config().Database.Prefix})
+	}
+	return a._passkeyRepo
+}
+
+func (a *App) sessionEvents() *eventbus.Bus[stateless.Event] {
+	if a._sessionEvents == nil {
+		const busName = "session_events"
+		cfg := a.config().EventBus
+		a._sessionEvents = eventbus.NewBus(eventbus.Options[stateless.Event]{
+			Workers:        cfg.Workers,
+			QueueSize:      cfg.QueueSize,
+			PublishTimeout: cfg.PublishTimeout,
+			MaxAttempts:    cfg.MaxAttempts,
+			RetryBase:      cfg.RetryBase,
+			RetryMax:       cfg.RetryMax,
+			OnDrop: func(subscriber string, event stateless.Event) {
+				a.metrics().EventDropped(busName)
+				a.Logger().Warn("event dropped", "bus", busName, "subscriber", subscriber,
+					"event_type", event.EventType(), "event_id", event.Metadata().ID)
+			},
+			OnDeadLetter: func(letter eventbus.DeadLetter[stateless.Event]) {
+				a.metrics().EventFailed(busName, letter.Subscriber)
+				a.Logger().Error("event handling failed", "bus", busName, "subscriber", letter.Subscriber,
+					"attempts", letter.Attempts, "error", letter.Err,
+					"event_type", letter.Event.EventType(), "event_id", letter.Event.Metadata().ID)
+			},
+		})
+	}
+	return a._sessionEvents
+}
+
+// eventPublisher при включённом брокере событие, кроме шины, записывается в outbox
+func (a *App) eventPublisher() stateless.EventPublisher {
+	if !a.brokerEnabled() {
+		return a.sessionEvents()
+	}
+	return stateless.EventPublishers{
+		a.sessionEvents(),
+		outbox.NewWriter(
+			a.outboxRepository(),
+			events.NewEncoder(a.eventFormat(a.config().Events.Format), a.config().Events.Source),
+			a.config().Events.SubjectPrefix,
+			2*time.Second,
+			a.Logger()),
+	}
+}
+
+// eventFormat значения форматов проверяет config.Load, ошибка здесь означает рассинхрон со списком в events
+func (a *App) eventFormat(s string) events.Format {
+	format, err := events.ParseFormat(s)
+	if err != nil {
+		a.startupFailed(err)
+		return events.FormatJSON
+	}
+	return format
+}
+
+// brokerEnabled допустимые значения EVENTS_BROKER проверяет config.Load
+func (a *App) brokerEnabled() bool {
+	return a.config().Events.Broker == "nats"
+}
+
+func (a *App) outboxRepository() *outboxpostgres.PostgresOutboxRepository {
+	if a._outboxRepo == nil {
+		a._outboxRepo = outboxpostgres.NewPostgresOutboxRepository(a.db(), &outboxpostgres.Config{Prefix: a.config().Database.Prefix})
 	}
-	return a._userIpChangedBus
+	return a._outboxRepo
 }
 
 func (a *App) config() *config.Config {
 	if a._config == nil {
 		a._config = &config.Config{}
-		a._config.Load()
+		if err := a._config.Load(); err != nil {
+			a.startupFailed(err)
+		}
 	}
 	return a._config
 }
@@ -188,71 +744,130 @@ func (a *App) db() *sql.DB {
 			)
 		// Можно добавить другие драйверы (mysql, sqlite и т.д.)
 		default:
-			panic("unsupported database type: " + cfg.DBType)
+			a._db = sql.OpenDB(failedConnector{fmt.Errorf("unsupported database type: %s", cfg.DBType)})
+			return a._db
 		}
 		db, err := sql.Open(driver, dsn)
 		if err != nil {
-			panic(err)
-		}
-		if err := db.Ping(); err != nil {
-			panic(err)
+			db = sql.OpenDB(failedConnector{err})
 		}
 		a._db = db
 	}
 	return a._db
 }
 
+// failedConnector возвращает ошибку настройки базы при подключении, её отдаёт хук database
+type failedConnector struct {
+	err error
+}
+
+func (c failedConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, c.err
+}
+
+func (c failedConnector) Driver() driver.Driver {
+	return nil
+}
+
 func (a *App) authMiddleware() *statelessauthhttp.MiddlewareFactory {
 	if a._authHttpMiddlewareFactory == nil {
-		a._authHttpMiddlewareFactory = statelessauthhttp.NewMiddlewareFactory(*a.accessTokenAlgoHelper())
+		a._authHttpMiddlewareFactory = statelessauthhttp.NewMiddlewareFactory(*a.accessTokenAlgoHelper(), a.apiKeyService(), a.config().MFA.StepUpMaxAge)
 	}
 	return a._authHttpMiddlewareFactory
 }
 
-func (a *App) doUserIPChangedWebhook(event stateless.UserIPChangedEvent) error {
-	if a.config().UserIPChangedWebhookUrl == "" {
-		return fmt.Errorf("UserIPChangedWebhookUrl is not configured")
+func (a *App) httpClient() *http.Client {
+	return &http.Client{
+		Transport: a.tracing().Transport(&http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		}),
 	}
+}
 
-	client := a.httpClient()
-
-	body := fmt.Sprintf(`{"user_id": "%s", "new_ip": "%s"}`, event.UserID, event.NewIP)
-
-	req, err := http.NewRequestWithContext(a._context, http.MethodPost, a.config().UserIPChangedWebhookUrl, strings.NewReader(body))
-	if err != nil {
-		return nil
+func (a *App) lifecycle() *lifecycle.Lifecycle {
+	if a._lifecycle == nil {
+		a._lifecycle = lifecycle.New()
 	}
+	return a._lifecycle
+}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil
+// schemaVersion версия схемы из test_database.sql, под которую собран сервис
+const schemaVersion = 3
+
+func (a *App) health() *health.Health {
+	if a._health == nil {
+		a._health = health.New(a.config().Health.CheckTimeout)
+		a._health.Add("database", health.DBPing(a.db()))
+		a._health.Add("schema_version", health.SchemaVersion(a.db(), a.config().Database.Prefix, schemaVersion))
+		a._health.Add("signing_keys", func(ctx context.Context) error {
+			if a.config().JWT.AccessAlg == "HS512" && a.config().JWT.AccessSecret == "" {
+				return fmt.Errorf("JWT access secret is not configured")
+			}
+			if a._signingKey == nil || a._signingKey.Key == nil {
+				return fmt.Errorf("OIDC signing key is not loaded")
+			}
+			return nil
+		})
+		a._health.Add("event_bus", func(ctx context.Context) error {
+			if a.sessionEvents().Closed() {
+				return fmt.Errorf("session event bus is closed")
+			}
+			if stats := a.sessionEvents().Stats(); stats.QueueDepth >= a.config().EventBus.QueueSize {
+				return fmt.Errorf("session event queue is full (%d events)", stats.QueueDepth)
+			}
+			return nil
+		})
+		if a.brokerEnabled() {
+			a._health.Add("event_broker", func(ctx context.Context) error {
+				if a._eventBroker == nil {
+					return fmt.Errorf("event broker is not connected")
+				}
+				return a._eventBroker.Ping()
+			})
+		}
 	}
-	defer resp.Body.Close()
+	return a._health
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil
+func (a *App) metrics() *metrics.Metrics {
+	if a._metrics == nil {
+		a._metrics = metrics.New()
 	}
-
-	return nil
+	return a._metrics
 }
 
-func (a *App) httpClient() *http.Client {
-	return &http.Client{
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			TLSHandshakeTimeout: 10 * time.Second,
-		},
+func (a *App) tracing() *tracing.Tracing {
+	if a._tracing == nil {
+		t, err := tracing.New(context.Background(), tracing.Config{
+			Exporter:    a.config().Tracing.Exporter,
+			ServiceName: a.config().Tracing.ServiceName,
+			SampleRatio: a.config().Tracing.SampleRatio,
+		})
+		if err != nil {
+			a.startupFailed(err)
+			t, _ = tracing.New(context.Background(), tracing.Config{Exporter: tracing.ExporterNone})
+		}
+		a._tracing = t
 	}
+	return a._tracing
 }
 
 func (a *App) Logger() *slog.Logger {
 	if a._logger == nil {
-		a._logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+		logger, err := logging.New(logging.Config{
+			Format: a.config().Log.Format,
+			Level:  a.config().Log.Level,
+			Debug:  a.config().Debug,
+		}, os.Stdout)
+		if err != nil {
+			a.startupFailed(err)
+			logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+		}
+		a._logger = logger
 	}
 	return a._logger
 }
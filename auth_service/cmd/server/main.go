@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"os"
 	"os/signal"
 	"syscall"
 )
@@ -13,15 +14,25 @@ import (
 // @securityDefinitions.apikey Bearer
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey ApiKey
+// @in header
+// @name X-API-Key
 func main() {
 	rootCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 	
 	app := App{}
-	app.AddHttp();
-	
-	app.Run(rootCtx)
-
-	<-rootCtx.Done()
+	if err := app.AddHttp(); err != nil {
+		app.Logger().Error("failed to configure application", "error", err)
+		os.Exit(1)
+	}
+	if err := app.AddGrpc(); err != nil {
+		app.Logger().Error("failed to configure application", "error", err)
+		os.Exit(1)
+	}
 	
-}
\ No newline at end of file
+	if err := app.Run(rootCtx); err != nil {
+		app.Logger().Error("application stopped with error", "error", err)
+		os.Exit(1)
+	}
+}
@@ -15,91 +15,144 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/logout": {
-            "post": {
-                "security": [
-                    {
-                        "Bearer": []
-                    }
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Публичные ключи для проверки ID токенов",
+                "produces": [
+                    "application/json"
                 ],
-                "description": "Удаляет сессию refresh токена",
-                "consumes": [
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "JWKS",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/jwthelper.JWKSet"
+                        }
+                    }
+                }
+            }
+        },
+        "/.well-known/openid-configuration": {
+            "get": {
+                "description": "Метаданные OpenID провайдера",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "oidc"
                 ],
-                "summary": "Выход пользователя",
-                "parameters": [
-                    {
-                        "description": "Refresh токен",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                "summary": "OIDC discovery",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/http.LogoutRequest"
+                            "$ref": "#/definitions/http.DiscoveryResponse"
                         }
                     }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Отвечает 200, пока процесс жив. Зависимости не проверяет",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
                 ],
+                "summary": "Liveness проба",
                 "responses": {
                     "200": {
-                        "description": "ok",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/health.Response"
                         }
-                    },
-                    "400": {
-                        "description": "bad request",
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет базу, версию схемы, ключи подписи и отправку вебхуков.\nВо время остановки сервера отвечает 503 с проверкой shutdown",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness проба",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/health.Response"
                         }
                     },
-                    "500": {
-                        "description": "internal server error",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/health.Response"
                         }
                     }
                 }
             }
         },
-        "/auth/me": {
+        "/v1/admin/api-keys": {
             "get": {
                 "security": [
                     {
                         "Bearer": []
+                    },
+                    {
+                        "ApiKey": []
                     }
                 ],
-                "description": "Возвращает ID пользователя по access токену\nПозжже будет заменена взятием других данных пользователя",
+                "description": "Возвращает все ключи без секретов",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "api-keys"
                 ],
-                "summary": "Получение текущего пользователя",
+                "summary": "Список API ключей",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/http.KeyResponse"
                             }
                         }
                     },
                     "401": {
                         "description": "unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/httperror.ErrorResponse"
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "insufficient_scope",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/auth/refresh": {
+            },
             "post": {
-                "description": "Обновляет токены по старой паре",
+                "security": [
+                    {
+                        "Bearer": []
+                    },
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Полный ключ показывается в ответе один раз. Ключу нельзя выдать scope, которых нет у вызывающего",
                 "consumes": [
                     "application/json"
                 ],
@@ -107,117 +160,2049 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "api-keys"
                 ],
-                "summary": "Обновление пары токенов",
+                "summary": "Создание API ключа",
                 "parameters": [
                     {
-                        "description": "Пара токенов",
+                        "description": "Параметры ключа",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/http.RefreshRequest"
+                            "$ref": "#/definitions/http.CreateKeyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/stateless.TokenPair"
+                            "$ref": "#/definitions/http.CreatedKeyResponse"
                         }
                     },
                     "400": {
-                        "description": "bad request",
+                        "description": "validation_failed или bad_request",
+                        "schema": {
+                            "$ref": "#/definitions/httpjson.ValidationProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/httperror.ErrorResponse"
+                            "$ref": "#/definitions/httperror.Problem"
                         }
                     },
                     "403": {
-                        "description": "Forbidden",
+                        "description": "scope_not_granted",
                         "schema": {
-                            "$ref": "#/definitions/httperror.ErrorResponse"
+                            "$ref": "#/definitions/httperror.Problem"
                         }
                     }
                 }
             }
         },
-        "/auth/token": {
-            "post": {
-                "description": "Возвращает пару токенов по user_id,\nв дальнейшем будет заменена настоящим алгоритмом входа",
-                "consumes": [
-                    "application/json"
+        "/v1/admin/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    },
+                    {
+                        "ApiKey": []
+                    }
+                ],
+                "description": "Отозванный ключ сразу перестаёт приниматься, запись остаётся в списке",
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Отзыв API ключа",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID ключа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "revoked",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "api_key_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/audit": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    },
+                    {
+                        "ApiKey": []
+                    }
                 ],
+                "description": "Возвращает записи от новых к старым. Страницы листаются параметром before из next_before предыдущего ответа",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "audit"
                 ],
-                "summary": "Получение access и refresh токенов",
+                "summary": "Журнал аудита",
                 "parameters": [
                     {
-                        "description": "ID пользователя",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/http.HandleTokenRequest"
-                        }
+                        "enum": [
+                            "login",
+                            "refresh",
+                            "ua_mismatch_logout",
+                            "ip_changed",
+                            "logout",
+                            "admin.api_key_created",
+                            "admin.api_key_revoked",
+                            "admin.oauth_client_registered"
+                        ],
+                        "type": "string",
+                        "description": "Действие",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Кто выполнил действие",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Над кем выполнено действие",
+                        "name": "subject",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "success",
+                            "failure",
+                            "pending"
+                        ],
+                        "type": "string",
+                        "description": "Результат",
+                        "name": "outcome",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Не раньше, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Раньше, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Только записи с seq меньше указанного",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/stateless.TokenPair"
+                            "$ref": "#/definitions/http.QueryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized",
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
-                            "$ref": "#/definitions/httperror.ErrorResponse"
+                            "$ref": "#/definitions/httperror.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "http.HandleTokenRequest": {
-            "type": "object",
-            "properties": {
-                "user_id": {
-                    "type": "string"
-                }
-            }
         },
-        "http.LogoutRequest": {
-            "type": "object",
-            "properties": {
-                "refresh_token": {
-                    "type": "string"
+        "/v1/auth/federation/callback": {
+            "get": {
+                "description": "Обменивает код у провайдера и выдаёт пару токенов. Незнакомый внешний аккаунт получает нового пользователя.\nЕсли вход начинался через /auth/federation/link, аккаунт привязывается и токены не выдаются",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "federation"
+                ],
+                "summary": "Callback внешнего OIDC провайдера",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "state",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Код авторизации провайдера",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stateless.TokenPair"
+                        }
+                    },
+                    "201": {
+                        "description": "аккаунт привязан",
+                        "schema": {
+                            "$ref": "#/definitions/http.LinkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "login_state_invalid: state неизвестен, истёк или выдан другому браузеру",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "требуется второй фактор",
+                        "schema": {
+                            "$ref": "#/definitions/http.MFARequiredResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "identity_already_linked",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "502": {
+                        "description": "upstream_login_failed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
                 }
             }
         },
-        "http.RefreshRequest": {
-            "type": "object",
-            "properties": {
-                "access_token": {
-                    "type": "string"
-                },
-                "refresh_token": {
-                    "type": "string"
+        "/v1/auth/federation/identities": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "federation"
+                ],
+                "summary": "Привязанные внешние аккаунты",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/http.IdentityResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
                 }
             }
         },
-        "httperror.ErrorResponse": {
+        "/v1/auth/federation/link": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Как /auth/federation/login, но после callback внешний аккаунт привязывается к текущему пользователю",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "federation"
+                ],
+                "summary": "Привязка внешнего аккаунта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя провайдера из FEDERATION_PROVIDERS_FILE",
+                        "name": "provider",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.RedirectResponse"
+                        }
+                    },
+                    "302": {
+                        "description": "redirect",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "provider_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/federation/login": {
+            "get": {
+                "description": "Перенаправляет на страницу входа провайдера (state, nonce и PKCE S256 генерируются сервисом).\nПри Accept: application/json адрес возвращается в теле. Ответ ставит HttpOnly cookie federation_binding,\nбез которой callback отклоняется",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "federation"
+                ],
+                "summary": "Вход через внешний OIDC провайдер",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя провайдера из FEDERATION_PROVIDERS_FILE",
+                        "name": "provider",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.RedirectResponse"
+                        }
+                    },
+                    "302": {
+                        "description": "redirect",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "provider_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Удаляет сессию refresh токена",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Выход пользователя",
+                "parameters": [
+                    {
+                        "description": "Refresh токен",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.LogoutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed или bad_request",
+                        "schema": {
+                            "$ref": "#/definitions/httpjson.ValidationProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized, token_invalid или token_expired",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "payload_too_large",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/me": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Возвращает ID пользователя по access токену\nПозжже будет заменена взятием других данных пользователя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Получение текущего пользователя",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/mfa/step-up": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Выдаёт access токен той же пары с отметкой о свежей проверке TOTP,\nнеобходимый для чувствительных операций",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Повторная проверка второго фактора",
+                "parameters": [
+                    {
+                        "description": "TOTP код",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.TOTPCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.StepUpResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed или bad_request",
+                        "schema": {
+                            "$ref": "#/definitions/httpjson.ValidationProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "mfa_invalid_code",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "mfa_locked",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/mfa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Включает второй фактор после проверки первого кода и возвращает коды восстановления.\nКоды показываются один раз",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Подтверждение TOTP",
+                "parameters": [
+                    {
+                        "description": "TOTP код",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.TOTPCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.TOTPConfirmResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed или bad_request",
+                        "schema": {
+                            "$ref": "#/definitions/httpjson.ValidationProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "mfa_invalid_code",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "mfa_locked",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/mfa/totp/disable": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Отключает второй фактор. Требует недавнего подтверждения через /auth/mfa/step-up",
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Отключение TOTP",
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "step_up_required",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/mfa/totp/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Создаёт новый TOTP секрет и возвращает otpauth:// ссылку для QR кода.\nВторой фактор включается только после подтверждения кодом через /auth/mfa/totp/confirm",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Подключение TOTP",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.TOTPEnrollResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "mfa_already_enrolled",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/mfa/verify": {
+            "post": {
+                "description": "Обменивает mfa_token из /auth/token и TOTP код (или код восстановления) на пару токенов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Проверка второго фактора при входе",
+                "parameters": [
+                    {
+                        "description": "Промежуточный токен и код",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.MFAVerifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stateless.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed или bad_request",
+                        "schema": {
+                            "$ref": "#/definitions/httpjson.ValidationProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "mfa_invalid_code",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "mfa_locked",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/passkey/login/begin": {
+            "post": {
+                "description": "Возвращает параметры для navigator.credentials.get(). Пользователь определяется по выбранному ключу",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "passkey"
+                ],
+                "summary": "Начало входа по passkey",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webauthn.BeginResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/passkey/login/finish": {
+            "post": {
+                "description": "Проверяет подпись аутентификатора и выдаёт пару токенов.\nЕсли счётчик подписей не вырос, ключ блокируется как склонированный",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "passkey"
+                ],
+                "summary": "Завершение входа по passkey",
+                "parameters": [
+                    {
+                        "description": "Идентификатор церемонии и ответ navigator.credentials.get()",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webauthn.FinishRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stateless.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed, bad_request или webauthn_verification_failed",
+                        "schema": {
+                            "$ref": "#/definitions/httpjson.ValidationProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "passkey_cloned",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/passkey/register/begin": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Возвращает параметры для navigator.credentials.create() и идентификатор церемонии",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "passkey"
+                ],
+                "summary": "Начало регистрации passkey",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webauthn.BeginResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/passkey/register/finish": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Проверяет ответ аутентификатора и сохраняет ключ за текущим пользователем",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "passkey"
+                ],
+                "summary": "Завершение регистрации passkey",
+                "parameters": [
+                    {
+                        "description": "Идентификатор церемонии и ответ navigator.credentials.create()",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webauthn.FinishRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed, bad_request или webauthn_verification_failed",
+                        "schema": {
+                            "$ref": "#/definitions/httpjson.ValidationProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/refresh": {
+            "post": {
+                "description": "Обновляет токены по старой паре",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Обновление пары токенов",
+                "parameters": [
+                    {
+                        "description": "Пара токенов",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stateless.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed или bad_request",
+                        "schema": {
+                            "$ref": "#/definitions/httpjson.ValidationProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "token_invalid, session_not_found или user_agent_changed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "session_client_mismatch или scope_not_granted",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "payload_too_large",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/token": {
+            "post": {
+                "description": "Возвращает пару токенов по user_id,\nв дальнейшем будет заменена настоящим алгоритмом входа.\nЕсли у пользователя подключён TOTP, вместо пары возвращается промежуточный mfa_token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Получение access и refresh токенов",
+                "parameters": [
+                    {
+                        "description": "ID пользователя",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.HandleTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stateless.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed или bad_request",
+                        "schema": {
+                            "$ref": "#/definitions/httpjson.ValidationProblem"
+                        }
+                    },
+                    "403": {
+                        "description": "mfa_required: требуется второй фактор",
+                        "schema": {
+                            "$ref": "#/definitions/http.MFARequiredResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "payload_too_large",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/auth/token/downscope": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Выдаёт новую независимую сессию с частью scope текущего access токена,\nнапример для интеграции, которой нужен только профиль. Текущая сессия не меняется,\nно должна быть активной: после выхода оставшийся access токен новую сессию не получит",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Пара токенов с урезанными правами",
+                "parameters": [
+                    {
+                        "description": "Нужные scope",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.DownscopeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stateless.TokenPair"
+                        }
+                    },
+                    "400": {
+                        "description": "validation_failed или bad_request",
+                        "schema": {
+                            "$ref": "#/definitions/httpjson.ValidationProblem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized, session_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "scope_not_granted",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/oauth/authorize": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Выдаёт код авторизации текущему пользователю (response_type=code, обязательный PKCE S256)\nи перенаправляет на redirect_uri клиента. При Accept: application/json адрес возвращается в теле.\nОшибки после проверки redirect_uri передаются клиенту через параметры error и error_description",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "OAuth 2.0 авторизация",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "code",
+                        "name": "response_type",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор клиента",
+                        "name": "client_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Зарегистрированный адрес возврата",
+                        "name": "redirect_uri",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Запрашиваемые scope через пробел",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Значение, возвращаемое клиенту без изменений",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "PKCE challenge",
+                        "name": "code_challenge",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "S256",
+                        "name": "code_challenge_method",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "OIDC nonce, возвращается в ID токене",
+                        "name": "nonce",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.AuthorizeResponse"
+                        }
+                    },
+                    "302": {
+                        "description": "redirect",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/oauth/introspect": {
+            "post": {
+                "description": "Проверяет access или refresh токен (RFC 7662). Доступно только конфиденциальным клиентам.\nRefresh токен принимается в виде \"\u003cuser_id\u003e.\u003ctoken\u003e\", как его выдаёт /oauth/token",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "OAuth 2.0 token introspection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Проверяемый токен",
+                        "name": "token",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "access_token | refresh_token",
+                        "name": "token_type_hint",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор клиента, если не используется Basic",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Секрет клиента, если не используется Basic",
+                        "name": "client_secret",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.IntrospectionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_client",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/oauth/logout": {
+            "get": {
+                "description": "Завершает сессию, указанную в sid из id_token_hint (просроченный ID токен тоже принимается).\nПри переданном post_logout_redirect_uri, зарегистрированном у клиента, выполняется перенаправление",
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "OIDC RP-initiated logout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID токен, выданный клиенту",
+                        "name": "id_token_hint",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Адрес возврата после выхода",
+                        "name": "post_logout_redirect_uri",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Значение, возвращаемое клиенту без изменений",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор клиента",
+                        "name": "client_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "no content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "302": {
+                        "description": "redirect",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/oauth/revoke": {
+            "post": {
+                "description": "Завершает сессию, к которой относится access или refresh токен (RFC 7009).\nДля неизвестного или уже отозванного токена тоже возвращается 200",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "OAuth 2.0 token revocation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Отзываемый токен",
+                        "name": "token",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "access_token | refresh_token",
+                        "name": "token_type_hint",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор клиента, если не используется Basic",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Секрет клиента, если не используется Basic",
+                        "name": "client_secret",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_client",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/oauth/token": {
+            "post": {
+                "description": "Поддерживаются grant_type authorization_code (с PKCE), refresh_token и client_credentials.\nКлиент аутентифицируется через HTTP Basic или client_id/client_secret в теле.\nRefresh токен имеет вид \"\u003cuser_id\u003e.\u003ctoken\u003e\" и принимается только этим эндпойнтом.\nЕсли в коде авторизации был scope openid, в ответе есть id_token",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oauth"
+                ],
+                "summary": "OAuth 2.0 выдача токенов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization_code | refresh_token | client_credentials",
+                        "name": "grant_type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Код авторизации",
+                        "name": "code",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Адрес возврата, если передавался в /oauth/authorize",
+                        "name": "redirect_uri",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "PKCE verifier",
+                        "name": "code_verifier",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Refresh токен",
+                        "name": "refresh_token",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Запрашиваемые scope (client_credentials) или сужение прав сессии (refresh_token)",
+                        "name": "scope",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор клиента, если не используется Basic",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Секрет клиента, если не используется Basic",
+                        "name": "client_secret",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid_client",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/userinfo": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Возвращает claims профиля пользователя по access токену со scope openid: имя при scope profile, почту при scope email.\nЕсли профиля нет, возвращается только sub",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "OIDC userinfo",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "insufficient_scope",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "health.CheckResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "health.Response": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.CheckResult"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "http.AuthorizeResponse": {
+            "type": "object",
+            "properties": {
+                "redirect_to": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.CreateKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "ExpiresIn срок жизни в секундах, 0 означает API_KEY_DEFAULT_TTL",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 86400
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "billing-sync"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "profile:read"
+                    ]
+                }
+            }
+        },
+        "http.CreatedKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "Key полный ключ для заголовка X-API-Key, больше нигде не показывается",
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "http.DiscoveryResponse": {
+            "type": "object",
+            "properties": {
+                "authorization_endpoint": {
+                    "type": "string"
+                },
+                "claims_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "code_challenge_methods_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "end_session_endpoint": {
+                    "type": "string"
+                },
+                "grant_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id_token_signing_alg_values_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "introspection_endpoint": {
+                    "type": "string"
+                },
+                "issuer": {
+                    "type": "string"
+                },
+                "jwks_uri": {
+                    "type": "string"
+                },
+                "response_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "revocation_endpoint": {
+                    "type": "string"
+                },
+                "scopes_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "subject_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token_endpoint": {
+                    "type": "string"
+                },
+                "token_endpoint_auth_methods_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "userinfo_endpoint": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.DownscopeRequest": {
+            "type": "object",
+            "properties": {
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "profile:read"
+                    ]
+                }
+            }
+        },
+        "http.EntryResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "refresh"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "outcome": {
+                    "type": "string",
+                    "example": "success"
+                },
+                "prev_hash": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "seq": {
+                    "type": "integer"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.ErrorResponse": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string",
-                    "example": "bad request"
+                    "example": "invalid_grant"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.HandleTokenRequest": {
+            "type": "object",
+            "properties": {
+                "user_id": {
+                    "type": "string",
+                    "format": "uuid"
+                }
+            }
+        },
+        "http.IdentityResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.IntrospectionResponse": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "client_id": {
+                    "type": "string"
+                },
+                "exp": {
+                    "type": "integer"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "sub": {
+                    "type": "string"
+                },
+                "token_pair_id": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "access_token"
+                }
+            }
+        },
+        "http.KeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "http.LinkResponse": {
+            "type": "object",
+            "properties": {
+                "linked": {
+                    "type": "boolean"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.LogoutRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string",
+                    "maxLength": 256
+                }
+            }
+        },
+        "http.MFARequiredResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "token_expired"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "access token expired"
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/v1/auth/logout"
+                },
+                "mfa_token": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 401
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Unauthorized"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "http.MFAVerifyRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "mfa_token": {
+                    "type": "string",
+                    "maxLength": 8192
+                },
+                "recovery_code": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "example": "ABCDE-FGHIJ"
+                }
+            }
+        },
+        "http.QueryResponse": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/http.EntryResponse"
+                    }
+                },
+                "next_before": {
+                    "description": "NextBefore значение before для следующей страницы, отсутствует на последней",
+                    "type": "integer"
+                }
+            }
+        },
+        "http.RedirectResponse": {
+            "type": "object",
+            "properties": {
+                "redirect_to": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string",
+                    "maxLength": 8192
+                },
+                "refresh_token": {
+                    "type": "string",
+                    "maxLength": 256
+                }
+            }
+        },
+        "http.StepUpResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.TOTPCodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "http.TOTPConfirmResponse": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "http.TOTPEnrollResponse": {
+            "type": "object",
+            "properties": {
+                "provisioning_uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/medods:123e4567-e89b-12d3-a456-426614174000?secret=..."
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "id_token": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string",
+                    "example": "openid profile email"
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "Bearer"
+                }
+            }
+        },
+        "httperror.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "token_expired"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "access token expired"
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/v1/auth/logout"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 401
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Unauthorized"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "httpjson.ValidationProblem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "token_expired"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "access token expired"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/validate.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/v1/auth/logout"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 401
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Unauthorized"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "jwthelper.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                }
+            }
+        },
+        "jwthelper.JWKSet": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/jwthelper.JWK"
+                    }
                 }
             }
         },
@@ -231,9 +2216,55 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "validate.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "invalid_uuid"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "user_id"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must be a UUID"
+                }
+            }
+        },
+        "webauthn.BeginResponse": {
+            "type": "object",
+            "properties": {
+                "ceremony_id": {
+                    "type": "string"
+                },
+                "options": {
+                    "description": "Options передаются в navigator.credentials.create() / get() без изменений",
+                    "type": "object"
+                }
+            }
+        },
+        "webauthn.FinishRequest": {
+            "type": "object",
+            "properties": {
+                "ceremony_id": {
+                    "type": "string",
+                    "format": "uuid"
+                },
+                "credential": {
+                    "type": "object"
+                }
+            }
         }
     },
     "securityDefinitions": {
+        "ApiKey": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
         "Bearer": {
             "type": "apiKey",
             "name": "Authorization",
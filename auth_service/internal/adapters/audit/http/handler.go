@@ -0,0 +1,151 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	statelessauthhttp "medods_test/internal/adapters/auth/stateless/http"
+	"medods_test/internal/core/audit"
+	"medods_test/pkg/httperror"
+	"medods_test/pkg/router"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Handler struct {
+	service           *audit.AuditService
+	middlewareFactory statelessauthhttp.MiddlewareFactory
+	logger            *slog.Logger
+}
+
+func NewHandler(service *audit.AuditService, authMiddlewareFactory statelessauthhttp.MiddlewareFactory, logger *slog.Logger) *Handler {
+	return &Handler{
+		service:           service,
+		middlewareFactory: authMiddlewareFactory,
+		logger:            logger,
+	}
+}
+
+func (h *Handler) RegisterRoutes(rt *router.Router) {
+	rt.HandleFunc("GET /admin/audit", h.middlewareFactory.Authenticate(h.middlewareFactory.RequireScopes(h.handleQuery, audit.ScopeAuditRead)))
+}
+
+type EntryResponse struct {
+	Seq        int64     `json:"seq"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Action     string    `json:"action" example:"refresh"`
+	Actor      string    `json:"actor"`
+	Subject    string    `json:"subject"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Outcome    string    `json:"outcome" example:"success"`
+	Reason     string    `json:"reason,omitempty"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+}
+
+type QueryResponse struct {
+	Entries []EntryResponse `json:"entries"`
+	// NextBefore значение before для следующей страницы, отсутствует на последней
+	NextBefore *int64 `json:"next_before,omitempty"`
+}
+
+// handleQuery godoc
+// @Summary Журнал аудита
+// @Description Возвращает записи от новых к старым. Страницы листаются параметром before из next_before предыдущего ответа
+// @Tags audit
+// @Produce json
+// @Param action query string false "Действие" Enums(login, refresh, ua_mismatch_logout, ip_changed, logout, admin.api_key_created, admin.api_key_revoked, admin.oauth_client_registered)
+// @Param actor query string false "Кто выполнил действие"
+// @Param subject query string false "Над кем выполнено действие"
+// @Param outcome query string false "Результат" Enums(success, failure, pending)
+// @Param from query string false "Не раньше, RFC 3339"
+// @Param to query string false "Раньше, RFC 3339"
+// @Param before query int false "Только записи с seq меньше указанного"
+// @Param limit query int false "Размер страницы"
+// @Success 200 {object} QueryResponse
+// @Failure 400 {object} httperror.Problem "bad_request"
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Failure 403 {object} httperror.Problem "forbidden"
+// @Router /v1/admin/audit [get]
+// @Security Bearer
+// @Security ApiKey
+func (h *Handler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, httperror.CodeBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.service.Query(r.Context(), filter)
+	if err != nil {
+		httperror.Write(w, r, http.StatusInternalServerError, httperror.CodeInternal, "")
+		h.logger.ErrorContext(r.Context(), "failed to query audit log", "error", err)
+		return
+	}
+
+	response := QueryResponse{Entries: make([]EntryResponse, 0, len(page.Entries))}
+	for _, entry := range page.Entries {
+		response.Entries = append(response.Entries, toResponse(entry))
+	}
+	if page.NextBefore != 0 {
+		response.NextBefore = &page.NextBefore
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func parseFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Action:  query.Get("action"),
+		Actor:   query.Get("actor"),
+		Subject: query.Get("subject"),
+		Outcome: query.Get("outcome"),
+	}
+
+	var err error
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return audit.Filter{}, errBadParam("from")
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return audit.Filter{}, errBadParam("to")
+		}
+	}
+	if v := query.Get("before"); v != "" {
+		if filter.BeforeSeq, err = strconv.ParseInt(v, 10, 64); err != nil || filter.BeforeSeq <= 0 {
+			return audit.Filter{}, errBadParam("before")
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 {
+			return audit.Filter{}, errBadParam("limit")
+		}
+	}
+	return filter, nil
+}
+
+type errBadParam string
+
+func (e errBadParam) Error() string {
+	return "invalid " + string(e)
+}
+
+func toResponse(entry audit.Entry) EntryResponse {
+	return EntryResponse{
+		Seq:        entry.Seq,
+		OccurredAt: entry.OccurredAt,
+		Action:     entry.Action,
+		Actor:      entry.Actor,
+		Subject:    entry.Subject,
+		IP:         entry.IP,
+		UserAgent:  entry.UserAgent,
+		Outcome:    entry.Outcome,
+		Reason:     entry.Reason,
+		PrevHash:   entry.PrevHash,
+		Hash:       entry.Hash,
+	}
+}
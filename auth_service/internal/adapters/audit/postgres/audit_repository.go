@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"medods_test/internal/core/audit"
+	"strings"
+)
+
+type Config struct {
+	Prefix string
+}
+
+type PostgresAuditRepository struct {
+	db   *sql.DB
+	conf *Config
+}
+
+func NewPostgresAuditRepository(db *sql.DB, conf *Config) *PostgresAuditRepository {
+	return &PostgresAuditRepository{db: db, conf: conf}
+}
+
+// appendLockKey ключ pg_advisory_xact_lock, под которым дописывается журнал
+const appendLockKey = 0x61756469
+
+const auditColumns = `seq, occurred_at, action, actor, subject, ip, user_agent, outcome, reason, prev_hash, hash`
+
+func (r *PostgresAuditRepository) Append(ctx context.Context, link func(last *audit.Entry) (audit.Entry, error)) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, appendLockKey); err != nil {
+		return err
+	}
+
+	var last *audit.Entry
+	entry, err := scanEntry(tx.QueryRowContext(ctx,
+		`SELECT `+auditColumns+` FROM `+r.conf.Prefix+`auth_audit_log ORDER BY seq DESC LIMIT 1`))
+	switch {
+	case err == nil:
+		last = &entry
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	next, err := link(last)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`auth_audit_log (`+auditColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		next.Seq, next.OccurredAt, next.Action, next.Actor, next.Subject, next.IP, next.UserAgent,
+		next.Outcome, next.Reason, next.PrevHash, next.Hash)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresAuditRepository) Query(ctx context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	var conditions []string
+	var args []any
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.Subject != "" {
+		add("subject = $%d", filter.Subject)
+	}
+	if filter.Outcome != "" {
+		add("outcome = $%d", filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		add("occurred_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("occurred_at < $%d", filter.To)
+	}
+	if filter.BeforeSeq > 0 {
+		add("seq < $%d", filter.BeforeSeq)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM ` + r.conf.Prefix + `auth_audit_log`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY seq DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []audit.Entry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *PostgresAuditRepository) Scan(ctx context.Context, fn func(entry audit.Entry) error) error {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+auditColumns+` FROM `+r.conf.Prefix+`auth_audit_log ORDER BY seq`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntry(row rowScanner) (audit.Entry, error) {
+	var e audit.Entry
+	err := row.Scan(&e.Seq, &e.OccurredAt, &e.Action, &e.Actor, &e.Subject, &e.IP, &e.UserAgent,
+		&e.Outcome, &e.Reason, &e.PrevHash, &e.Hash)
+	return e, err
+}
@@ -0,0 +1,218 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	statelessauthhttp "medods_test/internal/adapters/auth/stateless/http"
+	"medods_test/internal/core/auth/apikey"
+	"medods_test/internal/core/auth/principal"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/httperror"
+	"medods_test/pkg/httpjson"
+	"medods_test/pkg/router"
+	"medods_test/pkg/validate"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CodeAPIKeyNotFound ключ с таким ID не существует
+const CodeAPIKeyNotFound = "api_key_not_found"
+
+type Handler struct {
+	service           *apikey.APIKeyService
+	middlewareFactory statelessauthhttp.MiddlewareFactory
+	logger            *slog.Logger
+}
+
+func NewHandler(service *apikey.APIKeyService, authMiddlewareFactory statelessauthhttp.MiddlewareFactory, logger *slog.Logger) *Handler {
+	return &Handler{
+		service:           service,
+		middlewareFactory: authMiddlewareFactory,
+		logger:            logger,
+	}
+}
+
+func (h *Handler) RegisterRoutes(rt *router.Router) {
+	// управлять ключами можно и другим ключом, поэтому Authenticate, а не Wrap
+	rt.HandleFunc("GET /admin/api-keys", h.middlewareFactory.Authenticate(h.middlewareFactory.RequireScopes(h.handleList, apikey.ScopeAPIKeysManage)))
+	rt.HandleFunc("POST /admin/api-keys", h.middlewareFactory.Authenticate(h.middlewareFactory.RequireScopes(h.handleCreate, apikey.ScopeAPIKeysManage)))
+	rt.HandleFunc("DELETE /admin/api-keys/{id}", h.middlewareFactory.Authenticate(h.middlewareFactory.RequireScopes(h.handleRevoke, apikey.ScopeAPIKeysManage)))
+}
+
+// Ограничения полей CreateKeyRequest
+const (
+	nameMaxLength  = 100
+	scopesMaxCount = 32
+	scopeMaxLength = 64
+)
+
+type CreateKeyRequest struct {
+	Name   string   `json:"name" example:"billing-sync" maxLength:"100"`
+	Scopes []string `json:"scopes" example:"profile:read" maxItems:"32"`
+	// ExpiresIn срок жизни в секундах, 0 означает API_KEY_DEFAULT_TTL
+	ExpiresIn int64 `json:"expires_in" example:"86400" minimum:"0"`
+}
+
+func (req CreateKeyRequest) Validate() error {
+	var v validate.Validator
+	if v.Required("name", req.Name) {
+		v.MaxLength("name", req.Name, nameMaxLength)
+	}
+	v.List("scopes", req.Scopes, scopesMaxCount, scopeMaxLength)
+	if req.ExpiresIn < 0 {
+		v.Add("expires_in", validate.CodeOutOfRange, "must not be negative")
+	}
+	return v.Err()
+}
+
+type KeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type CreatedKeyResponse struct {
+	KeyResponse
+	// Key полный ключ для заголовка X-API-Key, больше нигде не показывается
+	Key string `json:"key"`
+}
+
+// handleList godoc
+// @Summary Список API ключей
+// @Description Возвращает все ключи без секретов
+// @Tags api-keys
+// @Produce json
+// @Success 200 {array} KeyResponse
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Failure 403 {object} httperror.Problem "insufficient_scope"
+// @Router /v1/admin/api-keys [get]
+// @Security Bearer
+// @Security ApiKey
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.List(r.Context())
+	if err != nil {
+		httperror.Write(w, r, http.StatusInternalServerError, httperror.CodeInternal, "")
+		h.logger.ErrorContext(r.Context(), "failed to list api keys", "error", err)
+		return
+	}
+	response := make([]KeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, toResponse(key))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleCreate godoc
+// @Summary Создание API ключа
+// @Description Полный ключ показывается в ответе один раз. Ключу нельзя выдать scope, которых нет у вызывающего
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param request body CreateKeyRequest true "Параметры ключа"
+// @Success 201 {object} CreatedKeyResponse
+// @Failure 400 {object} httpjson.ValidationProblem "validation_failed или bad_request"
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Failure 403 {object} httperror.Problem "scope_not_granted"
+// @Router /v1/admin/api-keys [post]
+// @Security Bearer
+// @Security ApiKey
+func (h *Handler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	req, err := httpjson.Decode[CreateKeyRequest](w, r, 0)
+	if err != nil {
+		httpjson.WriteError(w, r, err)
+		return
+	}
+	caller, ok := principal.FromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	if !stateless.HasScopes(caller.Scopes, req.Scopes...) {
+		httperror.Write(w, r, http.StatusForbidden, statelessauthhttp.CodeScopeNotGranted, stateless.ErrScopeNotGranted.Error())
+		return
+	}
+
+	created, err := h.service.Create(r.Context(), apikey.CreateCommand{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		TTL:       time.Duration(req.ExpiresIn) * time.Second,
+		CreatedBy: caller.Subject(),
+	})
+	if err != nil {
+		httperror.Write(w, r, http.StatusInternalServerError, httperror.CodeInternal, "")
+		h.logger.ErrorContext(r.Context(), "failed to create api key", "error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedKeyResponse{
+		KeyResponse: toResponse(created.Key),
+		Key:         created.Secret,
+	})
+}
+
+// handleRevoke godoc
+// @Summary Отзыв API ключа
+// @Description Отозванный ключ сразу перестаёт приниматься, запись остаётся в списке
+// @Tags api-keys
+// @Param id path string true "ID ключа"
+// @Success 204 {string} string "revoked"
+// @Failure 400 {object} httperror.Problem "bad_request"
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Failure 404 {object} httperror.Problem "api_key_not_found"
+// @Router /v1/admin/api-keys/{id} [delete]
+// @Security Bearer
+// @Security ApiKey
+func (h *Handler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		httperror.Write(w, r, http.StatusBadRequest, httperror.CodeBadRequest, "invalid api key id")
+		return
+	}
+	caller, ok := principal.FromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	err := h.service.Revoke(r.Context(), id, caller.Subject())
+	if errors.Is(err, apikey.ErrAPIKeyNotFound) {
+		httperror.Write(w, r, http.StatusNotFound, CodeAPIKeyNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		httperror.Write(w, r, http.StatusInternalServerError, httperror.CodeInternal, "")
+		h.logger.ErrorContext(r.Context(), "failed to revoke api key", "error", err, "api_key_id", id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toResponse(key apikey.APIKey) KeyResponse {
+	response := KeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    apikey.KeyPrefix + key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedBy: key.CreatedBy,
+		CreatedAt: key.CreatedAt,
+		ExpiresAt: key.ExpiresAt,
+	}
+	if !key.RevokedAt.IsZero() {
+		response.RevokedAt = &key.RevokedAt
+	}
+	if !key.LastUsedAt.IsZero() {
+		response.LastUsedAt = &key.LastUsedAt
+	}
+	return response
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"medods_test/internal/core/auth/apikey"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Config struct {
+	Prefix string
+}
+
+type PostgresAPIKeyRepository struct {
+	db   *sql.DB
+	conf *Config
+}
+
+func NewPostgresAPIKeyRepository(db *sql.DB, conf *Config) *PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db, conf: conf}
+}
+
+const apiKeyColumns = `id, name, prefix, secret_hash, scopes, created_by, created_at, expires_at, revoked_at, last_used_at`
+
+func (r *PostgresAPIKeyRepository) SaveKey(ctx context.Context, key apikey.APIKey) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`api_keys (id, name, prefix, secret_hash, scopes, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		key.ID, key.Name, key.Prefix, key.SecretHash, pq.Array(key.Scopes), key.CreatedBy, key.CreatedAt, key.ExpiresAt)
+	return err
+}
+
+func (r *PostgresAPIKeyRepository) GetKeyByPrefix(ctx context.Context, prefix string) (apikey.APIKey, error) {
+	key, err := scanKey(r.db.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM `+r.conf.Prefix+`api_keys WHERE prefix = $1`, prefix))
+	if errors.Is(err, sql.ErrNoRows) {
+		return apikey.APIKey{}, apikey.ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+func (r *PostgresAPIKeyRepository) ListKeys(ctx context.Context) ([]apikey.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM `+r.conf.Prefix+`api_keys ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []apikey.APIKey
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *PostgresAPIKeyRepository) RevokeKey(ctx context.Context, id string, at time.Time) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE `+r.conf.Prefix+`api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`, id, at)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return apikey.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *PostgresAPIKeyRepository) TouchKey(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE `+r.conf.Prefix+`api_keys SET last_used_at = $2 WHERE id = $1`, id, at)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row rowScanner) (apikey.APIKey, error) {
+	var k apikey.APIKey
+	var revokedAt, lastUsedAt sql.NullTime
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.SecretHash, pq.Array(&k.Scopes), &k.CreatedBy, &k.CreatedAt, &k.ExpiresAt, &revokedAt, &lastUsedAt)
+	k.RevokedAt = revokedAt.Time
+	k.LastUsedAt = lastUsedAt.Time
+	return k, err
+}
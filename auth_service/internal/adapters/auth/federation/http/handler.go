@@ -0,0 +1,261 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	statelessauthhttp "medods_test/internal/adapters/auth/stateless/http"
+	"medods_test/internal/core/auth/federation"
+	"medods_test/internal/core/auth/principal"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/getip"
+	"medods_test/pkg/httperror"
+	"medods_test/pkg/router"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// bindingCookie cookie с привязкой входа к браузеру, см. federation.BeginResult
+const bindingCookie = "federation_binding"
+
+type Config struct {
+	// RedirectURL адрес callback у провайдера: по нему выбираются путь и Secure для cookie привязки
+	RedirectURL string
+	StateTTL    time.Duration
+}
+
+type Handler struct {
+	service           *federation.FederationService
+	middlewareFactory statelessauthhttp.MiddlewareFactory
+	cookiePath        string
+	cookieSecure      bool
+	cookieMaxAge      int
+	logger            *slog.Logger
+}
+
+func NewHandler(service *federation.FederationService, authMiddlewareFactory statelessauthhttp.MiddlewareFactory, conf Config, logger *slog.Logger) *Handler {
+	h := &Handler{
+		service:           service,
+		middlewareFactory: authMiddlewareFactory,
+		cookiePath:        "/",
+		cookieMaxAge:      int(conf.StateTTL.Seconds()),
+		logger:            logger,
+	}
+	if redirect, err := url.Parse(conf.RedirectURL); err == nil && redirect.Path != "" {
+		h.cookiePath = redirect.Path
+		h.cookieSecure = redirect.Scheme == "https"
+	}
+	return h
+}
+
+func (h *Handler) RegisterRoutes(rt *router.Router) {
+	rt.HandleFunc("GET /auth/federation/login", h.handleLogin)
+	rt.HandleFunc("GET /auth/federation/link", h.middlewareFactory.RequireScopes(h.handleLink, stateless.ScopeIdentitiesManage))
+	rt.HandleFunc("GET /auth/federation/callback", h.handleCallback)
+	rt.HandleFunc("GET /auth/federation/identities", h.middlewareFactory.RequireScopes(h.handleIdentities, stateless.ScopeIdentitiesManage))
+}
+
+type RedirectResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+type LinkResponse struct {
+	Linked   bool   `json:"linked"`
+	UserID   string `json:"user_id"`
+	Provider string `json:"provider,omitempty"`
+}
+
+type IdentityResponse struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handleLogin godoc
+// @Summary Вход через внешний OIDC провайдер
+// @Description Перенаправляет на страницу входа провайдера (state, nonce и PKCE S256 генерируются сервисом).
+// @Description При Accept: application/json адрес возвращается в теле. Ответ ставит HttpOnly cookie federation_binding,
+// @Description без которой callback отклоняется
+// @Tags federation
+// @Produce json
+// @Param provider query string true "Имя провайдера из FEDERATION_PROVIDERS_FILE"
+// @Success 200 {object} RedirectResponse
+// @Success 302 {string} string "redirect"
+// @Failure 404 {object} httperror.Problem "provider_not_found"
+// @Router /v1/auth/federation/login [get]
+func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	h.begin(w, r, federation.BeginCommand{Provider: r.URL.Query().Get("provider")})
+}
+
+// handleLink godoc
+// @Summary Привязка внешнего аккаунта
+// @Description Как /auth/federation/login, но после callback внешний аккаунт привязывается к текущему пользователю
+// @Tags federation
+// @Produce json
+// @Param provider query string true "Имя провайдера из FEDERATION_PROVIDERS_FILE"
+// @Success 200 {object} RedirectResponse
+// @Success 302 {string} string "redirect"
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Failure 404 {object} httperror.Problem "provider_not_found"
+// @Router /v1/auth/federation/link [get]
+// @Security Bearer
+func (h *Handler) handleLink(w http.ResponseWriter, r *http.Request) {
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	h.begin(w, r, federation.BeginCommand{
+		Provider:   r.URL.Query().Get("provider"),
+		LinkUserID: caller.UserID,
+	})
+}
+
+// handleCallback godoc
+// @Summary Callback внешнего OIDC провайдера
+// @Description Обменивает код у провайдера и выдаёт пару токенов. Незнакомый внешний аккаунт получает нового пользователя.
+// @Description Если вход начинался через /auth/federation/link, аккаунт привязывается и токены не выдаются
+// @Tags federation
+// @Produce json
+// @Param state query string true "state"
+// @Param code query string true "Код авторизации провайдера"
+// @Success 200 {object} stateless.TokenPair
+// @Success 201 {object} LinkResponse "аккаунт привязан"
+// @Failure 400 {object} httperror.Problem "login_state_invalid: state неизвестен, истёк или выдан другому браузеру"
+// @Failure 403 {object} http.MFARequiredResponse "требуется второй фактор"
+// @Failure 409 {object} httperror.Problem "identity_already_linked"
+// @Failure 502 {object} httperror.Problem "upstream_login_failed"
+// @Router /v1/auth/federation/callback [get]
+func (h *Handler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if upstreamErr := query.Get("error"); upstreamErr != "" {
+		h.logger.WarnContext(r.Context(), "upstream login declined", "error", upstreamErr, "description", query.Get("error_description"))
+		httperror.Write(w, r, http.StatusBadRequest, CodeUpstreamLoginDeclined, "upstream login declined: "+upstreamErr)
+		return
+	}
+
+	// cookie одноразовая, как и state
+	http.SetCookie(w, h.newBindingCookie("", -1))
+	var binding string
+	if cookie, err := r.Cookie(bindingCookie); err == nil {
+		binding = cookie.Value
+	}
+	result, err := h.service.Callback(r.Context(), federation.CallbackCommand{
+		State:     query.Get("state"),
+		Binding:   binding,
+		Code:      query.Get("code"),
+		UserAgent: r.UserAgent(),
+		IP:        getip.GetIP(r),
+	})
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if result.Linked {
+		h.logger.InfoContext(r.Context(), "external identity linked", "user_id", result.UserID)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(LinkResponse{Linked: true, UserID: string(result.UserID)})
+		return
+	}
+	h.logger.InfoContext(r.Context(), "federated login", "user_id", result.UserID, "created", result.Created)
+	json.NewEncoder(w).Encode(struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}{
+		AccessToken:  string(result.Tokens.AccessToken),
+		RefreshToken: string(result.Tokens.RefreshToken),
+	})
+}
+
+// handleIdentities godoc
+// @Summary Привязанные внешние аккаунты
+// @Tags federation
+// @Produce json
+// @Success 200 {array} IdentityResponse
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Router /v1/auth/federation/identities [get]
+// @Security Bearer
+func (h *Handler) handleIdentities(w http.ResponseWriter, r *http.Request) {
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	identities, err := h.service.ListIdentities(r.Context(), caller.UserID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	response := make([]IdentityResponse, 0, len(identities))
+	for _, i := range identities {
+		response = append(response, IdentityResponse{
+			Provider:  i.Provider,
+			Subject:   i.Subject,
+			Email:     i.Email,
+			CreatedAt: i.CreatedAt,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *Handler) begin(w http.ResponseWriter, r *http.Request, cmd federation.BeginCommand) {
+	result, err := h.service.Begin(r.Context(), cmd)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	http.SetCookie(w, h.newBindingCookie(result.Binding, h.cookieMaxAge))
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(RedirectResponse{RedirectTo: result.RedirectTo})
+		return
+	}
+	http.Redirect(w, r, result.RedirectTo, http.StatusFound)
+}
+
+// newBindingCookie SameSite=Lax, чтобы cookie пришла с верхнеуровневым редиректом от провайдера на callback
+func (h *Handler) newBindingCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     bindingCookie,
+		Value:    value,
+		Path:     h.cookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.cookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Коды ошибок входа через внешних провайдеров
+const (
+	CodeProviderNotFound      = "provider_not_found"
+	CodeLoginStateInvalid     = "login_state_invalid"
+	CodeIdentityAlreadyLinked = "identity_already_linked"
+	CodeUpstreamLoginFailed   = "upstream_login_failed"
+	CodeUpstreamLoginDeclined = "upstream_login_declined"
+)
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, federation.ErrProviderNotFound):
+		httperror.Write(w, r, http.StatusNotFound, CodeProviderNotFound, err.Error())
+	case errors.Is(err, federation.ErrStateNotFound), errors.Is(err, federation.ErrStateBindingMismatch):
+		httperror.Write(w, r, http.StatusBadRequest, CodeLoginStateInvalid, err.Error())
+	case errors.Is(err, federation.ErrIdentityAlreadyLinked):
+		httperror.Write(w, r, http.StatusConflict, CodeIdentityAlreadyLinked, err.Error())
+	case errors.Is(err, federation.ErrUpstreamLogin):
+		h.logger.WarnContext(r.Context(), "federated login failed", "error", err)
+		httperror.Write(w, r, http.StatusBadGateway, CodeUpstreamLoginFailed, federation.ErrUpstreamLogin.Error())
+	default:
+		// в том числе stateless.MFARequiredError при входе пользователя с TOTP
+		statelessauthhttp.WriteError(w, r, h.logger, "federation request failed", err)
+	}
+}
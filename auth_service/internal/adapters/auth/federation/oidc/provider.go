@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"medods_test/internal/core/auth/federation"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Config внешний OIDC провайдер (Google, Keycloak и т.п.), адреса берутся из его discovery документа
+type Config struct {
+	Name         string   `json:"name"`
+	IssuerURL    string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+}
+
+// LoadConfigs читает список провайдеров из JSON файла. Пустой путь означает, что внешний вход выключен
+func LoadConfigs(path string) ([]Config, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []Config
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("federation providers: %w", err)
+	}
+	for _, c := range configs {
+		if c.Name == "" || c.IssuerURL == "" || c.ClientID == "" {
+			return nil, errors.New("federation providers: name, issuer and client_id are required")
+		}
+	}
+	return configs, nil
+}
+
+// Provider discovery выполняется при первом обращении, чтобы недоступный провайдер не мешал старту сервиса
+type Provider struct {
+	conf        Config
+	redirectURL string
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+func NewProvider(conf Config, redirectURL string) *Provider {
+	return &Provider{conf: conf, redirectURL: redirectURL}
+}
+
+func (p *Provider) AuthCodeURL(ctx context.Context, req federation.AuthorizationRequest) (string, error) {
+	conf, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return conf.AuthCodeURL(req.State,
+		gooidc.Nonce(req.Nonce),
+		oauth2.SetAuthURLParam("code_challenge", req.CodeChallenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	), nil
+}
+
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (federation.ExternalIdentity, error) {
+	conf, verifier, err := p.discover(ctx)
+	if err != nil {
+		return federation.ExternalIdentity{}, err
+	}
+
+	token, err := conf.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return federation.ExternalIdentity{}, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return federation.ExternalIdentity{}, errors.New("id_token missing in token response")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return federation.ExternalIdentity{}, err
+	}
+	if idToken.Nonce != nonce {
+		return federation.ExternalIdentity{}, errors.New("id_token nonce mismatch")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return federation.ExternalIdentity{}, err
+	}
+
+	return federation.ExternalIdentity{
+		Provider:      p.conf.Name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+	}, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *gooidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	// контекст запроса не используется: go-oidc держит его для обновления JWKS
+	provider, err := gooidc.NewProvider(context.WithoutCancel(ctx), p.conf.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("discovery of %s failed: %w", p.conf.Name, err)
+	}
+
+	scopes := []string{gooidc.ScopeOpenID, "profile", "email"}
+	if len(p.conf.Scopes) > 0 {
+		scopes = append([]string{gooidc.ScopeOpenID}, slices.DeleteFunc(slices.Clone(p.conf.Scopes), func(scope string) bool {
+			return scope == gooidc.ScopeOpenID
+		})...)
+	}
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.conf.ClientID,
+		ClientSecret: p.conf.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.redirectURL,
+		Scopes:       scopes,
+	}
+	p.verifier = provider.Verifier(&gooidc.Config{ClientID: p.conf.ClientID})
+	return p.oauth2, p.verifier, nil
+}
@@ -0,0 +1,418 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"medods_test/internal/adapters/auth/federation/oidc"
+	"medods_test/internal/core/auth/federation"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/auth/stateless/statelesstest"
+	"medods_test/internal/core/user"
+	"medods_test/pkg/unikelongstring"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID    = "auth-service"
+	testRedirectURL = "https://auth.example.com/v1/auth/federation/callback"
+	testKeyID       = "issuer-key"
+)
+
+// mockIssuer OIDC провайдер с discovery, JWKS и token endpoint. Token endpoint проверяет PKCE S256,
+// как настоящий провайдер, и кладёт в ID токен nonce из запроса авторизации
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authorization
+	// idTokenNonce, если задан, подменяет nonce в выдаваемом ID токене
+	idTokenNonce  string
+	pkceRejected  int
+	tokenRequests int
+}
+
+type authorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &mockIssuer{key: key, codes: map[string]authorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", issuer.handleDiscovery)
+	mux.HandleFunc("GET /jwks", issuer.handleJWKS)
+	mux.HandleFunc("GET /authorize", issuer.handleAuthorize)
+	mux.HandleFunc("POST /token", issuer.handleToken)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *mockIssuer) handleDiscovery(w http.ResponseWriter, _ *http.Request) {
+	base := i.server.URL
+	json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                                base,
+		"authorization_endpoint":                base + "/authorize",
+		"token_endpoint":                        base + "/token",
+		"jwks_uri":                              base + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (i *mockIssuer) handleJWKS(w http.ResponseWriter, _ *http.Request) {
+	pub := i.key.PublicKey
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// handleAuthorize сразу «логинит» пользователя и возвращает его на redirect_uri с кодом
+func (i *mockIssuer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != testClientID || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	code := rand.Text()
+	i.mu.Lock()
+	i.codes[code] = authorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	i.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (i *mockIssuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.tokenRequests++
+
+	auth, ok := i.codes[r.PostForm.Get("code")]
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "invalid_grant")
+		return
+	}
+	delete(i.codes, r.PostForm.Get("code"))
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(verifier[:]) != auth.challenge {
+		i.pkceRejected++
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	nonce := auth.nonce
+	if i.idTokenNonce != "" {
+		nonce = i.idTokenNonce
+	}
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            i.server.URL,
+		"aud":            testClientID,
+		"sub":            "upstream-subject-1",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Minute).Unix(),
+		"nonce":          nonce,
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+	})
+	idToken.Header["kid"] = testKeyID
+	signed, err := idToken.SignedString(i.key)
+	if err != nil {
+		tokenError(w, "server_error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "upstream-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+type memoryStates struct {
+	mu     sync.Mutex
+	states map[string]federation.LoginState
+}
+
+func (s *memoryStates) SaveState(_ context.Context, state federation.LoginState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state.StateHash] = state
+	return nil
+}
+
+func (s *memoryStates) TakeState(_ context.Context, stateHash string) (federation.LoginState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[stateHash]
+	if !ok || time.Now().After(state.ExpiresAt) {
+		return federation.LoginState{}, federation.ErrStateNotFound
+	}
+	delete(s.states, stateHash)
+	return state, nil
+}
+
+type memoryIdentities struct {
+	mu         sync.Mutex
+	identities []federation.LinkedIdentity
+}
+
+func (r *memoryIdentities) GetIdentity(_ context.Context, provider, subject string) (federation.LinkedIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, i := range r.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return i, nil
+		}
+	}
+	return federation.LinkedIdentity{}, federation.ErrIdentityNotFound
+}
+
+func (r *memoryIdentities) SaveIdentity(_ context.Context, identity federation.LinkedIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *memoryIdentities) ListIdentities(_ context.Context, userID stateless.UserID) ([]federation.LinkedIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []federation.LinkedIdentity
+	for _, i := range r.identities {
+		if i.UserID == userID {
+			result = append(result, i)
+		}
+	}
+	return result, nil
+}
+
+type memoryUsers struct {
+	mu    sync.Mutex
+	users []user.Profile
+}
+
+func (u *memoryUsers) CreateUser(_ context.Context, profile user.Profile) (user.Profile, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	profile.ID = rand.Text()
+	u.users = append(u.users, profile)
+	return profile, nil
+}
+
+func (u *memoryUsers) count() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.users)
+}
+
+type fixture struct {
+	issuer  *mockIssuer
+	service *federation.FederationService
+	users   *memoryUsers
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	issuer := newMockIssuer(t)
+	users := &memoryUsers{}
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		IssuerURL:    issuer.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+	}, testRedirectURL)
+	service := federation.NewFederationService(
+		map[string]federation.Provider{"mock": provider},
+		&memoryStates{states: map[string]federation.LoginState{}},
+		&memoryIdentities{},
+		users,
+		statelesstest.NewService(t, statelesstest.Options{}),
+		unikelongstring.NewULSHelper(),
+		federation.Config{StateTTL: time.Minute},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	return &fixture{issuer: issuer, service: service, users: users}
+}
+
+// authorize начинает вход и проходит страницу провайдера, возвращая state и code из redirect на callback
+// и привязку, которую браузер хранит в cookie
+func (f *fixture) authorize(t *testing.T) (state, code, binding string) {
+	t.Helper()
+	begin, err := f.service.Begin(context.Background(), federation.BeginCommand{Provider: "mock"})
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(begin.RedirectTo)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query().Get("state"), location.Query().Get("code"), begin.Binding
+}
+
+func (f *fixture) callback(state, code, binding string) (federation.CallbackResult, error) {
+	return f.service.Callback(context.Background(), federation.CallbackCommand{
+		State:     state,
+		Binding:   binding,
+		Code:      code,
+		UserAgent: "test",
+		IP:        "127.0.0.1",
+	})
+}
+
+func TestFederatedLoginAgainstMockIssuer(t *testing.T) {
+	f := newFixture(t)
+
+	result, err := f.callback(f.authorize(t))
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if !result.Created || result.Tokens.AccessToken == "" || result.Tokens.RefreshToken == "" {
+		t.Fatalf("first login result = %+v, want created user with tokens", result)
+	}
+
+	again, err := f.callback(f.authorize(t))
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if again.Created || again.UserID != result.UserID {
+		t.Fatalf("second login = %+v, want existing user %s", again, result.UserID)
+	}
+	if n := f.users.count(); n != 1 {
+		t.Fatalf("users created = %d, want 1", n)
+	}
+}
+
+func TestCallbackRejectsStateMismatch(t *testing.T) {
+	f := newFixture(t)
+	state, code, binding := f.authorize(t)
+
+	if _, err := f.callback(state+"x", code, binding); !errors.Is(err, federation.ErrStateNotFound) {
+		t.Fatalf("forged state: err = %v, want ErrStateNotFound", err)
+	}
+	if _, err := f.callback("", code, binding); !errors.Is(err, federation.ErrStateNotFound) {
+		t.Fatalf("empty state: err = %v, want ErrStateNotFound", err)
+	}
+	if _, err := f.callback(state, code, binding); err != nil {
+		t.Fatalf("valid state: %v", err)
+	}
+	// state одноразовый
+	if _, err := f.callback(state, code, binding); !errors.Is(err, federation.ErrStateNotFound) {
+		t.Fatalf("replayed state: err = %v, want ErrStateNotFound", err)
+	}
+	if f.issuer.tokenRequests != 1 {
+		t.Fatalf("token requests = %d, want only the one with a valid state", f.issuer.tokenRequests)
+	}
+}
+
+// TestCallbackRejectsForeignBrowser login CSRF: злоумышленник начинает вход сам и подсовывает жертве
+// ссылку на callback со своими state и code. У браузера жертвы нет cookie привязки этого входа
+func TestCallbackRejectsForeignBrowser(t *testing.T) {
+	f := newFixture(t)
+	state, code, _ := f.authorize(t)
+	_, _, victimBinding := f.authorize(t)
+
+	for name, binding := range map[string]string{"without cookie": "", "with cookie of another login": victimBinding} {
+		if _, err := f.callback(state, code, binding); !errors.Is(err, federation.ErrStateBindingMismatch) {
+			t.Fatalf("callback %s: err = %v, want ErrStateBindingMismatch", name, err)
+		}
+		state, code, _ = f.authorize(t)
+	}
+	if f.issuer.tokenRequests != 0 {
+		t.Fatalf("token requests = %d, want none for unbound callbacks", f.issuer.tokenRequests)
+	}
+	if n := f.users.count(); n != 0 {
+		t.Fatalf("users created = %d, want 0", n)
+	}
+}
+
+func TestCallbackRejectsNonceMismatch(t *testing.T) {
+	f := newFixture(t)
+	f.issuer.idTokenNonce = "nonce-from-another-login"
+
+	_, err := f.callback(f.authorize(t))
+	if !errors.Is(err, federation.ErrUpstreamLogin) || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("err = %v, want ErrUpstreamLogin on nonce mismatch", err)
+	}
+	if n := f.users.count(); n != 0 {
+		t.Fatalf("users created = %d, want 0", n)
+	}
+}
+
+func TestCallbackRejectsPKCEMismatch(t *testing.T) {
+	f := newFixture(t)
+	// код, перехваченный из чужого входа, подставляется в свой callback: verifier не совпадает с challenge
+	_, stolenCode, _ := f.authorize(t)
+	ownState, _, ownBinding := f.authorize(t)
+
+	_, err := f.callback(ownState, stolenCode, ownBinding)
+	if !errors.Is(err, federation.ErrUpstreamLogin) {
+		t.Fatalf("err = %v, want ErrUpstreamLogin", err)
+	}
+	if f.issuer.pkceRejected != 1 {
+		t.Fatalf("PKCE rejections = %d, want 1", f.issuer.pkceRejected)
+	}
+	if n := f.users.count(); n != 0 {
+		t.Fatalf("users created = %d, want 0", n)
+	}
+}
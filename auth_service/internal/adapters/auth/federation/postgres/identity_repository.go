@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"medods_test/internal/core/auth/federation"
+	"medods_test/internal/core/auth/stateless"
+)
+
+type PostgresIdentityRepository struct {
+	db   *sql.DB
+	conf *Config
+}
+
+func NewPostgresIdentityRepository(db *sql.DB, conf *Config) *PostgresIdentityRepository {
+	return &PostgresIdentityRepository{db: db, conf: conf}
+}
+
+func (r *PostgresIdentityRepository) GetIdentity(ctx context.Context, provider, subject string) (federation.LinkedIdentity, error) {
+	var i federation.LinkedIdentity
+	err := r.db.QueryRowContext(ctx,
+		`SELECT provider, subject, user_id, email, created_at FROM `+r.conf.Prefix+`user_external_identities
+		WHERE provider = $1 AND subject = $2`, provider, subject).
+		Scan(&i.Provider, &i.Subject, &i.UserID, &i.Email, &i.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return federation.LinkedIdentity{}, federation.ErrIdentityNotFound
+	}
+	return i, err
+}
+
+func (r *PostgresIdentityRepository) SaveIdentity(ctx context.Context, identity federation.LinkedIdentity) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`user_external_identities (provider, subject, user_id, email, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt)
+	return err
+}
+
+func (r *PostgresIdentityRepository) ListIdentities(ctx context.Context, userID stateless.UserID) ([]federation.LinkedIdentity, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT provider, subject, user_id, email, created_at FROM `+r.conf.Prefix+`user_external_identities
+		WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []federation.LinkedIdentity
+	for rows.Next() {
+		var i federation.LinkedIdentity
+		if err := rows.Scan(&i.Provider, &i.Subject, &i.UserID, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"medods_test/internal/core/auth/federation"
+	"medods_test/internal/core/auth/stateless"
+)
+
+type Config struct {
+	Prefix string
+}
+
+type PostgresStateRepository struct {
+	db   *sql.DB
+	conf *Config
+}
+
+func NewPostgresStateRepository(db *sql.DB, conf *Config) *PostgresStateRepository {
+	return &PostgresStateRepository{db: db, conf: conf}
+}
+
+func (r *PostgresStateRepository) SaveState(ctx context.Context, state federation.LoginState) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`federation_states (state_hash, binding_hash, provider, nonce, code_verifier, link_user_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		state.StateHash, state.BindingHash, state.Provider, state.Nonce, state.CodeVerifier,
+		sql.NullString{String: string(state.LinkUserID), Valid: state.LinkUserID != ""}, state.ExpiresAt)
+	return err
+}
+
+// TakeState удаляет состояние в том же запросе, поэтому callback с одним state проходит только один раз
+func (r *PostgresStateRepository) TakeState(ctx context.Context, stateHash string) (federation.LoginState, error) {
+	var s federation.LoginState
+	var linkUserID sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		`DELETE FROM `+r.conf.Prefix+`federation_states WHERE state_hash = $1 AND expires_at > now()
+		RETURNING state_hash, binding_hash, provider, nonce, code_verifier, link_user_id, expires_at`, stateHash).
+		Scan(&s.StateHash, &s.BindingHash, &s.Provider, &s.Nonce, &s.CodeVerifier, &linkUserID, &s.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return federation.LoginState{}, federation.ErrStateNotFound
+	}
+	s.LinkUserID = stateless.UserID(linkUserID.String)
+	return s, err
+}
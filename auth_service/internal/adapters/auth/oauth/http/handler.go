@@ -0,0 +1,255 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	statelessauthhttp "medods_test/internal/adapters/auth/stateless/http"
+	"medods_test/internal/adapters/auth/stateless/jwthelper"
+	"medods_test/internal/core/auth/oauth"
+	"medods_test/internal/core/auth/principal"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/getip"
+	"medods_test/pkg/httperror"
+	"medods_test/pkg/router"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Handler struct {
+	service           *oauth.OAuthService
+	middlewareFactory statelessauthhttp.MiddlewareFactory
+	oidc              OIDCConfig
+	logger            *slog.Logger
+}
+
+// OIDCConfig данные для discovery документа и JWKS
+type OIDCConfig struct {
+	Issuer string
+	JWKS   jwthelper.JWKSet
+}
+
+func NewHandler(service *oauth.OAuthService, authMiddlewareFactory statelessauthhttp.MiddlewareFactory, oidc OIDCConfig, logger *slog.Logger) *Handler {
+	return &Handler{
+		service:           service,
+		middlewareFactory: authMiddlewareFactory,
+		oidc:              oidc,
+		logger:            logger,
+	}
+}
+
+func (h *Handler) RegisterRoutes(rt *router.Router) {
+	authorize := h.middlewareFactory.RequireScopes(h.handleAuthorize, stateless.ScopeOAuthAuthorize)
+	rt.HandleFunc("GET /oauth/authorize", authorize)
+	rt.HandleFunc("POST /oauth/authorize", authorize)
+	rt.HandleFunc("POST /oauth/token", h.handleToken)
+	rt.HandleFunc("POST /oauth/introspect", h.handleIntrospect)
+	rt.HandleFunc("POST /oauth/revoke", h.handleRevoke)
+	rt.HandleFunc("GET /oauth/logout", h.handleEndSession)
+	rt.HandleFunc("POST /oauth/logout", h.handleEndSession)
+	userInfo := h.middlewareFactory.RequireScopes(h.handleUserInfo, oauth.ScopeOpenID)
+	rt.HandleFunc("GET /userinfo", userInfo)
+	rt.HandleFunc("POST /userinfo", userInfo)
+	// расположение документов discovery задано OpenID Connect и не версионируется
+	rt.HandleRootFunc("GET /.well-known/openid-configuration", h.handleDiscovery)
+	rt.HandleRootFunc("GET /.well-known/jwks.json", h.handleJWKS)
+}
+
+// TokenResponse ответ по RFC 6749, раздел 5.1
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty" example:"openid profile email"`
+}
+
+// ErrorResponse ответ с ошибкой по RFC 6749, раздел 5.2
+type ErrorResponse struct {
+	Error            string `json:"error" example:"invalid_grant"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type AuthorizeResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// handleAuthorize godoc
+// @Summary OAuth 2.0 авторизация
+// @Description Выдаёт код авторизации текущему пользователю (response_type=code, обязательный PKCE S256)
+// @Description и перенаправляет на redirect_uri клиента. При Accept: application/json адрес возвращается в теле.
+// @Description Ошибки после проверки redirect_uri передаются клиенту через параметры error и error_description
+// @Tags oauth
+// @Produce json
+// @Param response_type query string true "code"
+// @Param client_id query string true "Идентификатор клиента"
+// @Param redirect_uri query string false "Зарегистрированный адрес возврата"
+// @Param scope query string false "Запрашиваемые scope через пробел"
+// @Param state query string false "Значение, возвращаемое клиенту без изменений"
+// @Param code_challenge query string true "PKCE challenge"
+// @Param code_challenge_method query string true "S256"
+// @Param nonce query string false "OIDC nonce, возвращается в ID токене"
+// @Success 200 {object} AuthorizeResponse
+// @Success 302 {string} string "redirect"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Router /v1/oauth/authorize [get]
+// @Security Bearer
+func (h *Handler) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, oauth.ErrorInvalidRequest, "malformed request")
+		return
+	}
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	cmd := oauth.AuthorizeCommand{
+		UserID:              caller.UserID,
+		AuthTime:            caller.Token.AuthTime,
+		AMR:                 caller.Token.AMR,
+		CallerScopes:        caller.Scopes,
+		ResponseType:        r.Form.Get("response_type"),
+		ClientID:            r.Form.Get("client_id"),
+		RedirectURI:         r.Form.Get("redirect_uri"),
+		Scope:               r.Form.Get("scope"),
+		State:               r.Form.Get("state"),
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+		Nonce:               r.Form.Get("nonce"),
+	}
+	result, err := h.service.Authorize(r.Context(), cmd)
+
+	var oauthErr *oauth.Error
+	if err != nil && !errors.As(err, &oauthErr) {
+		h.logger.ErrorContext(r.Context(), "failed to authorize oauth client", "error", err, "client_id", cmd.ClientID)
+		oauthErr = &oauth.Error{Code: oauth.ErrorServerError}
+	}
+	if oauthErr != nil && result.RedirectURI == "" {
+		writeError(w, http.StatusBadRequest, oauthErr.Code, oauthErr.Description)
+		return
+	}
+
+	params := url.Values{}
+	if oauthErr != nil {
+		params.Set("error", oauthErr.Code)
+		if oauthErr.Description != "" {
+			params.Set("error_description", oauthErr.Description)
+		}
+	} else {
+		params.Set("code", result.Code)
+	}
+	if result.State != "" {
+		params.Set("state", result.State)
+	}
+	redirectTo := appendQuery(result.RedirectURI, params)
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(AuthorizeResponse{RedirectTo: redirectTo})
+		return
+	}
+	http.Redirect(w, r, redirectTo, http.StatusFound)
+}
+
+// handleToken godoc
+// @Summary OAuth 2.0 выдача токенов
+// @Description Поддерживаются grant_type authorization_code (с PKCE), refresh_token и client_credentials.
+// @Description Клиент аутентифицируется через HTTP Basic или client_id/client_secret в теле.
+// @Description Refresh токен имеет вид "<user_id>.<token>" и принимается только этим эндпойнтом.
+// @Description Если в коде авторизации был scope openid, в ответе есть id_token
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code | refresh_token | client_credentials"
+// @Param code formData string false "Код авторизации"
+// @Param redirect_uri formData string false "Адрес возврата, если передавался в /oauth/authorize"
+// @Param code_verifier formData string false "PKCE verifier"
+// @Param refresh_token formData string false "Refresh токен"
+// @Param scope formData string false "Запрашиваемые scope (client_credentials) или сужение прав сессии (refresh_token)"
+// @Param client_id formData string false "Идентификатор клиента, если не используется Basic"
+// @Param client_secret formData string false "Секрет клиента, если не используется Basic"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "invalid_client"
+// @Router /v1/oauth/token [post]
+func (h *Handler) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, oauth.ErrorInvalidRequest, "malformed request")
+		return
+	}
+	creds, basic, ok := clientCredentials(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, oauth.ErrorInvalidRequest, "multiple client authentication methods used")
+		return
+	}
+	cmd := oauth.TokenCommand{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Client:       creds,
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		Scope:        r.PostForm.Get("scope"),
+		UserAgent:    r.UserAgent(),
+		IP:           getip.GetIP(r),
+	}
+	tokens, err := h.service.Token(r.Context(), cmd)
+	if err != nil {
+		h.writeClientError(w, r, err, basic, "failed to issue oauth token", "client_id", creds.ID, "grant_type", cmd.GrantType)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	json.NewEncoder(w).Encode(TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    tokens.TokenType,
+		ExpiresIn:    tokens.ExpiresIn,
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      tokens.IDToken,
+		Scope:        strings.Join(tokens.Scopes, " "),
+	})
+}
+
+// clientCredentials достаёт данные клиента из Basic заголовка (RFC 6749, раздел 2.3.1) или из тела.
+// Одновременное использование обоих способов запрещено
+func clientCredentials(r *http.Request) (oauth.ClientCredentials, bool, bool) {
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		if r.PostForm.Get("client_secret") != "" {
+			return oauth.ClientCredentials{}, true, false
+		}
+		unescapedID, errID := url.QueryUnescape(id)
+		unescapedSecret, errSecret := url.QueryUnescape(secret)
+		if errID != nil || errSecret != nil {
+			return oauth.ClientCredentials{}, true, false
+		}
+		return oauth.ClientCredentials{ID: unescapedID, Secret: unescapedSecret}, true, true
+	}
+	return oauth.ClientCredentials{
+		ID:     r.PostForm.Get("client_id"),
+		Secret: r.PostForm.Get("client_secret"),
+	}, false, true
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + params.Encode()
+}
+
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: code, ErrorDescription: description})
+}
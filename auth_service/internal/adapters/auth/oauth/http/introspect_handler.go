@@ -0,0 +1,128 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"medods_test/internal/core/auth/oauth"
+	"net/http"
+	"strings"
+)
+
+// IntrospectionResponse ответ по RFC 7662, раздел 2.2
+type IntrospectionResponse struct {
+	Active      bool   `json:"active"`
+	Sub         string `json:"sub,omitempty"`
+	ClientID    string `json:"client_id,omitempty"`
+	TokenType   string `json:"token_type,omitempty" example:"access_token"`
+	TokenPairID string `json:"token_pair_id,omitempty"`
+	Exp         int64  `json:"exp,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// handleIntrospect godoc
+// @Summary OAuth 2.0 token introspection
+// @Description Проверяет access или refresh токен (RFC 7662). Доступно только конфиденциальным клиентам.
+// @Description Refresh токен принимается в виде "<user_id>.<token>", как его выдаёт /oauth/token
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Проверяемый токен"
+// @Param token_type_hint formData string false "access_token | refresh_token"
+// @Param client_id formData string false "Идентификатор клиента, если не используется Basic"
+// @Param client_secret formData string false "Секрет клиента, если не используется Basic"
+// @Success 200 {object} IntrospectionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "invalid_client"
+// @Router /v1/oauth/introspect [post]
+func (h *Handler) handleIntrospect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, oauth.ErrorInvalidRequest, "malformed request")
+		return
+	}
+	creds, basic, ok := clientCredentials(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, oauth.ErrorInvalidRequest, "multiple client authentication methods used")
+		return
+	}
+	result, err := h.service.Introspect(r.Context(), oauth.IntrospectCommand{
+		Client:        creds,
+		Token:         r.PostForm.Get("token"),
+		TokenTypeHint: r.PostForm.Get("token_type_hint"),
+	})
+	if err != nil {
+		h.writeClientError(w, r, err, basic, "failed to introspect token", "client_id", creds.ID)
+		return
+	}
+
+	response := IntrospectionResponse{Active: result.Active}
+	if result.Active {
+		response.Sub = string(result.Subject)
+		response.ClientID = result.ClientID
+		response.TokenType = result.TokenType
+		response.TokenPairID = string(result.TokenPairID)
+		response.Scope = strings.Join(result.Scopes, " ")
+		if !result.ExpiresAt.IsZero() {
+			response.Exp = result.ExpiresAt.Unix()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleRevoke godoc
+// @Summary OAuth 2.0 token revocation
+// @Description Завершает сессию, к которой относится access или refresh токен (RFC 7009).
+// @Description Для неизвестного или уже отозванного токена тоже возвращается 200
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Отзываемый токен"
+// @Param token_type_hint formData string false "access_token | refresh_token"
+// @Param client_id formData string false "Идентификатор клиента, если не используется Basic"
+// @Param client_secret formData string false "Секрет клиента, если не используется Basic"
+// @Success 200 {string} string "ok"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "invalid_client"
+// @Router /v1/oauth/revoke [post]
+func (h *Handler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, oauth.ErrorInvalidRequest, "malformed request")
+		return
+	}
+	creds, basic, ok := clientCredentials(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, oauth.ErrorInvalidRequest, "multiple client authentication methods used")
+		return
+	}
+	err := h.service.Revoke(r.Context(), oauth.RevokeCommand{
+		Client:        creds,
+		Token:         r.PostForm.Get("token"),
+		TokenTypeHint: r.PostForm.Get("token_type_hint"),
+	})
+	if err != nil {
+		h.writeClientError(w, r, err, basic, "failed to revoke token", "client_id", creds.ID)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeClientError отдаёт ошибку эндпойнтов с аутентификацией клиента, invalid_client отдаётся с кодом 401
+func (h *Handler) writeClientError(w http.ResponseWriter, r *http.Request, err error, basic bool, logMessage string, logArgs ...any) {
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) {
+		h.logger.ErrorContext(r.Context(), logMessage, append([]any{"error", err}, logArgs...)...)
+		writeError(w, http.StatusInternalServerError, oauth.ErrorServerError, "")
+		return
+	}
+	status := http.StatusBadRequest
+	if oauthErr.Code == oauth.ErrorInvalidClient {
+		status = http.StatusUnauthorized
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+	}
+	writeError(w, status, oauthErr.Code, oauthErr.Description)
+}
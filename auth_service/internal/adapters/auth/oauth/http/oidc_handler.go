@@ -0,0 +1,156 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"medods_test/internal/core/auth/oauth"
+	"medods_test/internal/core/auth/principal"
+	"medods_test/pkg/httperror"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DiscoveryResponse метаданные провайдера по OpenID Connect Discovery 1.0
+type DiscoveryResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	EndSessionEndpoint                string   `json:"end_session_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// handleDiscovery godoc
+// @Summary OIDC discovery
+// @Description Метаданные OpenID провайдера
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} DiscoveryResponse
+// @Router /.well-known/openid-configuration [get]
+func (h *Handler) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	issuer := strings.TrimSuffix(h.oidc.Issuer, "/")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DiscoveryResponse{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/v1/oauth/authorize",
+		TokenEndpoint:                     issuer + "/v1/oauth/token",
+		UserInfoEndpoint:                  issuer + "/v1/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		EndSessionEndpoint:                issuer + "/v1/oauth/logout",
+		IntrospectionEndpoint:             issuer + "/v1/oauth/introspect",
+		RevocationEndpoint:                issuer + "/v1/oauth/revoke",
+		ScopesSupported:                   []string{oauth.ScopeOpenID, "profile", "email"},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{oauth.GrantAuthorizationCode, oauth.GrantRefreshToken, oauth.GrantClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "amr", "sid", "at_hash",
+			"name", "given_name", "family_name", "email", "email_verified", "updated_at"},
+	})
+}
+
+// handleJWKS godoc
+// @Summary JWKS
+// @Description Публичные ключи для проверки ID токенов
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} jwthelper.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.oidc.JWKS)
+}
+
+// handleUserInfo godoc
+// @Summary OIDC userinfo
+// @Description Возвращает claims профиля пользователя по access токену со scope openid: имя при scope profile, почту при scope email.
+// @Description Если профиля нет, возвращается только sub
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Failure 403 {object} httperror.Problem "insufficient_scope"
+// @Router /v1/userinfo [get]
+// @Security Bearer
+func (h *Handler) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	userID := caller.UserID
+
+	claims, err := h.service.UserInfo(r.Context(), userID, caller.Scopes)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to get userinfo", "error", err, "user_id", userID)
+		httperror.Write(w, r, http.StatusInternalServerError, httperror.CodeInternal, "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(claims)
+}
+
+// handleEndSession godoc
+// @Summary OIDC RP-initiated logout
+// @Description Завершает сессию, указанную в sid из id_token_hint (просроченный ID токен тоже принимается).
+// @Description При переданном post_logout_redirect_uri, зарегистрированном у клиента, выполняется перенаправление
+// @Tags oidc
+// @Param id_token_hint query string true "ID токен, выданный клиенту"
+// @Param post_logout_redirect_uri query string false "Адрес возврата после выхода"
+// @Param state query string false "Значение, возвращаемое клиенту без изменений"
+// @Param client_id query string false "Идентификатор клиента"
+// @Success 204 {string} string "no content"
+// @Success 302 {string} string "redirect"
+// @Failure 400 {object} ErrorResponse
+// @Router /v1/oauth/logout [get]
+func (h *Handler) handleEndSession(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, oauth.ErrorInvalidRequest, "malformed request")
+		return
+	}
+	cmd := oauth.EndSessionCommand{
+		IDTokenHint:           r.Form.Get("id_token_hint"),
+		ClientID:              r.Form.Get("client_id"),
+		PostLogoutRedirectURI: r.Form.Get("post_logout_redirect_uri"),
+		State:                 r.Form.Get("state"),
+	}
+	redirectURI, err := h.service.EndSession(r.Context(), cmd)
+	if err != nil {
+		var oauthErr *oauth.Error
+		if errors.As(err, &oauthErr) {
+			writeError(w, http.StatusBadRequest, oauthErr.Code, oauthErr.Description)
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "failed to end oidc session", "error", err)
+		writeError(w, http.StatusInternalServerError, oauth.ErrorServerError, "")
+		return
+	}
+
+	if redirectURI == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	params := url.Values{}
+	if cmd.State != "" {
+		params.Set("state", cmd.State)
+	}
+	if len(params) > 0 {
+		redirectURI = appendQuery(redirectURI, params)
+	}
+	http.Redirect(w, r, redirectURI, http.StatusFound)
+}
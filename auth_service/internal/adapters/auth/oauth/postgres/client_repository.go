@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"medods_test/internal/core/auth/oauth"
+
+	"github.com/lib/pq"
+)
+
+type Config struct {
+	Prefix string
+}
+
+type PostgresClientRepository struct {
+	db   *sql.DB
+	conf *Config
+}
+
+func NewPostgresClientRepository(db *sql.DB, conf *Config) *PostgresClientRepository {
+	return &PostgresClientRepository{db: db, conf: conf}
+}
+
+func (r *PostgresClientRepository) SaveClient(ctx context.Context, client oauth.Client) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`oauth_clients (client_id, name, secret_hash, redirect_uris, allowed_scopes, grant_types, post_logout_redirect_uris) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		client.ID, client.Name, client.SecretHash, pq.Array(client.RedirectURIs), pq.Array(client.AllowedScopes), pq.Array(client.GrantTypes), pq.Array(client.PostLogoutRedirectURIs))
+	return err
+}
+
+func (r *PostgresClientRepository) GetClient(ctx context.Context, clientID string) (oauth.Client, error) {
+	var c oauth.Client
+	err := r.db.QueryRowContext(ctx,
+		`SELECT client_id, name, secret_hash, redirect_uris, allowed_scopes, grant_types, post_logout_redirect_uris FROM `+r.conf.Prefix+`oauth_clients WHERE client_id = $1`, clientID).
+		Scan(&c.ID, &c.Name, &c.SecretHash, pq.Array(&c.RedirectURIs), pq.Array(&c.AllowedScopes), pq.Array(&c.GrantTypes), pq.Array(&c.PostLogoutRedirectURIs))
+	if errors.Is(err, sql.ErrNoRows) {
+		return oauth.Client{}, oauth.ErrClientNotFound
+	}
+	return c, err
+}
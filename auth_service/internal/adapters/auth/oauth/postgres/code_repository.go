@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"medods_test/internal/core/auth/oauth"
+
+	"github.com/lib/pq"
+)
+
+type PostgresCodeRepository struct {
+	db   *sql.DB
+	conf *Config
+}
+
+func NewPostgresCodeRepository(db *sql.DB, conf *Config) *PostgresCodeRepository {
+	return &PostgresCodeRepository{db: db, conf: conf}
+}
+
+func (r *PostgresCodeRepository) SaveCode(ctx context.Context, code oauth.AuthorizationCode) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`oauth_authorization_codes
+		(code_hash, client_id, user_id, redirect_uri, redirect_uri_provided, scopes, code_challenge, nonce, auth_time, amr, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, code.RedirectURIProvided, pq.Array(code.Scopes), code.CodeChallenge,
+		code.Nonce, sql.NullTime{Time: code.AuthTime, Valid: !code.AuthTime.IsZero()}, pq.Array(code.AMR), code.ExpiresAt)
+	return err
+}
+
+// ConsumeCode удаляет код в том же запросе, поэтому один код нельзя обменять дважды
+func (r *PostgresCodeRepository) ConsumeCode(ctx context.Context, codeHash string) (oauth.AuthorizationCode, error) {
+	var c oauth.AuthorizationCode
+	var authTime sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		`DELETE FROM `+r.conf.Prefix+`oauth_authorization_codes WHERE code_hash = $1
+		RETURNING code_hash, client_id, user_id, redirect_uri, redirect_uri_provided, scopes, code_challenge, nonce, auth_time, amr, expires_at`, codeHash).
+		Scan(&c.CodeHash, &c.ClientID, &c.UserID, &c.RedirectURI, &c.RedirectURIProvided, pq.Array(&c.Scopes), &c.CodeChallenge,
+			&c.Nonce, &authTime, pq.Array(&c.AMR), &c.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return oauth.AuthorizationCode{}, oauth.ErrCodeNotFound
+	}
+	c.AuthTime = authTime.Time
+	return c, err
+}
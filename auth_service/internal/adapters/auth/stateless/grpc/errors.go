@@ -0,0 +1,58 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"medods_test/internal/core/auth/stateless"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const errorDomain = "auth.medods"
+
+// statusFromError переводит ошибки сервиса в коды gRPC. Неизвестные ошибки становятся Internal без подробностей
+func statusFromError(err error) error {
+	var mfaErr *stateless.MFARequiredError
+	switch {
+	case errors.As(err, &mfaErr):
+		st, detailsErr := status.New(codes.FailedPrecondition, "mfa required").WithDetails(&errdetails.ErrorInfo{
+			Reason:   "MFA_REQUIRED",
+			Domain:   errorDomain,
+			Metadata: map[string]string{"mfa_token": string(mfaErr.PendingToken)},
+		})
+		if detailsErr != nil {
+			return status.Error(codes.Internal, "internal error")
+		}
+		return st.Err()
+	case errors.Is(err, stateless.ErrAccessTokenInvalid),
+		errors.Is(err, stateless.ErrAccessTokenExpired),
+		errors.Is(err, stateless.ErrSessionNotFound),
+		errors.Is(err, stateless.ErrMFAPendingTokenInvalid):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, stateless.ErrUserAgentChanged):
+		// сессия уже отозвана, повтор с тем же refresh токеном не поможет
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, stateless.ErrSessionClientMismatch),
+		errors.Is(err, stateless.ErrScopeNotGranted):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, stateless.ErrMFALocked):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
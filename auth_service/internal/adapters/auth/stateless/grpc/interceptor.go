@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	statelessauthhttp "medods_test/internal/adapters/auth/stateless/http"
+	"medods_test/internal/core/auth/principal"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator проверка учётных данных, общая с HTTP, её реализует MiddlewareFactory
+type Authenticator interface {
+	Principal(ctx context.Context, authorization string, rawKey string) (principal.Principal, error)
+}
+
+// UnaryAuthInterceptor проверяет метаданные authorization (Bearer JWT) или x-api-key так же, как
+// MiddlewareFactory.Authenticate, и кладёт principal.Principal в контекст.
+// Методы из public вызываются без проверки: клиент с истёкшим access токеном должен суметь вызвать RefreshTokens
+func UnaryAuthInterceptor(auth Authenticator, logger *slog.Logger, public ...string) grpc.UnaryServerInterceptor {
+	publicMethods := make(map[string]bool, len(public))
+	for _, method := range public {
+		publicMethods[method] = true
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		caller, err := auth.Principal(ctx, first(md.Get("authorization")), first(md.Get("x-api-key")))
+		if err != nil {
+			if errors.Is(err, statelessauthhttp.ErrUnauthenticated) {
+				return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+			}
+			logger.ErrorContext(ctx, "failed to authenticate grpc call", "error", err, "method", info.FullMethod)
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+		return handler(principal.WithPrincipal(ctx, caller), req)
+	}
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,192 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	authv1 "medods_test/api/auth/v1"
+	"medods_test/internal/core/auth/principal"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/validate"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Server реализует authv1.AuthServiceServer поверх того же StatelessAuthService, что и HTTP обработчики
+type Server struct {
+	authv1.UnimplementedAuthServiceServer
+	service *stateless.StatelessAuthService
+	logger  *slog.Logger
+}
+
+func NewServer(service *stateless.StatelessAuthService, logger *slog.Logger) *Server {
+	return &Server{service: service, logger: logger}
+}
+
+// PublicMethods не требуют токена: в IssueTokens и RefreshTokens учётные данные в самом запросе,
+// ValidateAccessToken сообщает только о переданном токене
+var PublicMethods = []string{
+	authv1.AuthService_IssueTokens_FullMethodName,
+	authv1.AuthService_RefreshTokens_FullMethodName,
+	authv1.AuthService_ValidateAccessToken_FullMethodName,
+}
+
+func (s *Server) Register(server *grpc.Server) {
+	authv1.RegisterAuthServiceServer(server, s)
+}
+
+func (s *Server) IssueTokens(ctx context.Context, req *authv1.IssueTokensRequest) (*authv1.IssueTokensResponse, error) {
+	// та же проверка, что и в HTTP: user_id в базе имеет тип UUID
+	var v validate.Validator
+	v.UUID("user_id", req.GetUserId())
+	if err := v.Err(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	tokens, err := s.service.TestAuthenticateUser(ctx, stateless.TestAuthCommand{
+		UserId:    stateless.UserID(req.GetUserId()),
+		UserAgent: userAgent(ctx),
+		IP:        clientIP(ctx),
+	})
+	if err != nil {
+		return nil, s.statusError(ctx, "failed to authenticate user", err)
+	}
+	return &authv1.IssueTokensResponse{
+		AccessToken:  string(tokens.AccessToken),
+		RefreshToken: string(tokens.RefreshToken),
+	}, nil
+}
+
+func (s *Server) RefreshTokens(ctx context.Context, req *authv1.RefreshTokensRequest) (*authv1.RefreshTokensResponse, error) {
+	if req.GetAccessToken() == "" || req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token and refresh_token are required")
+	}
+	tokens, err := s.service.RefreshTokens(ctx, stateless.RefreshTokenCommand{
+		AccessToken:  stateless.AccessToken(req.GetAccessToken()),
+		RefreshToken: stateless.RefreshToken(req.GetRefreshToken()),
+		UserAgent:    userAgent(ctx),
+		IP:           clientIP(ctx),
+	})
+	if err != nil {
+		return nil, s.statusError(ctx, "failed to refresh token", err)
+	}
+	return &authv1.RefreshTokensResponse{
+		AccessToken:  string(tokens.AccessToken),
+		RefreshToken: string(tokens.RefreshToken),
+	}, nil
+}
+
+func (s *Server) Logout(ctx context.Context, req *authv1.LogoutRequest) (*authv1.LogoutResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+	caller, ok := principal.UserFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user access token required")
+	}
+	err := s.service.Logout(ctx, stateless.LogoutCommand{
+		RefreshToken: stateless.RefreshToken(req.GetRefreshToken()),
+		UserID:       caller.UserID,
+		TokenPairID:  caller.TokenPairID,
+		UserAgent:    userAgent(ctx),
+		IP:           clientIP(ctx),
+	})
+	if err != nil {
+		return nil, s.statusError(ctx, "failed to logout user", err)
+	}
+	return &authv1.LogoutResponse{}, nil
+}
+
+func (s *Server) ValidateAccessToken(ctx context.Context, req *authv1.ValidateAccessTokenRequest) (*authv1.ValidateAccessTokenResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token is required")
+	}
+	active, err := s.service.IntrospectAccessToken(ctx, stateless.AccessToken(req.GetAccessToken()))
+	if err != nil {
+		if status.Code(statusFromError(err)) == codes.Unauthenticated {
+			return &authv1.ValidateAccessTokenResponse{Active: false}, nil
+		}
+		return nil, s.statusError(ctx, "failed to validate access token", err)
+	}
+	return &authv1.ValidateAccessTokenResponse{
+		Active:      true,
+		UserId:      string(active.UserID),
+		SessionId:   string(active.SessionID),
+		TokenPairId: string(active.TokenPairID),
+		ClientId:    active.ClientID,
+		Scopes:      active.Scopes,
+		ExpiresAt:   timestamp(active.ExpiresAt),
+	}, nil
+}
+
+func (s *Server) ListSessions(ctx context.Context, _ *authv1.ListSessionsRequest) (*authv1.ListSessionsResponse, error) {
+	caller, ok := principal.UserFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user access token required")
+	}
+	sessions, err := s.service.ListSessions(ctx, caller.UserID)
+	if err != nil {
+		return nil, s.statusError(ctx, "failed to list sessions", err)
+	}
+	resp := &authv1.ListSessionsResponse{Sessions: make([]*authv1.Session, 0, len(sessions))}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &authv1.Session{
+			SessionId:   string(session.SessionID),
+			TokenPairId: string(session.TokenPairID),
+			UserAgent:   session.UserAgent,
+			Ip:          session.IP,
+			ClientId:    session.ClientID,
+			AuthTime:    timestamp(session.AuthTime),
+			Amr:         session.AMR,
+			Scopes:      session.Scopes,
+			Current:     session.TokenPairID == caller.TokenPairID,
+		})
+	}
+	return resp, nil
+}
+
+// statusError внутренние ошибки пишутся в лог, клиенту уходит только код
+func (s *Server) statusError(ctx context.Context, msg string, err error) error {
+	st := statusFromError(err)
+	if status.Code(st) == codes.Internal {
+		s.logger.ErrorContext(ctx, msg, "error", err)
+	} else if !errors.Is(err, stateless.ErrMFARequired) {
+		s.logger.WarnContext(ctx, msg, "error", err)
+	}
+	return st
+}
+
+// userAgent grpc-go дописывает к user-agent клиента свою версию, она остаётся частью значения,
+// поэтому сессии, созданные через gRPC, обновляются тоже через gRPC тем же клиентом
+func userAgent(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("user-agent"); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// clientIP как getip.GetIP: сначала x-forwarded-for и x-real-ip от прокси, затем адрес соединения
+func clientIP(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("x-forwarded-for"); len(values) > 0 && values[0] != "" {
+		return strings.TrimSpace(strings.Split(values[0], ",")[0])
+	}
+	if values := md.Get("x-real-ip"); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"encoding/json"
+	"medods_test/internal/core/auth/principal"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/getip"
+	"medods_test/pkg/httperror"
+	"medods_test/pkg/httpjson"
+	"medods_test/pkg/validate"
+	"net/http"
+)
+
+type DownscopeRequest struct {
+	Scopes []string `json:"scopes" example:"profile:read" maxItems:"32"`
+}
+
+// Validate пустой список допустим и даёт токен без прав, отсутствие поля нет
+func (req DownscopeRequest) Validate() error {
+	var v validate.Validator
+	if req.Scopes == nil {
+		v.Add("scopes", validate.CodeRequired, "is required")
+	}
+	v.List("scopes", req.Scopes, scopesMaxCount, scopeMaxLength)
+	return v.Err()
+}
+
+// handleDownscope godoc
+// @Summary Пара токенов с урезанными правами
+// @Description Выдаёт новую независимую сессию с частью scope текущего access токена,
+// @Description например для интеграции, которой нужен только профиль. Текущая сессия не меняется,
+// @Description но должна быть активной: после выхода оставшийся access токен новую сессию не получит
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body DownscopeRequest true "Нужные scope"
+// @Success 200 {object} stateless.TokenPair
+// @Failure 400 {object} httpjson.ValidationProblem "validation_failed или bad_request"
+// @Failure 401 {object} httperror.Problem "unauthorized, session_not_found"
+// @Failure 403 {object} httperror.Problem "scope_not_granted"
+// @Router /v1/auth/token/downscope [post]
+// @Security Bearer
+func (h *Handler) handleDownscope(w http.ResponseWriter, r *http.Request) {
+	req, err := httpjson.Decode[DownscopeRequest](w, r, 0)
+	if err != nil {
+		httpjson.WriteError(w, r, err)
+		return
+	}
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	tokens, err := h.service.DownscopeTokenPair(r.Context(), stateless.DownscopeCommand{
+		Payload:   caller.Token,
+		Scopes:    req.Scopes,
+		UserAgent: r.UserAgent(),
+		IP:        getip.GetIP(r),
+	})
+	if err != nil {
+		WriteError(w, r, &h.logger, "failed to downscope token pair", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}{
+		AccessToken:  string(tokens.AccessToken),
+		RefreshToken: string(tokens.RefreshToken),
+	})
+}
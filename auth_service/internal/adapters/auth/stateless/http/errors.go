@@ -0,0 +1,86 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/httperror"
+	"net/http"
+)
+
+// Коды ошибок сервиса авторизации в поле code ответа problem+json
+const (
+	CodeTokenInvalid            = "token_invalid"
+	CodeTokenExpired            = "token_expired"
+	CodeUserAgentChanged        = "user_agent_changed"
+	CodeSessionNotFound         = "session_not_found"
+	CodeSessionClientMismatch   = "session_client_mismatch"
+	CodeScopeNotGranted         = "scope_not_granted"
+	CodeInsufficientScope       = "insufficient_scope"
+	CodeStepUpRequired          = "step_up_required"
+	CodeMFARequired             = "mfa_required"
+	CodeMFANotEnrolled          = "mfa_not_enrolled"
+	CodeMFAAlreadyEnrolled      = "mfa_already_enrolled"
+	CodeMFAInvalidCode          = "mfa_invalid_code"
+	CodeMFALocked               = "mfa_locked"
+	CodeMFATokenInvalid         = "mfa_token_invalid"
+	CodePasskeyNotFound         = "passkey_not_found"
+	CodePasskeyCloned           = "passkey_cloned"
+	CodePasskeyCeremonyNotFound = "passkey_ceremony_not_found"
+)
+
+// MFARequiredResponse ответ на вход пользователя с подключённым вторым фактором
+type MFARequiredResponse struct {
+	httperror.Problem
+	MFAToken string `json:"mfa_token"`
+}
+
+type problemMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// problemMappings соответствие ошибок stateless/errors.go статусам и кодам ответа
+var problemMappings = []problemMapping{
+	{stateless.ErrAccessTokenInvalid, http.StatusUnauthorized, CodeTokenInvalid},
+	{stateless.ErrAccessTokenExpired, http.StatusUnauthorized, CodeTokenExpired},
+	// сессия при этом уже отозвана, повтор с тем же refresh токеном не поможет
+	{stateless.ErrUserAgentChanged, http.StatusUnauthorized, CodeUserAgentChanged},
+	{stateless.ErrSessionNotFound, http.StatusUnauthorized, CodeSessionNotFound},
+	{stateless.ErrSessionClientMismatch, http.StatusForbidden, CodeSessionClientMismatch},
+	{stateless.ErrScopeNotGranted, http.StatusForbidden, CodeScopeNotGranted},
+	{stateless.ErrMFARequired, http.StatusForbidden, CodeMFARequired},
+	{stateless.ErrMFANotEnrolled, http.StatusNotFound, CodeMFANotEnrolled},
+	{stateless.ErrMFAAlreadyEnrolled, http.StatusConflict, CodeMFAAlreadyEnrolled},
+	{stateless.ErrMFAInvalidCode, http.StatusUnauthorized, CodeMFAInvalidCode},
+	{stateless.ErrMFALocked, http.StatusTooManyRequests, CodeMFALocked},
+	{stateless.ErrMFAPendingTokenInvalid, http.StatusUnauthorized, CodeMFATokenInvalid},
+	{stateless.ErrPasskeyNotFound, http.StatusUnauthorized, CodePasskeyNotFound},
+	{stateless.ErrPasskeyCloned, http.StatusUnauthorized, CodePasskeyCloned},
+	{stateless.ErrPasskeyCeremonyNotFound, http.StatusBadRequest, CodePasskeyCeremonyNotFound},
+}
+
+// ProblemFromError Problem для ошибки сервиса. ok false, если ошибка не из stateless/errors.go
+func ProblemFromError(r *http.Request, err error) (problem httperror.Problem, ok bool) {
+	for _, m := range problemMappings {
+		if errors.Is(err, m.err) {
+			return httperror.New(r, m.status, m.code, m.err.Error()), true
+		}
+	}
+	return httperror.New(r, http.StatusInternalServerError, httperror.CodeInternal, ""), false
+}
+
+// WriteError отвечает на ошибку сервиса. Неизвестные ошибки отдаются как 500 без подробностей и логируются с msg
+func WriteError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, msg string, err error) {
+	problem, ok := ProblemFromError(r, err)
+	if !ok {
+		logger.ErrorContext(r.Context(), msg, "error", err)
+	}
+	var mfaErr *stateless.MFARequiredError
+	if errors.As(err, &mfaErr) {
+		httperror.WriteProblem(w, problem.Status, MFARequiredResponse{Problem: problem, MFAToken: string(mfaErr.PendingToken)})
+		return
+	}
+	httperror.WriteProblem(w, problem.Status, problem)
+}
@@ -2,15 +2,30 @@ package http
 
 import (
 	"encoding/json"
-	"io"
+	"medods_test/internal/core/auth/principal"
 	"medods_test/internal/core/auth/stateless"
 
 	"log/slog"
 	"medods_test/pkg/getip"
 	"medods_test/pkg/httperror"
+	"medods_test/pkg/httpjson"
+	"medods_test/pkg/router"
+	"medods_test/pkg/validate"
 	"net/http"
 )
 
+// Ограничения тела и полей запросов. Refresh токен — 43 символа base64,
+// access токен с запасом на RS256 подпись и длинный список scope
+const (
+	authMaxBodyBytes      int64 = 16 << 10
+	accessTokenMaxLength        = 8192
+	refreshTokenMaxLength       = 256
+	totpCodeDigits              = 6
+	recoveryCodeMaxLength       = 32
+	scopesMaxCount              = 32
+	scopeMaxLength              = 64
+)
+
 type Handler struct {
 	service           stateless.StatelessAuthService
 	middlewareFactory MiddlewareFactory
@@ -29,20 +44,33 @@ type AccessTokenAlgoHelper interface {
 	Validate(token stateless.AccessToken) (stateless.AccessTokenPayload, error)
 }
 
-func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/auth/token", h.handleToken)
-	mux.HandleFunc("/auth/refresh", h.handleRefresh)
-	mux.HandleFunc("/auth/logout", h.middlewareFactory.Wrap(h.handleLogout))
+func (h *Handler) RegisterRoutes(rt *router.Router) {
+	rt.HandleFunc("POST /auth/token", h.handleToken)
+	rt.HandleFunc("POST /auth/refresh", h.handleRefresh)
+	rt.HandleFunc("POST /auth/token/downscope", h.middlewareFactory.Wrap(h.handleDownscope))
+	rt.HandleFunc("POST /auth/logout", h.middlewareFactory.Wrap(h.handleLogout))
+	rt.HandleFunc("POST /auth/mfa/totp/enroll", h.middlewareFactory.RequireScopes(h.handleTOTPEnroll, stateless.ScopeMFAManage))
+	rt.HandleFunc("POST /auth/mfa/totp/confirm", h.middlewareFactory.RequireScopes(h.handleTOTPConfirm, stateless.ScopeMFAManage))
+	rt.HandleFunc("POST /auth/mfa/totp/disable", h.middlewareFactory.RequireScopes(h.middlewareFactory.RequireStepUp(h.handleTOTPDisable), stateless.ScopeMFAManage))
+	rt.HandleFunc("POST /auth/mfa/verify", h.handleMFAVerify)
+	rt.HandleFunc("POST /auth/mfa/step-up", h.middlewareFactory.Wrap(h.handleMFAStepUp))
 }
 
 type HandleTokenRequest struct {
-	UserID string `json:"user_id"`
+	UserID string `json:"user_id" format:"uuid"`
+}
+
+func (req HandleTokenRequest) Validate() error {
+	var v validate.Validator
+	v.UUID("user_id", req.UserID)
+	return v.Err()
 }
 
 // handleToken godoc
 // @Summary Получение access и refresh токенов
 // @Description Возвращает пару токенов по user_id,
-// @Description в дальнейшем будет заменена настоящим алгоритмом входа
+// @Description в дальнейшем будет заменена настоящим алгоритмом входа.
+// @Description Если у пользователя подключён TOTP, вместо пары возвращается промежуточный mfa_token
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -53,19 +81,15 @@ type HandleTokenRequest struct {
 //	}
 //
 // @Success 200 {object} stateless.TokenPair
-// @Failure 401 {object} httperror.ErrorResponse
-// @Router /auth/token [post]
+// @Failure 400 {object} httpjson.ValidationProblem "validation_failed или bad_request"
+// @Failure 413 {object} httperror.Problem "payload_too_large"
+// @Failure 403 {object} MFARequiredResponse "mfa_required: требуется второй фактор"
+// @Failure 500 {object} httperror.Problem "internal_error"
+// @Router /v1/auth/token [post]
 func (h *Handler) handleToken(w http.ResponseWriter, r *http.Request) {
-
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
-	}
-	var req HandleTokenRequest
-	body, _ := io.ReadAll(r.Body)
-	err := json.Unmarshal(body, &req)
-	if err != nil || req.UserID == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	req, err := httpjson.Decode[HandleTokenRequest](w, r, authMaxBodyBytes)
+	if err != nil {
+		httpjson.WriteError(w, r, err)
 		return
 	}
 	cmd := stateless.TestAuthCommand{
@@ -75,8 +99,7 @@ func (h *Handler) handleToken(w http.ResponseWriter, r *http.Request) {
 	}
 	tokens, err := h.service.TestAuthenticateUser(r.Context(), cmd)
 	if err != nil {
-		httperror.WriteJSONError(w, http.StatusUnauthorized, "internal server error")
-		h.logger.Error("failed to authenticate user", "error", err)
+		WriteError(w, r, &h.logger, "failed to authenticate user", err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -90,8 +113,19 @@ func (h *Handler) handleToken(w http.ResponseWriter, r *http.Request) {
 }
 
 type RefreshRequest struct {
-	RefreshToken string `json:"refresh_token"`
-	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token" maxLength:"256"`
+	AccessToken  string `json:"access_token" maxLength:"8192"`
+}
+
+func (req RefreshRequest) Validate() error {
+	var v validate.Validator
+	if v.Required("refresh_token", req.RefreshToken) {
+		v.MaxLength("refresh_token", req.RefreshToken, refreshTokenMaxLength)
+	}
+	if v.Required("access_token", req.AccessToken) {
+		v.MaxLength("access_token", req.AccessToken, accessTokenMaxLength)
+	}
+	return v.Err()
 }
 
 // handleRefresh godoc
@@ -104,29 +138,20 @@ type RefreshRequest struct {
 //	@Param request body RefreshRequest true "Пара токенов"
 //
 // @Success 200 {object} stateless.TokenPair
-// @Failure 400 {object} httperror.ErrorResponse "bad request"
-// @Failure 403 {object} httperror.ErrorResponse
-// @Router /auth/refresh [post]
+// @Failure 400 {object} httpjson.ValidationProblem "validation_failed или bad_request"
+// @Failure 401 {object} httperror.Problem "token_invalid, session_not_found или user_agent_changed"
+// @Failure 403 {object} httperror.Problem "session_client_mismatch или scope_not_granted"
+// @Failure 413 {object} httperror.Problem "payload_too_large"
+// @Router /v1/auth/refresh [post]
 //
 //	@Example request "Пример пары токенов" {
 //	  "access_token": "access-token-abc",
 //	  "refresh_token": "refresh-token-def"
 //	}
 func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
-
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
-	}
-	var req struct {
-		AccessToken  string `json:"access_token"`
-		RefreshToken string `json:"refresh_token"`
-	}
-	body, _ := io.ReadAll(r.Body)
-	err := json.Unmarshal(body, &req)
-	if err != nil || req.AccessToken == "" || req.RefreshToken == "" {
-		httperror.WriteJSONError(w, http.StatusBadRequest, "bad request")
-		h.logger.Error("failed to refresh token", "error", err)
+	req, err := httpjson.Decode[RefreshRequest](w, r, authMaxBodyBytes)
+	if err != nil {
+		httpjson.WriteError(w, r, err)
 		return
 	}
 	cmd := stateless.RefreshTokenCommand{
@@ -137,20 +162,22 @@ func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
 	}
 	tokens, err := h.service.RefreshTokens(r.Context(), cmd)
 	if err != nil {
-		if err == stateless.ErrUserAgentChanged {
-			httperror.WriteJSONError(w, http.StatusUnauthorized, "user agent changed")
-			h.logger.Error("failed to refresh token", "error", err)
-			return
-		}
-		w.WriteHeader(http.StatusForbidden)
-		h.logger.Error("failed to refresh token", "error", err)
+		WriteError(w, r, &h.logger, "failed to refresh token", err)
 		return
 	}
 	json.NewEncoder(w).Encode(tokens)
 }
 
 type LogoutRequest struct {
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token" maxLength:"256"`
+}
+
+func (req LogoutRequest) Validate() error {
+	var v validate.Validator
+	if v.Required("refresh_token", req.RefreshToken) {
+		v.MaxLength("refresh_token", req.RefreshToken, refreshTokenMaxLength)
+	}
+	return v.Err()
 }
 
 // handleLogout godoc
@@ -160,31 +187,36 @@ type LogoutRequest struct {
 // @Accept json
 // @Param request body LogoutRequest true "Refresh токен"
 // @Success 200 {string} string "ok"
-// @Failure 400 {string} string "bad request"
-// @Failure 500 {string} string "internal server error"
-// @Router /auth/logout [post]
+// @Failure 400 {object} httpjson.ValidationProblem "validation_failed или bad_request"
+// @Failure 401 {object} httperror.Problem "unauthorized, token_invalid или token_expired"
+// @Failure 413 {object} httperror.Problem "payload_too_large"
+// @Failure 500 {object} httperror.Problem "internal_error"
+// @Router /v1/auth/logout [post]
 // @Security Bearer
 //
 //	@Example request "Пример выхода" {
 //	  "refresh_token": "refresh-token-def"
 //	}
 func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
-
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	req, err := httpjson.Decode[LogoutRequest](w, r, authMaxBodyBytes)
+	if err != nil {
+		httpjson.WriteError(w, r, err)
 		return
 	}
-	var req LogoutRequest
-	body, _ := io.ReadAll(r.Body)
-	err := json.Unmarshal(body, &req)
-	if err != nil || req.RefreshToken == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "user access token required")
 		return
 	}
-	err = h.service.Logout(r.Context(), stateless.RefreshToken(req.RefreshToken), r.Context().Value("user_id").(stateless.UserID))
+	err = h.service.Logout(r.Context(), stateless.LogoutCommand{
+		RefreshToken: stateless.RefreshToken(req.RefreshToken),
+		UserID:       caller.UserID,
+		TokenPairID:  caller.TokenPairID,
+		UserAgent:    r.UserAgent(),
+		IP:           getip.GetIP(r),
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		h.logger.Error("failed to logout user", "error", err)
+		WriteError(w, r, &h.logger, "failed to logout user", err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
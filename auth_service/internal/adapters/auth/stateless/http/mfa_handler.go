@@ -0,0 +1,224 @@
+package http
+
+import (
+	"encoding/json"
+	"medods_test/internal/core/auth/principal"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/getip"
+	"medods_test/pkg/httperror"
+	"medods_test/pkg/httpjson"
+	"medods_test/pkg/validate"
+	"net/http"
+	"strings"
+)
+
+type TOTPEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/medods:123e4567-e89b-12d3-a456-426614174000?secret=..."`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" example:"123456" pattern:"^[0-9]{6}$"`
+}
+
+func (req TOTPCodeRequest) Validate() error {
+	var v validate.Validator
+	v.Digits("code", req.Code, totpCodeDigits)
+	return v.Err()
+}
+
+type TOTPConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" maxLength:"8192"`
+	Code         string `json:"code,omitempty" example:"123456" pattern:"^[0-9]{6}$"`
+	RecoveryCode string `json:"recovery_code,omitempty" example:"ABCDE-FGHIJ" maxLength:"32"`
+}
+
+// Validate нужен ровно один из code и recovery_code
+func (req MFAVerifyRequest) Validate() error {
+	var v validate.Validator
+	if v.Required("mfa_token", req.MFAToken) {
+		v.MaxLength("mfa_token", req.MFAToken, accessTokenMaxLength)
+	}
+	switch {
+	case req.Code != "" && req.RecoveryCode != "":
+		v.Add("recovery_code", validate.CodeOutOfRange, "must not be sent together with code")
+	case req.RecoveryCode != "":
+		v.MaxLength("recovery_code", req.RecoveryCode, recoveryCodeMaxLength)
+	default:
+		v.Digits("code", req.Code, totpCodeDigits)
+	}
+	return v.Err()
+}
+
+type StepUpResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// handleTOTPEnroll godoc
+// @Summary Подключение TOTP
+// @Description Создаёт новый TOTP секрет и возвращает otpauth:// ссылку для QR кода.
+// @Description Второй фактор включается только после подтверждения кодом через /auth/mfa/totp/confirm
+// @Tags mfa
+// @Produce json
+// @Success 200 {object} TOTPEnrollResponse
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Failure 409 {object} httperror.Problem "mfa_already_enrolled"
+// @Router /v1/auth/mfa/totp/enroll [post]
+// @Security Bearer
+func (h *Handler) handleTOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	userID := caller.UserID
+	result, err := h.service.EnrollTOTP(r.Context(), userID)
+	if err != nil {
+		WriteError(w, r, &h.logger, "mfa operation failed", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TOTPEnrollResponse{
+		Secret:          result.Secret,
+		ProvisioningURI: result.ProvisioningURI,
+	})
+}
+
+// handleTOTPConfirm godoc
+// @Summary Подтверждение TOTP
+// @Description Включает второй фактор после проверки первого кода и возвращает коды восстановления.
+// @Description Коды показываются один раз
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body TOTPCodeRequest true "TOTP код"
+// @Success 200 {object} TOTPConfirmResponse
+// @Failure 400 {object} httpjson.ValidationProblem "validation_failed или bad_request"
+// @Failure 401 {object} httperror.Problem "mfa_invalid_code"
+// @Failure 429 {object} httperror.Problem "mfa_locked"
+// @Router /v1/auth/mfa/totp/confirm [post]
+// @Security Bearer
+func (h *Handler) handleTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	req, err := httpjson.Decode[TOTPCodeRequest](w, r, 0)
+	if err != nil {
+		httpjson.WriteError(w, r, err)
+		return
+	}
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	userID := caller.UserID
+	codes, err := h.service.ConfirmTOTP(r.Context(), userID, req.Code)
+	if err != nil {
+		WriteError(w, r, &h.logger, "mfa operation failed", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TOTPConfirmResponse{RecoveryCodes: codes})
+}
+
+// handleTOTPDisable godoc
+// @Summary Отключение TOTP
+// @Description Отключает второй фактор. Требует недавнего подтверждения через /auth/mfa/step-up
+// @Tags mfa
+// @Success 200 {string} string "ok"
+// @Failure 401 {object} httperror.Problem "step_up_required"
+// @Router /v1/auth/mfa/totp/disable [post]
+// @Security Bearer
+func (h *Handler) handleTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	userID := caller.UserID
+	if err := h.service.DisableTOTP(r.Context(), userID); err != nil {
+		WriteError(w, r, &h.logger, "mfa operation failed", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleMFAVerify godoc
+// @Summary Проверка второго фактора при входе
+// @Description Обменивает mfa_token из /auth/token и TOTP код (или код восстановления) на пару токенов
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body MFAVerifyRequest true "Промежуточный токен и код"
+// @Success 200 {object} stateless.TokenPair
+// @Failure 400 {object} httpjson.ValidationProblem "validation_failed или bad_request"
+// @Failure 401 {object} httperror.Problem "mfa_invalid_code"
+// @Failure 429 {object} httperror.Problem "mfa_locked"
+// @Router /v1/auth/mfa/verify [post]
+func (h *Handler) handleMFAVerify(w http.ResponseWriter, r *http.Request) {
+	req, err := httpjson.Decode[MFAVerifyRequest](w, r, 0)
+	if err != nil {
+		httpjson.WriteError(w, r, err)
+		return
+	}
+	cmd := stateless.VerifyMFACommand{
+		PendingToken: stateless.MFAPendingToken(req.MFAToken),
+		Code:         req.Code,
+		RecoveryCode: strings.ToUpper(strings.TrimSpace(req.RecoveryCode)),
+		UserAgent:    r.UserAgent(),
+		IP:           getip.GetIP(r),
+	}
+	tokens, err := h.service.VerifyMFA(r.Context(), cmd)
+	if err != nil {
+		WriteError(w, r, &h.logger, "mfa operation failed", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}{
+		AccessToken:  string(tokens.AccessToken),
+		RefreshToken: string(tokens.RefreshToken),
+	})
+}
+
+// handleMFAStepUp godoc
+// @Summary Повторная проверка второго фактора
+// @Description Выдаёт access токен той же пары с отметкой о свежей проверке TOTP,
+// @Description необходимый для чувствительных операций
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body TOTPCodeRequest true "TOTP код"
+// @Success 200 {object} StepUpResponse
+// @Failure 400 {object} httpjson.ValidationProblem "validation_failed или bad_request"
+// @Failure 401 {object} httperror.Problem "mfa_invalid_code"
+// @Failure 429 {object} httperror.Problem "mfa_locked"
+// @Router /v1/auth/mfa/step-up [post]
+// @Security Bearer
+func (h *Handler) handleMFAStepUp(w http.ResponseWriter, r *http.Request) {
+	req, err := httpjson.Decode[TOTPCodeRequest](w, r, 0)
+	if err != nil {
+		httpjson.WriteError(w, r, err)
+		return
+	}
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	cmd := stateless.StepUpCommand{
+		Payload: caller.Token,
+		Code:    req.Code,
+	}
+	accessToken, err := h.service.StepUpMFA(r.Context(), cmd)
+	if err != nil {
+		WriteError(w, r, &h.logger, "mfa operation failed", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StepUpResponse{AccessToken: string(accessToken)})
+}
@@ -2,34 +2,158 @@ package http
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"medods_test/internal/core/auth/apikey"
+	"medods_test/internal/core/auth/principal"
 	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/httperror"
+	"strings"
+	"time"
 
 	"net/http"
 )
 
 type MiddlewareFactory struct {
-	algohelper AccessTokenAlgoHelper
+	algohelper   AccessTokenAlgoHelper
+	apiKeys      APIKeyAuthenticator
+	stepUpMaxAge time.Duration
 }
 
-func NewMiddlewareFactory(algohelper AccessTokenAlgoHelper) *MiddlewareFactory {
-	return &MiddlewareFactory{algohelper: algohelper}
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, raw string) (apikey.APIKey, error)
 }
 
+func NewMiddlewareFactory(algohelper AccessTokenAlgoHelper, apiKeys APIKeyAuthenticator, stepUpMaxAge time.Duration) *MiddlewareFactory {
+	return &MiddlewareFactory{algohelper: algohelper, apiKeys: apiKeys, stepUpMaxAge: stepUpMaxAge}
+}
+
+// Wrap пропускает только access токены пользователей
 func (h *MiddlewareFactory) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return h.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		// токены client_credentials и API ключи не привязаны к пользователю и не дают доступа к пользовательским роутам
+		if _, ok := principal.UserFromContext(r.Context()); !ok {
+			httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "user access token required")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// ErrUnauthenticated учётные данные не переданы или недействительны
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticate принимает Bearer JWT (пользователя или OAuth клиента) либо заголовок X-API-Key
+// и кладёт в контекст principal.Principal
+func (h *MiddlewareFactory) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		header := r.Header.Get("Authorization")
-		if header == "" || len(header) < 8 || header[:7] != "Bearer " {
-			w.WriteHeader(http.StatusUnauthorized)
+		caller, err := h.Principal(r.Context(), r.Header.Get("Authorization"), r.Header.Get("X-API-Key"))
+		if err != nil {
+			writeAuthenticationError(w, r, err)
 			return
 		}
-		tokenStr := header[7:]
-		payload, err := h.algohelper.Validate(stateless.AccessToken(tokenStr))
+		next(w, r.WithContext(principal.WithPrincipal(r.Context(), caller)))
+	}
+}
+
+// Principal проверяет значение заголовка Authorization или API ключ, передавать можно только что-то одно.
+// Возвращает ErrUnauthenticated (с причиной, например stateless.ErrAccessTokenExpired) для неверных учётных данных,
+// другие ошибки — сбой проверки.
+// Используется и HTTP, и gRPC транспортом, чтобы проверка была одна
+func (h *MiddlewareFactory) Principal(ctx context.Context, authorization string, rawKey string) (principal.Principal, error) {
+	if rawKey != "" && authorization != "" {
+		return principal.Principal{}, ErrUnauthenticated
+	}
+
+	if rawKey != "" {
+		key, err := h.apiKeys.Authenticate(ctx, rawKey)
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
+			if errors.Is(err, apikey.ErrAPIKeyInvalid) || errors.Is(err, apikey.ErrAPIKeyExpired) || errors.Is(err, apikey.ErrAPIKeyRevoked) {
+				return principal.Principal{}, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+			}
+			return principal.Principal{}, err
+		}
+		return principal.Principal{
+			APIKeyID:   key.ID,
+			Scopes:     key.Scopes,
+			AuthMethod: principal.AuthMethodAPIKey,
+		}, nil
+	}
+
+	if len(authorization) < 8 || authorization[:7] != "Bearer " {
+		return principal.Principal{}, ErrUnauthenticated
+	}
+	payload, err := h.algohelper.Validate(stateless.AccessToken(authorization[7:]))
+	if err != nil {
+		return principal.Principal{}, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+	return principal.Principal{
+		UserID:      payload.UserID,
+		TokenPairID: payload.TokenPairID,
+		ClientID:    payload.ClientID,
+		Role:        payload.Role,
+		Scopes:      payload.GrantedScopes(),
+		AuthMethod:  principal.AuthMethodJWT,
+		Token:       payload,
+	}, nil
+}
+
+// writeAuthenticationError истёкший или неверный токен получает свой код, чтобы клиент знал, стоит ли делать refresh
+func writeAuthenticationError(w http.ResponseWriter, r *http.Request, err error) {
+	if !errors.Is(err, ErrUnauthenticated) {
+		httperror.Write(w, r, http.StatusInternalServerError, httperror.CodeInternal, "")
+		return
+	}
+	problem, ok := ProblemFromError(r, err)
+	if !ok {
+		problem = httperror.New(r, http.StatusUnauthorized, httperror.CodeUnauthorized, "missing or invalid credentials")
+	}
+	if r.Header.Get("Authorization") != "" {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	} else {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	httperror.WriteProblem(w, problem.Status, problem)
+}
+
+// RequireStepUp пропускает запрос только если второй фактор подтверждался не раньше stepUpMaxAge назад.
+// Иначе клиент должен пройти /auth/mfa/step-up и повторить запрос с новым access токеном
+func (h *MiddlewareFactory) RequireStepUp(next http.HandlerFunc) http.HandlerFunc {
+	return h.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ := principal.FromContext(r.Context())
+		verifiedAt := caller.Token.MFAVerifiedAt
+		if verifiedAt.IsZero() || time.Since(verifiedAt) > h.stepUpMaxAge {
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_user_authentication"`)
+			httperror.Write(w, r, http.StatusUnauthorized, CodeStepUpRequired, "step-up authentication required")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// RequireScopes пропускает запрос, только если токен или API ключ содержит все перечисленные scope.
+// Токены без claim scope проверяются по правам своей роли.
+// Без внешнего Authenticate пропускаются только пользователи, как в Wrap
+func (h *MiddlewareFactory) RequireScopes(next http.HandlerFunc, scopes ...string) http.HandlerFunc {
+	return h.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ := principal.FromContext(r.Context())
+		if !stateless.HasScopes(caller.Scopes, scopes...) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+			httperror.Write(w, r, http.StatusForbidden, CodeInsufficientScope, "insufficient scope")
 			return
 		}
-		ctx := context.WithValue(r.Context(), "user_id", payload.UserID)
-		r = r.WithContext(ctx)
 		next(w, r)
+	})
+}
+
+// authenticated проверяет токен, если этого ещё не сделал внешний Wrap или Authenticate, чтобы проверки можно было комбинировать
+func (h *MiddlewareFactory) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	wrapped := h.Wrap(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := principal.FromContext(r.Context()); ok {
+			next(w, r)
+			return
+		}
+		wrapped(w, r)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,88 @@
+package jwthelper
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"medods_test/internal/core/auth/oauth"
+	"medods_test/internal/core/auth/stateless"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTIDTokenHelper выпускает ID токены OIDC, подписанные RS256 ключом из JWKS
+type JWTIDTokenHelper struct {
+	Key    *SigningKey
+	Issuer string
+	TTL    time.Duration
+}
+
+func NewJWTIDTokenHelper(key *SigningKey, issuer string, ttl time.Duration) *JWTIDTokenHelper {
+	return &JWTIDTokenHelper{Key: key, Issuer: issuer, TTL: ttl}
+}
+
+func (h *JWTIDTokenHelper) Generate(c oauth.IDTokenClaims) (string, error) {
+	now := time.Now()
+	// typ отличает ID токен от access токена, подписанного тем же ключом
+	claims := jwt.MapClaims{
+		"typ": tokenTypeID,
+		"iss": h.Issuer,
+		"sub": string(c.Subject),
+		"aud": c.Audience,
+		"iat": now.Unix(),
+		"exp": now.Add(h.TTL).Unix(),
+	}
+	if c.Nonce != "" {
+		claims["nonce"] = c.Nonce
+	}
+	if !c.AuthTime.IsZero() {
+		claims["auth_time"] = c.AuthTime.Unix()
+	}
+	if len(c.AMR) > 0 {
+		claims["amr"] = c.AMR
+	}
+	if c.SessionID != "" {
+		claims["sid"] = string(c.SessionID)
+	}
+	if c.AccessToken != "" {
+		// at_hash левая половина SHA-256 от access токена (OIDC Core 3.1.3.6)
+		sum := sha256.Sum256([]byte(c.AccessToken))
+		claims["at_hash"] = base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = h.Key.KeyID
+	return token.SignedString(h.Key.Key)
+}
+
+func (h *JWTIDTokenHelper) Validate(token string) (oauth.IDTokenClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return &h.Key.Key.PublicKey, nil
+	}, jwt.WithIssuer(h.Issuer))
+	// id_token_hint может быть просроченным, поэтому истечение срока не считается ошибкой
+	if err != nil && !errors.Is(err, jwt.ErrTokenExpired) {
+		return oauth.IDTokenClaims{}, err
+	}
+	if errors.Is(err, jwt.ErrTokenExpired) && stringClaim(claims, "iss") != h.Issuer {
+		return oauth.IDTokenClaims{}, jwt.ErrTokenInvalidIssuer
+	}
+	// ID токены без typ выпущены раньше и принимаются, access токен вместо id_token_hint нет
+	if typ := stringClaim(claims, "typ"); typ != "" && typ != tokenTypeID {
+		return oauth.IDTokenClaims{}, errors.New("unexpected token type")
+	}
+
+	return oauth.IDTokenClaims{
+		Subject:   stateless.UserID(stringClaim(claims, "sub")),
+		Audience:  stringClaim(claims, "aud"),
+		Nonce:     stringClaim(claims, "nonce"),
+		AuthTime:  timeClaim(claims, "auth_time"),
+		AMR:       stringsClaim(claims, "amr"),
+		SessionID: stateless.SessionID(stringClaim(claims, "sid")),
+	}, nil
+}
@@ -2,6 +2,7 @@ package jwthelper
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"medods_test/internal/core/auth/stateless"
@@ -9,53 +10,161 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	tokenTypeAccess     = "access"
+	tokenTypeMFAPending = "mfa_pending"
+	tokenTypeID         = "id"
+)
+
+// JWTAccessTokenHelper подписывает access токены HS512 общим секретом либо, если задан Key, RS256 ключом из JWKS,
+// чтобы другие сервисы могли проверять токены без секрета. Issuer и Audience попадают в iss и aud, если заданы
 type JWTAccessTokenHelper struct {
-	Secret []byte
+	Secret   []byte
+	Key      *SigningKey
+	Issuer   string
+	Audience []string
+	TTL      time.Duration
+}
+
+func NewJWTAccessTokenHelper(secret string, ttl time.Duration) *JWTAccessTokenHelper {
+	return &JWTAccessTokenHelper{Secret: []byte(secret), TTL: ttl}
 }
 
-func NewJWTAccessTokenHelper(secret string) *JWTAccessTokenHelper {
-	return &JWTAccessTokenHelper{Secret: []byte(secret)}
+func NewRSAAccessTokenHelper(key *SigningKey, ttl time.Duration) *JWTAccessTokenHelper {
+	return &JWTAccessTokenHelper{Key: key, TTL: ttl}
 }
 
 func (h *JWTAccessTokenHelper) Generate(payload stateless.AccessTokenPayload) (stateless.AccessToken, error) {
 	claims := jwt.MapClaims{
+		"typ":           tokenTypeAccess,
 		"user_id":       string(payload.UserID),
 		"token_pair_id": string(payload.TokenPairID),
 		"role":          string(payload.Role),
-		"exp":           time.Now().Add(15 * time.Minute).Unix(),
+		"exp":           time.Now().Add(h.TTL).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
-	signed, err := token.SignedString(h.Secret)
+	if payload.ClientID != "" {
+		claims["client_id"] = payload.ClientID
+	}
+	if h.Issuer != "" {
+		claims["iss"] = h.Issuer
+	}
+	if len(h.Audience) > 0 {
+		claims["aud"] = h.Audience
+	}
+	if !payload.MFAVerifiedAt.IsZero() {
+		claims["mfa_at"] = payload.MFAVerifiedAt.Unix()
+	}
+	if !payload.AuthTime.IsZero() {
+		claims["auth_time"] = payload.AuthTime.Unix()
+	}
+	if len(payload.AMR) > 0 {
+		claims["amr"] = payload.AMR
+	}
+	// scope строкой через пробел, как в RFC 9068. Пустой набор тоже пишется, чтобы не путать его с отсутствием claim
+	if payload.Scopes != nil {
+		claims["scope"] = strings.Join(payload.Scopes, " ")
+	}
+	signed, err := h.sign(claims)
 	if err != nil {
 		return "", err
 	}
 	return stateless.AccessToken(signed), nil
 }
 
+func (h *JWTAccessTokenHelper) sign(claims jwt.MapClaims) (string, error) {
+	if h.Key == nil {
+		return sign(claims, h.Secret)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = h.Key.KeyID
+	return token.SignedString(h.Key.Key)
+}
+
+func (h *JWTAccessTokenHelper) parse(token string) (jwt.MapClaims, error) {
+	if h.Key == nil {
+		return parse(token, h.Secret)
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return &h.Key.Key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	return claims, err
+}
+
 func (h *JWTAccessTokenHelper) Validate(token stateless.AccessToken) (stateless.AccessTokenPayload, error) {
+	claims, err := h.parse(string(token))
+	if err != nil && !errors.Is(err, jwt.ErrTokenExpired) {
+		return stateless.AccessTokenPayload{}, stateless.ErrAccessTokenInvalid
+	}
+	// HS512 токены без typ выпущены до появления промежуточных MFA токенов и считаются access.
+	// RS256 ключом подписываются и ID токены, поэтому для него typ обязателен
+	if typ := stringClaim(claims, "typ"); typ != tokenTypeAccess && (typ != "" || h.Key != nil) {
+		return stateless.AccessTokenPayload{}, stateless.ErrAccessTokenInvalid
+	}
+
+	payload := stateless.AccessTokenPayload{
+		UserID:      stateless.UserID(stringClaim(claims, "user_id")),
+		TokenPairID: stateless.TokenPairID(stringClaim(claims, "token_pair_id")),
+		Role:        stateless.UserRole(stringClaim(claims, "role")),
+		ClientID:    stringClaim(claims, "client_id"),
+	}
+	payload.MFAVerifiedAt = timeClaim(claims, "mfa_at")
+	payload.AuthTime = timeClaim(claims, "auth_time")
+	payload.AMR = stringsClaim(claims, "amr")
+	payload.ExpiresAt = timeClaim(claims, "exp")
+	if scope, ok := claims["scope"].(string); ok {
+		payload.Scopes = strings.Fields(scope)
+	}
+
+	if err != nil {
+		return payload, stateless.ErrAccessTokenExpired
+	}
+	return payload, nil
+}
+
+func sign(claims jwt.MapClaims, secret []byte) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
+	return token.SignedString(secret)
+}
+
+// parse проверяет подпись и возвращает claims. При истёкшем токене claims заполнены,
+// а ошибка оборачивает jwt.ErrTokenExpired
+func parse(token string, secret []byte) (jwt.MapClaims, error) {
 	claims := jwt.MapClaims{}
-	t, err := jwt.ParseWithClaims(string(token), claims, func(token *jwt.Token) (interface{}, error) {
+	t, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || token.Method.Alg() != "HS512" {
 			return nil, errors.New("unexpected signing method")
 		}
-		return h.Secret, nil
+		return secret, nil
 	})
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return stateless.AccessTokenPayload{
-				UserID:      stateless.UserID(claims["user_id"].(string)),
-				TokenPairID: stateless.TokenPairID(claims["token_pair_id"].(string)),
-				Role:        stateless.UserRole(claims["role"].(string)),
-			}, stateless.ErrAccessTokenExpired
-		}
-		return stateless.AccessTokenPayload{}, stateless.ErrAccessTokenInvalid
+		return claims, err
 	}
 	if !t.Valid {
-		return stateless.AccessTokenPayload{}, stateless.ErrAccessTokenInvalid
+		return claims, errors.New("token invalid")
+	}
+	return claims, nil
+}
+
+func stringClaim(claims jwt.MapClaims, key string) string {
+	s, _ := claims[key].(string)
+	return s
+}
+
+func timeClaim(claims jwt.MapClaims, key string) time.Time {
+	if v, ok := claims[key].(float64); ok {
+		return time.Unix(int64(v), 0)
+	}
+	return time.Time{}
+}
+
+func stringsClaim(claims jwt.MapClaims, key string) []string {
+	values, _ := claims[key].([]interface{})
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
 	}
-	return stateless.AccessTokenPayload{
-		UserID:      stateless.UserID(claims["user_id"].(string)),
-		TokenPairID: stateless.TokenPairID(claims["token_pair_id"].(string)),
-		Role:        stateless.UserRole(claims["role"].(string)),
-	}, nil
+	return result
 }
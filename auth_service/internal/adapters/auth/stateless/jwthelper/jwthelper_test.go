@@ -0,0 +1,55 @@
+package jwthelper_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"medods_test/internal/adapters/auth/stateless/jwthelper"
+	"medods_test/internal/core/auth/oauth"
+	"medods_test/internal/core/auth/stateless"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TestRSAAccessTokenRequiresType ID токены подписываются тем же RS256 ключом, что и access токены,
+// и не должны приниматься вместо них
+func TestRSAAccessTokenRequiresType(t *testing.T) {
+	key, _, err := jwthelper.LoadSigningKey("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessTokens := jwthelper.NewRSAAccessTokenHelper(key, time.Minute)
+	idTokens := jwthelper.NewJWTIDTokenHelper(key, "https://auth.example.com", time.Minute)
+
+	idToken, err := idTokens.Generate(oauth.IDTokenClaims{Subject: "123e4567-e89b-12d3-a456-426614174000", Audience: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	untyped := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"user_id": "123e4567-e89b-12d3-a456-426614174000",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+	untyped.Header["kid"] = key.KeyID
+	untypedToken, err := untyped.SignedString(key.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"id token": idToken, "token without typ": untypedToken} {
+		if _, err := accessTokens.Validate(stateless.AccessToken(token)); !errors.Is(err, stateless.ErrAccessTokenInvalid) {
+			t.Errorf("%s: err = %v, want ErrAccessTokenInvalid", name, err)
+		}
+	}
+
+	accessToken, err := accessTokens.Generate(stateless.AccessTokenPayload{UserID: "123e4567-e89b-12d3-a456-426614174000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := accessTokens.Validate(accessToken); err != nil {
+		t.Fatalf("access token: %v", err)
+	}
+	if _, err := idTokens.Validate(string(accessToken)); err == nil {
+		t.Fatal("access token accepted as id_token_hint")
+	}
+}
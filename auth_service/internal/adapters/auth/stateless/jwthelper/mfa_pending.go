@@ -0,0 +1,45 @@
+package jwthelper
+
+import (
+	"time"
+
+	"medods_test/internal/core/auth/stateless"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTMFAPendingTokenHelper выпускает короткоживущие промежуточные токены,
+// которые принимает только /auth/mfa/verify
+type JWTMFAPendingTokenHelper struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+func NewJWTMFAPendingTokenHelper(secret string, ttl time.Duration) *JWTMFAPendingTokenHelper {
+	return &JWTMFAPendingTokenHelper{Secret: []byte(secret), TTL: ttl}
+}
+
+func (h *JWTMFAPendingTokenHelper) Generate(payload stateless.MFAPendingPayload) (stateless.MFAPendingToken, error) {
+	claims := jwt.MapClaims{
+		"typ":     tokenTypeMFAPending,
+		"user_id": string(payload.UserID),
+		"ua":      payload.UserAgent,
+		"exp":     time.Now().Add(h.TTL).Unix(),
+	}
+	signed, err := sign(claims, h.Secret)
+	if err != nil {
+		return "", err
+	}
+	return stateless.MFAPendingToken(signed), nil
+}
+
+func (h *JWTMFAPendingTokenHelper) Validate(token stateless.MFAPendingToken) (stateless.MFAPendingPayload, error) {
+	claims, err := parse(string(token), h.Secret)
+	if err != nil || stringClaim(claims, "typ") != tokenTypeMFAPending || stringClaim(claims, "user_id") == "" {
+		return stateless.MFAPendingPayload{}, stateless.ErrMFAPendingTokenInvalid
+	}
+	return stateless.MFAPendingPayload{
+		UserID:    stateless.UserID(stringClaim(claims, "user_id")),
+		UserAgent: stringClaim(claims, "ua"),
+	}, nil
+}
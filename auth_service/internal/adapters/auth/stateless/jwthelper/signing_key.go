@@ -0,0 +1,96 @@
+package jwthelper
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// SigningKey RSA ключ для токенов, которые проверяют сторонние сервисы (ID токены OIDC)
+type SigningKey struct {
+	Key   *rsa.PrivateKey
+	KeyID string
+}
+
+// LoadSigningKey читает ключ из PEM файла (PKCS#1 или PKCS#8).
+// При пустом пути создаётся временный ключ, который живёт до перезапуска сервиса
+func LoadSigningKey(path string) (*SigningKey, bool, error) {
+	if path == "" {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, false, err
+		}
+		return newSigningKey(key), true, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, false, errors.New("signing key: no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return newSigningKey(key), false, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, false, fmt.Errorf("signing key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, false, errors.New("signing key: only RSA keys are supported")
+	}
+	return newSigningKey(key), false, nil
+}
+
+func newSigningKey(key *rsa.PrivateKey) *SigningKey {
+	jwk := publicJWK(&key.PublicKey, "")
+	// kid отпечаток ключа по RFC 7638
+	thumbprint, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{E: jwk.E, Kty: jwk.Kty, N: jwk.N})
+	sum := sha256.Sum256(thumbprint)
+	return &SigningKey{Key: key, KeyID: base64.RawURLEncoding.EncodeToString(sum[:])}
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS публичная часть ключа для /.well-known/jwks.json
+func (k *SigningKey) JWKS() JWKSet {
+	return JWKSet{Keys: []JWK{publicJWK(&k.Key.PublicKey, k.KeyID)}}
+}
+
+func publicJWK(key *rsa.PublicKey, kid string) JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
@@ -3,10 +3,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"medods_test/internal/core/auth/stateless"
 
-	_ "github.com/lib/pq"
-	"fmt"
+	"github.com/lib/pq"
 )
 
 type PostgresAuthRepository struct {
@@ -29,20 +29,53 @@ func NewPostgresAuthRepository(db *sql.DB, refreshHashChecker RefreshHashChecker
 
 func (r *PostgresAuthRepository) SaveSession(ctx context.Context, session stateless.SessionData) error {
 	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO `+r.conf.Prefix+`sls_auth_sessions (user_id, token_pair_id, refresh_hash, user_agent, ip) VALUES ($1, $2, $3, $4, $5)`,
-		session.UserID, session.TokenPairID, session.RefreshHash, session.UserAgent, session.IP)
+		`INSERT INTO `+r.conf.Prefix+`sls_auth_sessions (user_id, session_id, token_pair_id, refresh_hash, user_agent, ip, client_id, auth_time, amr, scopes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		session.UserID, session.SessionID, session.TokenPairID, session.RefreshHash, session.UserAgent, session.IP,
+		sql.NullString{String: session.ClientID, Valid: session.ClientID != ""}, nullTime(session.AuthTime), pq.Array(session.AMR),
+		pq.Array(session.Scopes))
 	return err
 }
 
 func (r *PostgresAuthRepository) DeleteSession(ctx context.Context, userID stateless.UserID, refreshHash string) error {
-	_, err := r.db.ExecContext(ctx,
+	result, err := r.db.ExecContext(ctx,
 		`DELETE FROM `+r.conf.Prefix+`sls_auth_sessions WHERE user_id = $1 AND refresh_hash = $2`, userID, refreshHash)
-	return err
+	return sessionDeleted(result, err)
+}
+
+// DeleteSessionByID для сессий, созданных до появления session_id, идентификатором считается token_pair_id
+func (r *PostgresAuthRepository) DeleteSessionByID(ctx context.Context, userID stateless.UserID, sessionID stateless.SessionID) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM `+r.conf.Prefix+`sls_auth_sessions WHERE user_id = $1 AND COALESCE(session_id, token_pair_id) = $2`, userID, sessionID)
+	return sessionDeleted(result, err)
+}
+
+// sessionDeleted ErrSessionNotFound, если DELETE не затронул ни одной строки
+func sessionDeleted(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return stateless.ErrSessionNotFound
+	}
+	return nil
+}
+
+// CountSessions число активных сессий для метрик
+func (r *PostgresAuthRepository) CountSessions(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM `+r.conf.Prefix+`sls_auth_sessions`).Scan(&count)
+	return count, err
 }
 
 func (r *PostgresAuthRepository) GetSession(ctx context.Context, userID stateless.UserID, refreshToken string) (stateless.SessionData, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT user_id, token_pair_id, refresh_hash, user_agent, ip FROM `+r.conf.Prefix+`sls_auth_sessions WHERE user_id = $1`, userID)
+		`SELECT user_id, COALESCE(session_id, token_pair_id), token_pair_id, refresh_hash, user_agent, ip, COALESCE(client_id, ''), auth_time, amr, scopes
+		FROM `+r.conf.Prefix+`sls_auth_sessions WHERE user_id = $1`, userID)
 	if err != nil {
 		return stateless.SessionData{}, err
 	}
@@ -50,12 +83,52 @@ func (r *PostgresAuthRepository) GetSession(ctx context.Context, userID stateles
 
 	for rows.Next() {
 		var s stateless.SessionData
-		if err := rows.Scan(&s.UserID, &s.TokenPairID, &s.RefreshHash, &s.UserAgent, &s.IP); err != nil {
+		var authTime sql.NullTime
+		if err := rows.Scan(&s.UserID, &s.SessionID, &s.TokenPairID, &s.RefreshHash, &s.UserAgent, &s.IP, &s.ClientID, &authTime, pq.Array(&s.AMR), pq.Array(&s.Scopes)); err != nil {
 			continue
 		}
 		if r.refreshHashChecker.CompareHash(s.RefreshHash, string(refreshToken)) {
+			s.AuthTime = authTime.Time
 			return s, nil
 		}
 	}
-	return stateless.SessionData{}, sql.ErrNoRows
+	return stateless.SessionData{}, stateless.ErrSessionNotFound
+}
+
+func (r *PostgresAuthRepository) GetSessionByTokenPairID(ctx context.Context, userID stateless.UserID, tokenPairID stateless.TokenPairID) (stateless.SessionData, error) {
+	var s stateless.SessionData
+	var authTime sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		`SELECT user_id, COALESCE(session_id, token_pair_id), token_pair_id, refresh_hash, user_agent, ip, COALESCE(client_id, ''), auth_time, amr, scopes
+		FROM `+r.conf.Prefix+`sls_auth_sessions WHERE user_id = $1 AND token_pair_id = $2`, userID, tokenPairID).
+		Scan(&s.UserID, &s.SessionID, &s.TokenPairID, &s.RefreshHash, &s.UserAgent, &s.IP, &s.ClientID, &authTime, pq.Array(&s.AMR), pq.Array(&s.Scopes))
+	if errors.Is(err, sql.ErrNoRows) {
+		return stateless.SessionData{}, stateless.ErrSessionNotFound
+	}
+	s.AuthTime = authTime.Time
+	return s, err
+}
+
+// ListSessions сессии пользователя, сначала самые новые. Сессии без auth_time идут последними
+func (r *PostgresAuthRepository) ListSessions(ctx context.Context, userID stateless.UserID) ([]stateless.SessionData, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT user_id, COALESCE(session_id, token_pair_id), token_pair_id, refresh_hash, user_agent, ip, COALESCE(client_id, ''), auth_time, amr, scopes
+		FROM `+r.conf.Prefix+`sls_auth_sessions WHERE user_id = $1
+		ORDER BY auth_time DESC NULLS LAST`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []stateless.SessionData
+	for rows.Next() {
+		var s stateless.SessionData
+		var authTime sql.NullTime
+		if err := rows.Scan(&s.UserID, &s.SessionID, &s.TokenPairID, &s.RefreshHash, &s.UserAgent, &s.IP, &s.ClientID, &authTime, pq.Array(&s.AMR), pq.Array(&s.Scopes)); err != nil {
+			return nil, err
+		}
+		s.AuthTime = authTime.Time
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
 }
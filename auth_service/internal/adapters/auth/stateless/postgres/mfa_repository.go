@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"medods_test/internal/core/auth/stateless"
+	"time"
+)
+
+type PostgresMFARepository struct {
+	db                 *sql.DB
+	conf               *Config
+	refreshHashChecker RefreshHashChecker
+}
+
+func NewPostgresMFARepository(db *sql.DB, refreshHashChecker RefreshHashChecker, conf *Config) *PostgresMFARepository {
+	return &PostgresMFARepository{db: db, conf: conf, refreshHashChecker: refreshHashChecker}
+}
+
+func (r *PostgresMFARepository) SaveTOTP(ctx context.Context, enrollment stateless.TOTPEnrollment) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`sls_mfa_totp (user_id, secret, confirmed, last_used_step, failed_attempts, locked_until)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, confirmed = EXCLUDED.confirmed,
+			last_used_step = EXCLUDED.last_used_step, failed_attempts = EXCLUDED.failed_attempts, locked_until = EXCLUDED.locked_until`,
+		enrollment.UserID, enrollment.Secret, enrollment.Confirmed, enrollment.LastUsedStep, enrollment.FailedAttempts, nullTime(enrollment.LockedUntil))
+	return err
+}
+
+func (r *PostgresMFARepository) GetTOTP(ctx context.Context, userID stateless.UserID) (stateless.TOTPEnrollment, error) {
+	var e stateless.TOTPEnrollment
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		`SELECT user_id, secret, confirmed, last_used_step, failed_attempts, locked_until FROM `+r.conf.Prefix+`sls_mfa_totp WHERE user_id = $1`, userID).
+		Scan(&e.UserID, &e.Secret, &e.Confirmed, &e.LastUsedStep, &e.FailedAttempts, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return stateless.TOTPEnrollment{}, stateless.ErrMFANotEnrolled
+	}
+	if err != nil {
+		return stateless.TOTPEnrollment{}, err
+	}
+	e.LockedUntil = lockedUntil.Time
+	return e, nil
+}
+
+// ConfirmTOTP подтверждение и коды восстановления сохраняются в одной транзакции
+func (r *PostgresMFARepository) ConfirmTOTP(ctx context.Context, userID stateless.UserID, recoveryCodeHashes []string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE `+r.conf.Prefix+`sls_mfa_totp SET confirmed = TRUE WHERE user_id = $1 AND NOT confirmed`, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+r.conf.Prefix+`sls_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return false, err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO `+r.conf.Prefix+`sls_mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// UseTOTPStep условие на last_used_step и блокировку проверяется в самом UPDATE:
+// из параллельных запросов с одним кодом строку обновит только первый
+func (r *PostgresMFARepository) UseTOTPStep(ctx context.Context, userID stateless.UserID, step int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE `+r.conf.Prefix+`sls_mfa_totp SET last_used_step = $2, failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1 AND last_used_step < $2 AND (locked_until IS NULL OR locked_until <= now())`,
+		userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// RegisterTOTPFailure счётчик увеличивается в базе, параллельные ошибки не перезаписывают друг друга
+func (r *PostgresMFARepository) RegisterTOTPFailure(ctx context.Context, userID stateless.UserID, maxAttempts int, lockUntil time.Time) (bool, error) {
+	var locked bool
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE `+r.conf.Prefix+`sls_mfa_totp SET
+			failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+			locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN $3 ELSE locked_until END
+		WHERE user_id = $1
+		RETURNING failed_attempts = 0`,
+		userID, maxAttempts, lockUntil).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, stateless.ErrMFANotEnrolled
+	}
+	return locked, err
+}
+
+func (r *PostgresMFARepository) DeleteTOTP(ctx context.Context, userID stateless.UserID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+r.conf.Prefix+`sls_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+r.conf.Prefix+`sls_mfa_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode ищет подходящий код и удаляет его, чтобы он не мог быть использован повторно
+func (r *PostgresMFARepository) UseRecoveryCode(ctx context.Context, userID stateless.UserID, code string) (bool, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT code_hash FROM `+r.conf.Prefix+`sls_mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	var matched string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			continue
+		}
+		if r.refreshHashChecker.CompareHash(hash, code) {
+			matched = hash
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	rows.Close()
+
+	if matched == "" {
+		return false, nil
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM `+r.conf.Prefix+`sls_mfa_recovery_codes WHERE user_id = $1 AND code_hash = $2`, userID, matched)
+	if err != nil {
+		return false, err
+	}
+	// при параллельном использовании одного кода выигрывает только один запрос
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"medods_test/internal/core/auth/stateless"
+
+	"github.com/lib/pq"
+)
+
+type PostgresPasskeyRepository struct {
+	db   *sql.DB
+	conf *Config
+}
+
+func NewPostgresPasskeyRepository(db *sql.DB, conf *Config) *PostgresPasskeyRepository {
+	return &PostgresPasskeyRepository{db: db, conf: conf}
+}
+
+const passkeyColumns = `credential_id, user_id, public_key, attestation_type, aaguid, sign_count, transports, flags, attachment, clone_detected`
+
+func (r *PostgresPasskeyRepository) SavePasskey(ctx context.Context, c stateless.PasskeyCredential) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`sls_passkey_credentials (`+passkeyColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		c.ID, c.UserID, c.PublicKey, c.AttestationType, c.AAGUID, int64(c.SignCount), pq.Array(c.Transports), int16(c.Flags), c.Attachment, c.CloneDetected)
+	return err
+}
+
+func (r *PostgresPasskeyRepository) GetPasskey(ctx context.Context, credentialID []byte) (stateless.PasskeyCredential, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+passkeyColumns+` FROM `+r.conf.Prefix+`sls_passkey_credentials WHERE credential_id = $1`, credentialID)
+	c, err := scanPasskey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return stateless.PasskeyCredential{}, stateless.ErrPasskeyNotFound
+	}
+	return c, err
+}
+
+func (r *PostgresPasskeyRepository) ListPasskeys(ctx context.Context, userID stateless.UserID) ([]stateless.PasskeyCredential, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+passkeyColumns+` FROM `+r.conf.Prefix+`sls_passkey_credentials WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []stateless.PasskeyCredential
+	for rows.Next() {
+		c, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// UpdatePasskeyUsage счётчик сравнивается в том же UPDATE, поэтому из параллельных входов
+// с одинаковым счётчиком проходит только один
+func (r *PostgresPasskeyRepository) UpdatePasskeyUsage(ctx context.Context, credentialID []byte, signCount uint32, flags uint8) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE `+r.conf.Prefix+`sls_passkey_credentials SET sign_count = $2, flags = $3, last_used_at = now()
+		WHERE credential_id = $1 AND NOT clone_detected AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0))`,
+		credentialID, int64(signCount), int16(flags))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *PostgresPasskeyRepository) MarkPasskeyCloned(ctx context.Context, credentialID []byte) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE `+r.conf.Prefix+`sls_passkey_credentials SET clone_detected = TRUE WHERE credential_id = $1`, credentialID)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPasskey(row rowScanner) (stateless.PasskeyCredential, error) {
+	var c stateless.PasskeyCredential
+	var signCount int64
+	var flags int16
+	err := row.Scan(&c.ID, &c.UserID, &c.PublicKey, &c.AttestationType, &c.AAGUID, &signCount,
+		pq.Array(&c.Transports), &flags, &c.Attachment, &c.CloneDetected)
+	c.SignCount = uint32(signCount)
+	c.Flags = uint8(flags)
+	return c, err
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"medods_test/internal/core/auth/stateless"
+	"time"
+)
+
+// PostgresWebAuthnCeremonyRepository хранит состояние незавершённых WebAuthn церемоний между begin и finish
+type PostgresWebAuthnCeremonyRepository struct {
+	db   *sql.DB
+	conf *Config
+}
+
+func NewPostgresWebAuthnCeremonyRepository(db *sql.DB, conf *Config) *PostgresWebAuthnCeremonyRepository {
+	return &PostgresWebAuthnCeremonyRepository{db: db, conf: conf}
+}
+
+func (r *PostgresWebAuthnCeremonyRepository) SaveCeremony(ctx context.Context, id string, userID stateless.UserID, data []byte, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`sls_webauthn_ceremonies (id, user_id, data, expires_at) VALUES ($1, $2, $3, $4)`,
+		id, sql.NullString{String: string(userID), Valid: userID != ""}, data, expiresAt)
+	return err
+}
+
+// TakeCeremony возвращает и удаляет церемонию, так что challenge нельзя использовать повторно
+func (r *PostgresWebAuthnCeremonyRepository) TakeCeremony(ctx context.Context, id string) (stateless.UserID, []byte, error) {
+	var userID sql.NullString
+	var data []byte
+	err := r.db.QueryRowContext(ctx,
+		`DELETE FROM `+r.conf.Prefix+`sls_webauthn_ceremonies WHERE id = $1 AND expires_at > now() RETURNING user_id, data`, id).
+		Scan(&userID, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, stateless.ErrPasskeyCeremonyNotFound
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return stateless.UserID(userID.String), data, nil
+}
@@ -0,0 +1,303 @@
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	statelessauthhttp "medods_test/internal/adapters/auth/stateless/http"
+	"medods_test/internal/core/auth/principal"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/getip"
+	"medods_test/pkg/httperror"
+	"medods_test/pkg/httpjson"
+	"medods_test/pkg/router"
+	"medods_test/pkg/validate"
+	"net/http"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	gowebauthn "github.com/go-webauthn/webauthn/webauthn"
+)
+
+// CeremonyStore хранит данные церемонии между begin и finish запросами
+type CeremonyStore interface {
+	SaveCeremony(ctx context.Context, id string, userID stateless.UserID, data []byte, expiresAt time.Time) error
+	TakeCeremony(ctx context.Context, id string) (stateless.UserID, []byte, error)
+}
+
+type Config struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+	CeremonyTTL   time.Duration
+}
+
+type Handler struct {
+	service           stateless.StatelessAuthService
+	middlewareFactory statelessauthhttp.MiddlewareFactory
+	webauthn          *gowebauthn.WebAuthn
+	ceremonies        CeremonyStore
+	idGenerator       stateless.StringIdGenerator
+	ceremonyTTL       time.Duration
+	logger            *slog.Logger
+}
+
+func NewHandler(service stateless.StatelessAuthService, authMiddlewareFactory statelessauthhttp.MiddlewareFactory, ceremonies CeremonyStore, idGenerator stateless.StringIdGenerator, conf Config, logger *slog.Logger) (*Handler, error) {
+	w, err := gowebauthn.New(&gowebauthn.Config{
+		RPID:          conf.RPID,
+		RPDisplayName: conf.RPDisplayName,
+		RPOrigins:     conf.RPOrigins,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		service:           service,
+		middlewareFactory: authMiddlewareFactory,
+		webauthn:          w,
+		ceremonies:        ceremonies,
+		idGenerator:       idGenerator,
+		ceremonyTTL:       conf.CeremonyTTL,
+		logger:            logger,
+	}, nil
+}
+
+func (h *Handler) RegisterRoutes(rt *router.Router) {
+	rt.HandleFunc("POST /auth/passkey/register/begin", h.middlewareFactory.RequireScopes(h.handleRegisterBegin, stateless.ScopePasskeysManage))
+	rt.HandleFunc("POST /auth/passkey/register/finish", h.middlewareFactory.RequireScopes(h.handleRegisterFinish, stateless.ScopePasskeysManage))
+	rt.HandleFunc("POST /auth/passkey/login/begin", h.handleLoginBegin)
+	rt.HandleFunc("POST /auth/passkey/login/finish", h.handleLoginFinish)
+}
+
+type BeginResponse struct {
+	CeremonyID string `json:"ceremony_id"`
+	// Options передаются в navigator.credentials.create() / get() без изменений
+	Options any `json:"options" swaggertype:"object"`
+}
+
+type FinishRequest struct {
+	CeremonyID string          `json:"ceremony_id" format:"uuid"`
+	Credential json.RawMessage `json:"credential" swaggertype:"object"`
+}
+
+func (req FinishRequest) Validate() error {
+	var v validate.Validator
+	v.UUID("ceremony_id", req.CeremonyID)
+	if len(req.Credential) == 0 || string(req.Credential) == "null" {
+		v.Add("credential", validate.CodeRequired, "is required")
+	}
+	return v.Err()
+}
+
+// handleRegisterBegin godoc
+// @Summary Начало регистрации passkey
+// @Description Возвращает параметры для navigator.credentials.create() и идентификатор церемонии
+// @Tags passkey
+// @Produce json
+// @Success 200 {object} BeginResponse
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Router /v1/auth/passkey/register/begin [post]
+// @Security Bearer
+func (h *Handler) handleRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	userID := caller.UserID
+	user, err := h.loadUser(r.Context(), userID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	creation, session, err := h.webauthn.BeginRegistration(user,
+		gowebauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		gowebauthn.WithExclusions(gowebauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+	)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.writeBegin(w, r, userID, creation, session)
+}
+
+// handleRegisterFinish godoc
+// @Summary Завершение регистрации passkey
+// @Description Проверяет ответ аутентификатора и сохраняет ключ за текущим пользователем
+// @Tags passkey
+// @Accept json
+// @Param request body FinishRequest true "Идентификатор церемонии и ответ navigator.credentials.create()"
+// @Success 200 {string} string "ok"
+// @Failure 400 {object} httpjson.ValidationProblem "validation_failed, bad_request или webauthn_verification_failed"
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Router /v1/auth/passkey/register/finish [post]
+// @Security Bearer
+func (h *Handler) handleRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	req, session, ceremonyUserID, ok := h.readFinish(w, r)
+	if !ok {
+		return
+	}
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
+		return
+	}
+	userID := caller.UserID
+	if ceremonyUserID != userID {
+		httperror.Write(w, r, http.StatusBadRequest, statelessauthhttp.CodePasskeyCeremonyNotFound, stateless.ErrPasskeyCeremonyNotFound.Error())
+		return
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	user, err := h.loadUser(r.Context(), userID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	credential, err := h.webauthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if err := h.service.RegisterPasskey(r.Context(), fromLibraryCredential(userID, credential)); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleLoginBegin godoc
+// @Summary Начало входа по passkey
+// @Description Возвращает параметры для navigator.credentials.get(). Пользователь определяется по выбранному ключу
+// @Tags passkey
+// @Produce json
+// @Success 200 {object} BeginResponse
+// @Router /v1/auth/passkey/login/begin [post]
+func (h *Handler) handleLoginBegin(w http.ResponseWriter, r *http.Request) {
+	assertion, session, err := h.webauthn.BeginDiscoverableLogin(
+		gowebauthn.WithUserVerification(protocol.VerificationPreferred),
+	)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.writeBegin(w, r, "", assertion, session)
+}
+
+// handleLoginFinish godoc
+// @Summary Завершение входа по passkey
+// @Description Проверяет подпись аутентификатора и выдаёт пару токенов.
+// @Description Если счётчик подписей не вырос, ключ блокируется как склонированный
+// @Tags passkey
+// @Accept json
+// @Produce json
+// @Param request body FinishRequest true "Идентификатор церемонии и ответ navigator.credentials.get()"
+// @Success 200 {object} stateless.TokenPair
+// @Failure 400 {object} httpjson.ValidationProblem "validation_failed, bad_request или webauthn_verification_failed"
+// @Failure 401 {object} httperror.Problem "passkey_cloned"
+// @Router /v1/auth/passkey/login/finish [post]
+func (h *Handler) handleLoginFinish(w http.ResponseWriter, r *http.Request) {
+	req, session, _, ok := h.readFinish(w, r)
+	if !ok {
+		return
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	var userID stateless.UserID
+	_, credential, err := h.webauthn.ValidatePasskeyLogin(func(rawID, userHandle []byte) (gowebauthn.User, error) {
+		userID = stateless.UserID(userHandle)
+		return h.loadUser(r.Context(), userID)
+	}, session, parsed)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	tokens, err := h.service.AuthenticateWithPasskey(r.Context(), stateless.PasskeyLoginCommand{
+		CredentialID: credential.ID,
+		SignCount:    credential.Authenticator.SignCount,
+		Flags:        uint8(credential.Flags.ProtocolValue()),
+		UserVerified: credential.Flags.UserVerified,
+		UserAgent:    r.UserAgent(),
+		IP:           getip.GetIP(r),
+	})
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "passkey login", "user_id", userID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}{
+		AccessToken:  string(tokens.AccessToken),
+		RefreshToken: string(tokens.RefreshToken),
+	})
+}
+
+func (h *Handler) loadUser(ctx context.Context, userID stateless.UserID) (*passkeyUser, error) {
+	passkeys, err := h.service.ListPasskeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return newPasskeyUser(userID, passkeys), nil
+}
+
+func (h *Handler) writeBegin(w http.ResponseWriter, r *http.Request, userID stateless.UserID, options any, session *gowebauthn.SessionData) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	ceremonyID, err := h.idGenerator.Generate()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if err := h.ceremonies.SaveCeremony(r.Context(), ceremonyID, userID, data, time.Now().Add(h.ceremonyTTL)); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BeginResponse{CeremonyID: ceremonyID, Options: options})
+}
+
+func (h *Handler) readFinish(w http.ResponseWriter, r *http.Request) (FinishRequest, gowebauthn.SessionData, stateless.UserID, bool) {
+	var session gowebauthn.SessionData
+	req, err := httpjson.Decode[FinishRequest](w, r, 0)
+	if err != nil {
+		httpjson.WriteError(w, r, err)
+		return req, session, "", false
+	}
+	userID, data, err := h.ceremonies.TakeCeremony(r.Context(), req.CeremonyID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return req, session, "", false
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		h.writeError(w, r, err)
+		return req, session, "", false
+	}
+	return req, session, userID, true
+}
+
+// CodeWebAuthnVerificationFailed ответ аутентификатора не прошёл проверку
+const CodeWebAuthnVerificationFailed = "webauthn_verification_failed"
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) {
+		httperror.Write(w, r, http.StatusBadRequest, CodeWebAuthnVerificationFailed, protocolErr.Details)
+		h.logger.WarnContext(r.Context(), "webauthn ceremony failed", "error", protocolErr.DevInfo)
+		return
+	}
+	statelessauthhttp.WriteError(w, r, h.logger, "passkey operation failed", err)
+}
@@ -0,0 +1,362 @@
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	statelessauthhttp "medods_test/internal/adapters/auth/stateless/http"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/auth/stateless/statelesstest"
+	"medods_test/pkg/guidgenerator"
+	"medods_test/pkg/router"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:8080"
+	testUserID = stateless.UserID("123e4567-e89b-12d3-a456-426614174000")
+)
+
+var b64 = base64.RawURLEncoding
+
+// softwareAuthenticator ES256 аутентификатор без аттестации, счётчик подписей задаётся тестом
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+	return &softwareAuthenticator{key: key, credentialID: credentialID}
+}
+
+type ceremonyOptions struct {
+	PublicKey struct {
+		Challenge string `json:"challenge"`
+		User      struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	} `json:"publicKey"`
+}
+
+func (a *softwareAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func clientData(t *testing.T, ceremonyType, challenge string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{"type": ceremonyType, "challenge": challenge, "origin": testOrigin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// create отвечает на navigator.credentials.create()
+func (a *softwareAuthenticator) create(t *testing.T, options ceremonyOptions) json.RawMessage {
+	t.Helper()
+	userHandle, err := b64.DecodeString(options.PublicKey.User.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.userHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{KeyType: int64(webauthncose.EllipticKey), Algorithm: int64(webauthncose.AlgES256)},
+		Curve:         1, // P-256
+		XCoord:        a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		YCoord:        a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attested := make([]byte, 16) // нулевой AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	// UP | UV | AT
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(0x45, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    b64.EncodeToString(clientData(t, "webauthn.create", options.PublicKey.Challenge)),
+		"attestationObject": b64.EncodeToString(attestationObject),
+	})
+}
+
+// get отвечает на navigator.credentials.get() с текущим значением счётчика
+func (a *softwareAuthenticator) get(t *testing.T, options ceremonyOptions) json.RawMessage {
+	t.Helper()
+	authData := a.authenticatorData(0x05, nil) // UP | UV
+	clientDataJSON := clientData(t, "webauthn.get", options.PublicKey.Challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    b64.EncodeToString(clientDataJSON),
+		"authenticatorData": b64.EncodeToString(authData),
+		"signature":         b64.EncodeToString(signature),
+		"userHandle":        b64.EncodeToString(a.userHandle),
+	})
+}
+
+func (a *softwareAuthenticator) credential(t *testing.T, response map[string]string) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"id":       b64.EncodeToString(a.credentialID),
+		"rawId":    b64.EncodeToString(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+type memoryCeremonies struct {
+	mu   sync.Mutex
+	data map[string]ceremony
+}
+
+type ceremony struct {
+	userID stateless.UserID
+	data   []byte
+}
+
+func (m *memoryCeremonies) SaveCeremony(_ context.Context, id string, userID stateless.UserID, data []byte, _ time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[id] = ceremony{userID: userID, data: data}
+	return nil
+}
+
+func (m *memoryCeremonies) TakeCeremony(_ context.Context, id string) (stateless.UserID, []byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.data[id]
+	if !ok {
+		return "", nil, stateless.ErrPasskeyCeremonyNotFound
+	}
+	delete(m.data, id)
+	return c.userID, c.data, nil
+}
+
+type passkeyFixture struct {
+	t           *testing.T
+	svc         *statelesstest.Service
+	server      *httptest.Server
+	accessToken string
+}
+
+func newPasskeyFixture(t *testing.T) *passkeyFixture {
+	t.Helper()
+	svc := statelesstest.NewService(t, statelesstest.Options{})
+	handler, err := NewHandler(*svc.StatelessAuthService,
+		*statelessauthhttp.NewMiddlewareFactory(svc.AccessTokens, nil, time.Minute),
+		&memoryCeremonies{data: map[string]ceremony{}},
+		guidgenerator.GuidGenerator{},
+		Config{RPID: testRPID, RPDisplayName: "Test", RPOrigins: []string{testOrigin}, CeremonyTTL: time.Minute},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(router.New(mux, router.Config{Prefix: "/v1"}))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tokens, err := svc.TestAuthenticateUser(context.Background(), stateless.TestAuthCommand{UserId: testUserID, UserAgent: "test", IP: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &passkeyFixture{t: t, svc: svc, server: server, accessToken: string(tokens.AccessToken)}
+}
+
+func (f *passkeyFixture) post(path string, body any, authorized bool) *http.Response {
+	f.t.Helper()
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(http.MethodPost, f.server.URL+"/v1"+path, reader)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorized {
+		req.Header.Set("Authorization", "Bearer "+f.accessToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func (f *passkeyFixture) begin(path string, authorized bool) (string, ceremonyOptions) {
+	f.t.Helper()
+	resp := f.post(path, nil, authorized)
+	if resp.StatusCode != http.StatusOK {
+		f.t.Fatalf("%s: status %d", path, resp.StatusCode)
+	}
+	var begin struct {
+		CeremonyID string          `json:"ceremony_id"`
+		Options    ceremonyOptions `json:"options"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&begin); err != nil {
+		f.t.Fatal(err)
+	}
+	return begin.CeremonyID, begin.Options
+}
+
+func (f *passkeyFixture) register(authenticator *softwareAuthenticator) {
+	f.t.Helper()
+	ceremonyID, options := f.begin("/auth/passkey/register/begin", true)
+	resp := f.post("/auth/passkey/register/finish", FinishRequest{CeremonyID: ceremonyID, Credential: authenticator.create(f.t, options)}, true)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		f.t.Fatalf("register finish: status %d: %s", resp.StatusCode, body)
+	}
+}
+
+func (f *passkeyFixture) login(authenticator *softwareAuthenticator) *http.Response {
+	f.t.Helper()
+	ceremonyID, options := f.begin("/auth/passkey/login/begin", false)
+	return f.post("/auth/passkey/login/finish", FinishRequest{CeremonyID: ceremonyID, Credential: authenticator.get(f.t, options)}, false)
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	f := newPasskeyFixture(t)
+	authenticator := newSoftwareAuthenticator(t)
+	f.register(authenticator)
+
+	passkeys, err := f.svc.ListPasskeys(context.Background(), testUserID)
+	if err != nil || len(passkeys) != 1 || !bytes.Equal(passkeys[0].ID, authenticator.credentialID) {
+		t.Fatalf("stored passkeys = %v, %v", passkeys, err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		authenticator.signCount = uint32(i)
+		resp := f.login(authenticator)
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("login %d: status %d: %s", i, resp.StatusCode, body)
+		}
+		var tokens struct {
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil || tokens.AccessToken == "" {
+			t.Fatalf("login %d: no access token: %v", i, err)
+		}
+	}
+}
+
+func TestPasskeyRegressedSignCountIsRejectedAsClone(t *testing.T) {
+	f := newPasskeyFixture(t)
+	authenticator := newSoftwareAuthenticator(t)
+	f.register(authenticator)
+
+	authenticator.signCount = 5
+	if resp := f.login(authenticator); resp.StatusCode != http.StatusOK {
+		t.Fatalf("first login: status %d", resp.StatusCode)
+	}
+
+	// копия ключа с отставшим счётчиком
+	authenticator.signCount = 3
+	resp := f.login(authenticator)
+	var problem struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(resp.Body).Decode(&problem)
+	if resp.StatusCode != http.StatusUnauthorized || problem.Code != statelessauthhttp.CodePasskeyCloned {
+		t.Fatalf("regressed sign count: status %d code %q, want 401 %s", resp.StatusCode, problem.Code, statelessauthhttp.CodePasskeyCloned)
+	}
+
+	// после обнаружения клона ключ заблокирован и с правильным счётчиком
+	authenticator.signCount = 6
+	if resp := f.login(authenticator); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("login with cloned key: status %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestPasskeyConcurrentLoginsWithSameSignCount(t *testing.T) {
+	f := newPasskeyFixture(t)
+	authenticator := newSoftwareAuthenticator(t)
+	f.register(authenticator)
+	authenticator.signCount = 1
+
+	// два ответа с одинаковым счётчиком, проверенные подписью: пройти может только один
+	type attempt struct {
+		ceremonyID string
+		credential json.RawMessage
+	}
+	attempts := make([]attempt, 2)
+	for i := range attempts {
+		ceremonyID, options := f.begin("/auth/passkey/login/begin", false)
+		attempts[i] = attempt{ceremonyID: ceremonyID, credential: authenticator.get(t, options)}
+	}
+
+	statuses := make([]int, len(attempts))
+	var wg sync.WaitGroup
+	for i, a := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = f.post("/auth/passkey/login/finish", FinishRequest{CeremonyID: a.ceremonyID, Credential: a.credential}, false).StatusCode
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, status := range statuses {
+		if status == http.StatusOK {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("login statuses = %v, want exactly one 200", statuses)
+	}
+}
@@ -0,0 +1,75 @@
+package webauthn
+
+import (
+	"medods_test/internal/core/auth/stateless"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	gowebauthn "github.com/go-webauthn/webauthn/webauthn"
+)
+
+// passkeyUser адаптирует пользователя и его ключи к интерфейсу библиотеки
+type passkeyUser struct {
+	id          stateless.UserID
+	credentials []gowebauthn.Credential
+}
+
+func newPasskeyUser(id stateless.UserID, passkeys []stateless.PasskeyCredential) *passkeyUser {
+	credentials := make([]gowebauthn.Credential, 0, len(passkeys))
+	for _, p := range passkeys {
+		credentials = append(credentials, toLibraryCredential(p))
+	}
+	return &passkeyUser{id: id, credentials: credentials}
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	return []byte(u.id)
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return string(u.id)
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return string(u.id)
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []gowebauthn.Credential {
+	return u.credentials
+}
+
+func toLibraryCredential(p stateless.PasskeyCredential) gowebauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, 0, len(p.Transports))
+	for _, t := range p.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(t))
+	}
+	return gowebauthn.Credential{
+		ID:              p.ID,
+		PublicKey:       p.PublicKey,
+		AttestationType: p.AttestationType,
+		Transport:       transports,
+		Flags:           gowebauthn.NewCredentialFlags(protocol.AuthenticatorFlags(p.Flags)),
+		Authenticator: gowebauthn.Authenticator{
+			AAGUID:     p.AAGUID,
+			SignCount:  p.SignCount,
+			Attachment: protocol.AuthenticatorAttachment(p.Attachment),
+		},
+	}
+}
+
+func fromLibraryCredential(userID stateless.UserID, c *gowebauthn.Credential) stateless.PasskeyCredential {
+	transports := make([]string, 0, len(c.Transport))
+	for _, t := range c.Transport {
+		transports = append(transports, string(t))
+	}
+	return stateless.PasskeyCredential{
+		ID:              c.ID,
+		UserID:          userID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		AAGUID:          c.Authenticator.AAGUID,
+		SignCount:       c.Authenticator.SignCount,
+		Transports:      transports,
+		Flags:           uint8(c.Flags.ProtocolValue()),
+		Attachment:      string(c.Authenticator.Attachment),
+	}
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"encoding/json"
+	"medods_test/internal/core/auth/stateless"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType тип тела в structured режиме
+	CloudEventsContentType = "application/cloudevents+json"
+	// CloudEventTypePrefix префикс типа CloudEvents, например auth.session.ip_changed
+	CloudEventTypePrefix = "auth."
+)
+
+// CloudEvent событие в формате CloudEvents 1.0. Subject — ID пользователя,
+// correlationid, sessionid и tokenpairid — расширения с общими полями события
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	SessionID       string          `json:"sessionid,omitempty"`
+	TokenPairID     string          `json:"tokenpairid,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// NewCloudEvent source — URI-ссылка на сервис, одинаковая для всех его событий
+func NewCloudEvent(event stateless.Event, source string) (CloudEvent, error) {
+	data, err := EventData(event)
+	if err != nil {
+		return CloudEvent{}, err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return CloudEvent{}, err
+	}
+	meta := event.Metadata()
+	return CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              eventID(meta),
+		Source:          source,
+		Type:            CloudEventTypePrefix + string(event.EventType()),
+		Subject:         string(meta.UserID),
+		Time:            meta.OccurredAt,
+		DataContentType: "application/json",
+		CorrelationID:   meta.CorrelationID,
+		SessionID:       string(meta.SessionID),
+		TokenPairID:     string(meta.TokenPairID),
+		Data:            raw,
+	}, nil
+}
+
+// BinaryHeaders атрибуты для binary режима: заголовки ce-* и Content-Type, тело — Data.
+// Имена одинаковы для HTTP и NATS
+func (e CloudEvent) BinaryHeaders() map[string]string {
+	headers := map[string]string{
+		"ce-specversion": e.SpecVersion,
+		"ce-id":          e.ID,
+		"ce-source":      e.Source,
+		"ce-type":        e.Type,
+		"ce-time":        e.Time.UTC().Format(time.RFC3339Nano),
+		"Content-Type":   e.DataContentType,
+	}
+	optional := map[string]string{
+		"ce-subject":       e.Subject,
+		"ce-correlationid": e.CorrelationID,
+		"ce-sessionid":     e.SessionID,
+		"ce-tokenpairid":   e.TokenPairID,
+	}
+	for name, value := range optional {
+		if value != "" {
+			headers[name] = value
+		}
+	}
+	return headers
+}
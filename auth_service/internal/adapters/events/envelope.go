@@ -0,0 +1,117 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"medods_test/internal/core/auth/stateless"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EnvelopeVersion версия формата Envelope. Меняется только при несовместимых изменениях,
+// новые поля добавляются без смены версии, и потребители должны игнорировать незнакомые
+const EnvelopeVersion = 1
+
+// Envelope внешнее представление события для брокера
+type Envelope struct {
+	Version       int             `json:"version"`
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	UserID        string          `json:"user_id"`
+	SessionID     string          `json:"session_id,omitempty"`
+	TokenPairID   string          `json:"token_pair_id,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+type sessionCreatedData struct {
+	ClientID  string   `json:"client_id,omitempty"`
+	UserAgent string   `json:"user_agent"`
+	IP        string   `json:"ip"`
+	AMR       []string `json:"amr"`
+}
+
+type sessionRefreshedData struct {
+	PreviousTokenPairID string `json:"previous_token_pair_id"`
+	ClientID            string `json:"client_id,omitempty"`
+	UserAgent           string `json:"user_agent"`
+	IP                  string `json:"ip"`
+}
+
+type sessionRevokedData struct {
+	Reason string `json:"reason"`
+}
+
+type userAgentMismatchData struct {
+	ExpectedUserAgent string `json:"expected_user_agent"`
+	ActualUserAgent   string `json:"actual_user_agent"`
+	IP                string `json:"ip"`
+}
+
+type logoutPerformedData struct {
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+}
+
+type userIPChangedData struct {
+	OldIP string `json:"old_ip"`
+	NewIP string `json:"new_ip"`
+}
+
+// EventData данные события без общих полей, в формате, который видят внешние потребители
+func EventData(event stateless.Event) (any, error) {
+	switch e := event.(type) {
+	case stateless.SessionCreated:
+		return sessionCreatedData{ClientID: e.ClientID, UserAgent: e.UserAgent, IP: e.IP, AMR: e.AMR}, nil
+	case stateless.SessionRefreshed:
+		return sessionRefreshedData{
+			PreviousTokenPairID: string(e.PreviousTokenPairID),
+			ClientID:            e.ClientID,
+			UserAgent:           e.UserAgent,
+			IP:                  e.IP,
+		}, nil
+	case stateless.SessionRevoked:
+		return sessionRevokedData{Reason: string(e.Reason)}, nil
+	case stateless.UserAgentMismatch:
+		return userAgentMismatchData{ExpectedUserAgent: e.ExpectedUserAgent, ActualUserAgent: e.ActualUserAgent, IP: e.IP}, nil
+	case stateless.LogoutPerformed:
+		return logoutPerformedData{UserAgent: e.UserAgent, IP: e.IP}, nil
+	case stateless.UserIPChangedEvent:
+		return userIPChangedData{OldIP: e.OldIP, NewIP: e.NewIP}, nil
+	default:
+		return nil, fmt.Errorf("unknown event type %T", event)
+	}
+}
+
+// NewEnvelope если у события нет ID, он генерируется, чтобы потребители могли отбрасывать повторы
+func NewEnvelope(event stateless.Event) (Envelope, error) {
+	data, err := EventData(event)
+	if err != nil {
+		return Envelope{}, err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Envelope{}, err
+	}
+	meta := event.Metadata()
+	return Envelope{
+		Version:       EnvelopeVersion,
+		ID:            eventID(meta),
+		Type:          string(event.EventType()),
+		OccurredAt:    meta.OccurredAt,
+		CorrelationID: meta.CorrelationID,
+		UserID:        string(meta.UserID),
+		SessionID:     string(meta.SessionID),
+		TokenPairID:   string(meta.TokenPairID),
+		Data:          raw,
+	}, nil
+}
+
+func eventID(meta stateless.EventMeta) string {
+	if meta.ID == "" {
+		return uuid.NewString()
+	}
+	return meta.ID
+}
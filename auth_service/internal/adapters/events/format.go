@@ -0,0 +1,80 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"medods_test/internal/core/auth/stateless"
+)
+
+// Format формат сообщения, выбирается для каждой подписки отдельно
+type Format string
+
+const (
+	// FormatJSON собственный формат: Envelope в брокере, прежнее тело у вебхуков
+	FormatJSON Format = "json"
+	// FormatCloudEvents CloudEvents в structured режиме: всё событие в теле
+	FormatCloudEvents Format = "cloudevents"
+	// FormatCloudEventsBinary CloudEvents в binary режиме: атрибуты в заголовках ce-*, в теле только данные
+	FormatCloudEventsBinary Format = "cloudevents-binary"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case "":
+		return FormatJSON, nil
+	case FormatJSON, FormatCloudEvents, FormatCloudEventsBinary:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported event format %q", s)
+	}
+}
+
+// Message закодированное событие для транспорта с заголовками
+type Message struct {
+	ID     string
+	Header map[string]string
+	Body   []byte
+}
+
+// Encoder кодирует событие в выбранный формат
+type Encoder struct {
+	format Format
+	source string
+}
+
+func NewEncoder(format Format, source string) *Encoder {
+	return &Encoder{format: format, source: source}
+}
+
+// Encode для FormatJSON тело — Envelope
+func (e *Encoder) Encode(event stateless.Event) (Message, error) {
+	switch e.format {
+	case FormatCloudEvents, FormatCloudEventsBinary:
+		return e.encodeCloudEvent(event)
+	default:
+		envelope, err := NewEnvelope(event)
+		if err != nil {
+			return Message{}, err
+		}
+		body, err := json.Marshal(envelope)
+		if err != nil {
+			return Message{}, err
+		}
+		return Message{ID: envelope.ID, Header: map[string]string{"Content-Type": "application/json"}, Body: body}, nil
+	}
+}
+
+func (e *Encoder) encodeCloudEvent(event stateless.Event) (Message, error) {
+	ce, err := NewCloudEvent(event, e.source)
+	if err != nil {
+		return Message{}, err
+	}
+	if e.format == FormatCloudEventsBinary {
+		return Message{ID: ce.ID, Header: ce.BinaryHeaders(), Body: ce.Data}, nil
+	}
+	body, err := json.Marshal(ce)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{ID: ce.ID, Header: map[string]string{"Content-Type": CloudEventsContentType}, Body: body}, nil
+}
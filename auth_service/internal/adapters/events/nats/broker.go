@@ -0,0 +1,75 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"medods_test/internal/adapters/events/outbox"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type Config struct {
+	URL    string
+	Stream string
+	// Subjects темы, которые хранит поток, например auth.>
+	Subjects []string
+	// DuplicateWindow в течение этого окна JetStream отбрасывает повторы с тем же Nats-Msg-Id
+	DuplicateWindow time.Duration
+}
+
+// Broker публикует события в поток JetStream. ID сообщения передаётся как Nats-Msg-Id,
+// поэтому повторная отправка после сбоя не создаёт дубль в пределах DuplicateWindow
+type Broker struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+// Connect подключается к серверу и создаёт поток, если его нет
+func Connect(ctx context.Context, conf Config) (*Broker, error) {
+	conn, err := nats.Connect(conf.URL, nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       conf.Stream,
+		Subjects:   conf.Subjects,
+		Storage:    jetstream.FileStorage,
+		Duplicates: conf.DuplicateWindow,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to ensure stream %s: %w", conf.Stream, err)
+	}
+	return &Broker{conn: conn, js: js}, nil
+}
+
+// Publish заголовки передаются без канонизации имён: в binary режиме CloudEvents атрибуты ce-* в нижнем регистре
+func (b *Broker) Publish(ctx context.Context, m outbox.Message) error {
+	msg := nats.NewMsg(m.Subject)
+	msg.Data = m.Payload
+	for name, value := range m.Header {
+		msg.Header[name] = []string{value}
+	}
+	_, err := b.js.PublishMsg(ctx, msg, jetstream.WithMsgID(m.ID))
+	return err
+}
+
+// Ping проверяет соединение с сервером
+func (b *Broker) Ping() error {
+	if !b.conn.IsConnected() {
+		return fmt.Errorf("nats connection is %s", b.conn.Status())
+	}
+	return nil
+}
+
+// Close отправляет буферизованные данные и закрывает соединение
+func (b *Broker) Close() error {
+	return b.conn.Drain()
+}
@@ -0,0 +1,272 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"medods_test/internal/adapters/events"
+	"medods_test/internal/adapters/events/outbox"
+	"medods_test/internal/core/auth/stateless"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const testStream = "AUTH_EVENTS_TEST"
+
+func runServer(t *testing.T) *server.Server {
+	t.Helper()
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	s := natstest.RunServer(&opts)
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func connect(t *testing.T, s *server.Server) *Broker {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker, err := Connect(ctx, Config{
+		URL:             s.ClientURL(),
+		Stream:          testStream,
+		Subjects:        []string{outbox.Subject("auth", ">")},
+		DuplicateWindow: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+	return broker
+}
+
+// memoryOutbox outbox в памяти с той же семантикой, что и postgres: сообщение отмечается отправленным
+// только после успешного send, иначе откладывается до retryAt
+type memoryOutbox struct {
+	mu       sync.Mutex
+	messages []*storedMessage
+	// failMarkSent имитирует падение после подтверждения брокера, но до записи sent_at
+	failMarkSent int
+}
+
+type storedMessage struct {
+	outbox.Message
+	sent      bool
+	nextRetry time.Time
+}
+
+func (o *memoryOutbox) Add(_ context.Context, msg outbox.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, &storedMessage{Message: msg})
+	return nil
+}
+
+func (o *memoryOutbox) Process(ctx context.Context, limit int, send func(ctx context.Context, msg outbox.Message) error, retryAt func(attempts int) time.Time) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	sent := 0
+	for _, m := range o.messages {
+		if sent == limit {
+			break
+		}
+		if m.sent || time.Now().Before(m.nextRetry) {
+			continue
+		}
+		if err := send(ctx, m.Message); err != nil {
+			m.Attempts++
+			m.nextRetry = retryAt(m.Attempts)
+			continue
+		}
+		if o.failMarkSent > 0 {
+			o.failMarkSent--
+			continue
+		}
+		m.sent = true
+		sent++
+	}
+	return sent, nil
+}
+
+func (o *memoryOutbox) PurgeSent(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (o *memoryOutbox) pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	pending := 0
+	for _, m := range o.messages {
+		if !m.sent {
+			pending++
+		}
+	}
+	return pending
+}
+
+// flakyBroker отказывает первые failures публикаций, как недоступный брокер
+type flakyBroker struct {
+	outbox.Broker
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (b *flakyBroker) Publish(ctx context.Context, msg outbox.Message) error {
+	b.mu.Lock()
+	b.calls++
+	fail := b.calls <= b.failures
+	b.mu.Unlock()
+	if fail {
+		return errors.New("broker unavailable")
+	}
+	return b.Broker.Publish(ctx, msg)
+}
+
+func (b *flakyBroker) publishCalls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls
+}
+
+func runRelay(t *testing.T, repo outbox.Repository, broker outbox.Broker) {
+	t.Helper()
+	relay := outbox.NewRelay(repo, broker, outbox.RelayConfig{
+		PollInterval: 10 * time.Millisecond,
+		BatchSize:    10,
+		RetryBase:    10 * time.Millisecond,
+		RetryMax:     50 * time.Millisecond,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func writeEvent(t *testing.T, repo outbox.Repository) stateless.Event {
+	t.Helper()
+	event := stateless.SessionRevoked{
+		EventMeta: stateless.EventMeta{
+			ID:         "6f1c2a3e-0000-4000-8000-000000000001",
+			OccurredAt: time.Now().UTC(),
+			UserID:     "123e4567-e89b-12d3-a456-426614174000",
+			SessionID:  "session-1",
+		},
+		Reason: stateless.RevokeReasonLogout,
+	}
+	writer := outbox.NewWriter(repo, events.NewEncoder(events.FormatJSON, "/auth_service"), "auth", time.Second,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	writer.Publish(context.Background(), event)
+	return event
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func streamMessages(t *testing.T, s *server.Server) []jetstream.Msg {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	broker := connect(t, s)
+	consumer, err := broker.js.OrderedConsumer(ctx, testStream, jetstream.OrderedConsumerConfig{})
+	if err != nil {
+		t.Fatalf("OrderedConsumer: %v", err)
+	}
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		t.Fatalf("consumer info: %v", err)
+	}
+	var result []jetstream.Msg
+	if info.NumPending == 0 {
+		return result
+	}
+	batch, err := consumer.Fetch(int(info.NumPending), jetstream.FetchMaxWait(time.Second))
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	for msg := range batch.Messages() {
+		result = append(result, msg)
+	}
+	return result
+}
+
+func TestOutboxDeliversToJetStream(t *testing.T) {
+	s := runServer(t)
+	repo := &memoryOutbox{}
+	event := writeEvent(t, repo)
+	runRelay(t, repo, connect(t, s))
+
+	waitFor(t, "outbox to drain", func() bool { return repo.pending() == 0 })
+
+	messages := streamMessages(t, s)
+	if len(messages) != 1 {
+		t.Fatalf("stream has %d messages, want 1", len(messages))
+	}
+	msg := messages[0]
+	if want := outbox.Subject("auth", event.EventType()); msg.Subject() != want {
+		t.Fatalf("subject = %q, want %q", msg.Subject(), want)
+	}
+	if got := msg.Headers().Get("Nats-Msg-Id"); got != event.Metadata().ID {
+		t.Fatalf("Nats-Msg-Id = %q, want event id %q", got, event.Metadata().ID)
+	}
+	if len(msg.Data()) == 0 {
+		t.Fatal("message payload is empty")
+	}
+}
+
+func TestOutboxRedeliversAfterBrokerFailure(t *testing.T) {
+	s := runServer(t)
+	repo := &memoryOutbox{}
+	writeEvent(t, repo)
+	broker := &flakyBroker{Broker: connect(t, s), failures: 2}
+	runRelay(t, repo, broker)
+
+	waitFor(t, "outbox to drain", func() bool { return repo.pending() == 0 })
+
+	if calls := broker.publishCalls(); calls != 3 {
+		t.Fatalf("publish calls = %d, want 2 failures and 1 success", calls)
+	}
+	if messages := streamMessages(t, s); len(messages) != 1 {
+		t.Fatalf("stream has %d messages, want 1", len(messages))
+	}
+}
+
+func TestOutboxRedeliveryIsDeduplicated(t *testing.T) {
+	s := runServer(t)
+	// брокер принял сообщение, но отметка об отправке не сохранилась: relay отправит его повторно
+	repo := &memoryOutbox{failMarkSent: 1}
+	writeEvent(t, repo)
+	broker := &flakyBroker{Broker: connect(t, s)}
+	runRelay(t, repo, broker)
+
+	waitFor(t, "outbox to drain", func() bool { return repo.pending() == 0 })
+
+	if calls := broker.publishCalls(); calls != 2 {
+		t.Fatalf("publish calls = %d, want 2", calls)
+	}
+	if messages := streamMessages(t, s); len(messages) != 1 {
+		t.Fatalf("stream has %d messages after redelivery, want 1 (Nats-Msg-Id dedup)", len(messages))
+	}
+}
@@ -0,0 +1,31 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+// Message событие, ожидающее отправки в брокер. ID совпадает с ID события и служит ключом дедупликации
+type Message struct {
+	ID        string
+	Subject   string
+	Header    map[string]string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Repository хранит исходящие сообщения до подтверждения брокером
+type Repository interface {
+	Add(ctx context.Context, msg Message) error
+	// Process блокирует до limit готовых к отправке сообщений (другие экземпляры их пропускают),
+	// передаёт каждое в send и отмечает отправленным или откладывает до retryAt
+	Process(ctx context.Context, limit int, send func(ctx context.Context, msg Message) error, retryAt func(attempts int) time.Time) (int, error)
+	// PurgeSent удаляет отправленные сообщения старше before
+	PurgeSent(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Broker публикует сообщение и возвращается только после подтверждения приёма
+type Broker interface {
+	Publish(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"medods_test/internal/adapters/events/outbox"
+	"time"
+)
+
+type Config struct {
+	Prefix string
+}
+
+type PostgresOutboxRepository struct {
+	db   *sql.DB
+	conf *Config
+}
+
+func NewPostgresOutboxRepository(db *sql.DB, conf *Config) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{db: db, conf: conf}
+}
+
+// Add повторная запись с тем же id игнорируется
+func (r *PostgresOutboxRepository) Add(ctx context.Context, msg outbox.Message) error {
+	header, err := json.Marshal(msg.Header)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`event_outbox (id, subject, header, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO NOTHING`,
+		msg.ID, msg.Subject, header, msg.Payload)
+	return err
+}
+
+// Process держит блокировку строк на время отправки пачки, поэтому несколько экземпляров сервиса
+// не отправляют одно сообщение одновременно
+func (r *PostgresOutboxRepository) Process(
+	ctx context.Context,
+	limit int,
+	send func(ctx context.Context, msg outbox.Message) error,
+	retryAt func(attempts int) time.Time,
+) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, subject, header, payload, attempts, created_at
+		FROM `+r.conf.Prefix+`event_outbox
+		WHERE sent_at IS NULL AND next_attempt_at <= now()
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, err
+	}
+	var batch []outbox.Message
+	for rows.Next() {
+		var msg outbox.Message
+		var header []byte
+		if err := rows.Scan(&msg.ID, &msg.Subject, &header, &msg.Payload, &msg.Attempts, &msg.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if err := json.Unmarshal(header, &msg.Header); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, msg := range batch {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+		if sendErr := send(ctx, msg); sendErr != nil {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE `+r.conf.Prefix+`event_outbox
+				SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+				WHERE id = $1`,
+				msg.ID, retryAt(msg.Attempts), sendErr.Error())
+		} else {
+			sent++
+			_, err = tx.ExecContext(ctx,
+				`UPDATE `+r.conf.Prefix+`event_outbox
+				SET attempts = attempts + 1, sent_at = now(), last_error = NULL
+				WHERE id = $1`,
+				msg.ID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return sent, nil
+}
+
+func (r *PostgresOutboxRepository) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM `+r.conf.Prefix+`event_outbox WHERE sent_at IS NOT NULL AND sent_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,86 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	RetryBase    time.Duration
+	RetryMax     time.Duration
+	// Retention сколько хранить отправленные сообщения, например для разбора инцидентов
+	Retention time.Duration
+}
+
+// Relay переносит сообщения из outbox в брокер. Сообщение удаляется из очереди только после подтверждения брокера,
+// поэтому доставка не реже одного раза: потребители должны быть идемпотентны по id
+type Relay struct {
+	repo   Repository
+	broker Broker
+	conf   RelayConfig
+	logger *slog.Logger
+
+	lastPurge time.Time
+}
+
+func NewRelay(repo Repository, broker Broker, conf RelayConfig, logger *slog.Logger) *Relay {
+	return &Relay{repo: repo, broker: broker, conf: conf, logger: logger}
+}
+
+// Run отправляет сообщения до отмены ctx. Полная пачка означает, что в очереди есть ещё, и следующая берётся сразу
+func (r *Relay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		sent, err := r.repo.Process(ctx, r.conf.BatchSize, r.send, r.retryAt)
+		if err != nil && ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "failed to process outbox", "error", err)
+		}
+		r.purge(ctx)
+
+		if err == nil && sent == r.conf.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(r.conf.PollInterval)
+		}
+	}
+}
+
+func (r *Relay) send(ctx context.Context, msg Message) error {
+	err := r.broker.Publish(ctx, msg)
+	if err != nil {
+		r.logger.WarnContext(ctx, "failed to publish event", "error", err, "event_id", msg.ID, "subject", msg.Subject, "attempts", msg.Attempts+1)
+	}
+	return err
+}
+
+// retryAt случайная задержка до RetryBase*2^attempts, но не больше RetryMax
+func (r *Relay) retryAt(attempts int) time.Time {
+	limit := r.conf.RetryMax
+	if attempts < 32 {
+		if d := r.conf.RetryBase << attempts; d > 0 && d < limit {
+			limit = d
+		}
+	}
+	return time.Now().Add(rand.N(limit) + 1)
+}
+
+func (r *Relay) purge(ctx context.Context) {
+	if r.conf.Retention <= 0 || time.Since(r.lastPurge) < time.Hour {
+		return
+	}
+	r.lastPurge = time.Now()
+	if _, err := r.repo.PurgeSent(ctx, time.Now().Add(-r.conf.Retention)); err != nil && ctx.Err() == nil {
+		r.logger.ErrorContext(ctx, "failed to purge outbox", "error", err)
+	}
+}
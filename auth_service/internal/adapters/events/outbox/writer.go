@@ -0,0 +1,52 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"medods_test/internal/adapters/events"
+	"medods_test/internal/core/auth/stateless"
+	"time"
+)
+
+// Writer реализует stateless.EventPublisher: сохраняет событие в outbox до ответа на запрос,
+// дальше его доставляет Relay. Так событие не теряется при падении процесса или недоступном брокере
+type Writer struct {
+	repo          Repository
+	encoder       *events.Encoder
+	subjectPrefix string
+	timeout       time.Duration
+	logger        *slog.Logger
+}
+
+func NewWriter(repo Repository, encoder *events.Encoder, subjectPrefix string, timeout time.Duration, logger *slog.Logger) *Writer {
+	return &Writer{repo: repo, encoder: encoder, subjectPrefix: subjectPrefix, timeout: timeout, logger: logger}
+}
+
+func (w *Writer) Publish(ctx context.Context, event stateless.Event) {
+	msg, err := w.encoder.Encode(event)
+	if err != nil {
+		w.logger.ErrorContext(ctx, "failed to encode event for outbox", "error", err, "event_type", event.EventType())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), w.timeout)
+	defer cancel()
+	err = w.repo.Add(ctx, Message{
+		ID:      msg.ID,
+		Subject: Subject(w.subjectPrefix, event.EventType()),
+		Header:  msg.Header,
+		Payload: msg.Body,
+	})
+	if err != nil {
+		w.logger.ErrorContext(ctx, "failed to store event in outbox", "error", err,
+			"event_type", event.EventType(), "event_id", msg.ID, "correlation_id", event.Metadata().CorrelationID)
+	}
+}
+
+// Subject тема брокера для типа события, например auth.session.created
+func Subject(prefix string, eventType stateless.EventType) string {
+	if prefix == "" {
+		return string(eventType)
+	}
+	return prefix + "." + string(eventType)
+}
@@ -0,0 +1,136 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"medods_test/pkg/router"
+)
+
+// Check возвращает ошибку, если зависимость не готова обслуживать запросы
+type Check func(ctx context.Context) error
+
+var ErrDraining = errors.New("server is shutting down")
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health отвечает на /healthz и /readyz. Проверки готовности выполняются параллельно при каждом запросе
+type Health struct {
+	checks   []namedCheck
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// Add регистрирует проверку готовности, вызывается до запуска сервера
+func (h *Health) Add(name string, check Check) {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// SetDraining переводит /readyz в 503, чтобы балансировщик перестал слать запросы до остановки сервера
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+// RegisterRoutes пробы не версионируются, их пути прописаны в манифестах оркестратора
+func (h *Health) RegisterRoutes(rt *router.Router) {
+	rt.HandleRootFunc("GET /healthz", h.handleLiveness)
+	rt.HandleRootFunc("GET /readyz", h.handleReadiness)
+}
+
+type CheckResult struct {
+	Status string `json:"status" example:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Response struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+// handleLiveness godoc
+// @Summary Liveness проба
+// @Description Отвечает 200, пока процесс жив. Зависимости не проверяет
+// @Tags health
+// @Produce json
+// @Success 200 {object} Response
+// @Router /healthz [get]
+func (h *Health) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, Response{Status: statusOK})
+}
+
+// handleReadiness godoc
+// @Summary Readiness проба
+// @Description Проверяет базу, версию схемы, ключи подписи и отправку вебхуков.
+// @Description Во время остановки сервера отвечает 503 с проверкой shutdown
+// @Tags health
+// @Produce json
+// @Success 200 {object} Response
+// @Failure 503 {object} Response
+// @Router /readyz [get]
+func (h *Health) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		writeResponse(w, http.StatusServiceUnavailable, Response{
+			Status: statusUnavailable,
+			Checks: map[string]CheckResult{"shutdown": {Status: statusUnavailable, Error: ErrDraining.Error()}},
+		})
+		return
+	}
+
+	response := h.run(r.Context())
+	status := http.StatusOK
+	if response.Status != statusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeResponse(w, status, response)
+}
+
+func (h *Health) run(ctx context.Context) Response {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	response := Response{Status: statusOK, Checks: make(map[string]CheckResult, len(h.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := CheckResult{Status: statusOK}
+			if err := c.check(ctx); err != nil {
+				result = CheckResult{Status: statusUnavailable, Error: err.Error()}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[c.name] = result
+			if result.Status != statusOK {
+				response.Status = statusUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+	return response
+}
+
+func writeResponse(w http.ResponseWriter, status int, response Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,30 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DBPing проверяет соединение с базой
+func DBPing(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// SchemaVersion проверяет, что схема базы не старее той, под которую собран сервис.
+// Более новая схема допустима: при раскатке старые экземпляры работают с уже обновлённой базой
+func SchemaVersion(db *sql.DB, prefix string, want int) Check {
+	return func(ctx context.Context) error {
+		var version int
+		err := db.QueryRowContext(ctx, `SELECT version FROM `+prefix+`schema_version`).Scan(&version)
+		if err != nil {
+			return fmt.Errorf("failed to read schema version: %w", err)
+		}
+		if version < want {
+			return fmt.Errorf("schema version %d, want at least %d", version, want)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/eventbus"
+)
+
+// EventLog пишет каждое событие сессии отдельной строкой лога, correlation_id связывает её с логами запроса
+type EventLog struct {
+	logger *slog.Logger
+}
+
+func NewEventLog(logger *slog.Logger) *EventLog {
+	return &EventLog{logger: logger}
+}
+
+func (l *EventLog) Subscribe(bus *eventbus.Bus[stateless.Event]) {
+	bus.Subscribe("log", func(ctx context.Context, event stateless.Event) error {
+		meta := event.Metadata()
+		l.logger.InfoContext(ctx, "session event",
+			slog.String("event_type", string(event.EventType())),
+			slog.String("event_id", meta.ID),
+			slog.String("correlation_id", meta.CorrelationID),
+			slog.String("user_id", string(meta.UserID)),
+			slog.String("session_id", string(meta.SessionID)),
+			slog.String("token_pair_id", string(meta.TokenPairID)),
+			slog.Time("occurred_at", meta.OccurredAt),
+		)
+		return nil
+	})
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys ключи, значения которых никогда не пишутся в лог
+var sensitiveKeys = map[string]bool{
+	"token":         true,
+	"secret":        true,
+	"password":      true,
+	"hash":          true,
+	"code":          true,
+	"authorization": true,
+	"cookie":        true,
+	"api_key":       true,
+	"code_verifier": true,
+}
+
+// sensitiveSuffixes access_token, client_secret, refresh_hash и подобные
+var sensitiveSuffixes = []string{"_token", "_secret", "_hash", "_password"}
+
+// sensitiveValues JWT и API ключи, попавшие в текст ошибки или в поле с безобидным именем
+var sensitiveValues = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*|mdk_[A-Za-z0-9]+_[A-Za-z0-9_-]+`)
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+	if isSensitiveKey(key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		if s := attr.Value.String(); sensitiveValues.MatchString(s) {
+			return slog.String(attr.Key, sensitiveValues.ReplaceAllString(s, redacted))
+		}
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok && sensitiveValues.MatchString(err.Error()) {
+			return slog.String(attr.Key, sensitiveValues.ReplaceAllString(err.Error(), redacted))
+		}
+	}
+	return attr
+}
+
+func isSensitiveKey(key string) bool {
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
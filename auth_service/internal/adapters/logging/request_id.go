@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"context"
+	"medods_test/pkg/requestid"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// входящий идентификатор принимается, только если его безопасно писать в лог и заголовок ответа
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return requestid.With(ctx, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	return requestid.FromContext(ctx)
+}
+
+// RequestID берёт X-Request-ID из запроса или генерирует новый, возвращает его в ответе
+// и кладёт в контекст, откуда его берёт логгер
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// UnaryRequestID то же для gRPC: идентификатор берётся из метаданных x-request-id и возвращается в заголовке ответа
+func UnaryRequestID(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if !validRequestID.MatchString(requestID) {
+		requestID = uuid.NewString()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+	return handler(WithRequestID(ctx, requestID), req)
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"time"
+)
+
+// Hasher генератор секретов с bcrypt хешированием, как unikelongstring.ULSHelper
+type Hasher interface {
+	Generate() (string, error)
+	GetHash(token string) (string, error)
+	CompareHash(hashFromDb, token string) bool
+}
+
+// InstrumentedHasher замеряет время GetHash и CompareHash
+type InstrumentedHasher struct {
+	Hasher
+	metrics *Metrics
+}
+
+func NewInstrumentedHasher(hasher Hasher, metrics *Metrics) *InstrumentedHasher {
+	return &InstrumentedHasher{Hasher: hasher, metrics: metrics}
+}
+
+func (h *InstrumentedHasher) GetHash(token string) (string, error) {
+	started := time.Now()
+	defer func() { h.metrics.hashDuration.WithLabelValues("hash").Observe(time.Since(started).Seconds()) }()
+	return h.Hasher.GetHash(token)
+}
+
+func (h *InstrumentedHasher) CompareHash(hashFromDb, token string) bool {
+	started := time.Now()
+	defer func() { h.metrics.hashDuration.WithLabelValues("compare").Observe(time.Since(started).Seconds()) }()
+	return h.Hasher.CompareHash(hashFromDb, token)
+}
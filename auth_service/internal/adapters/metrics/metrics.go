@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"context"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/eventbus"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "auth"
+
+// Metrics метрики сервиса в формате Prometheus, реализует stateless.Metrics
+type Metrics struct {
+	registry *prometheus.Registry
+
+	tokensIssued      *prometheus.CounterVec
+	refreshes         *prometheus.CounterVec
+	logouts           *prometheus.CounterVec
+	webhookDeliveries *prometheus.CounterVec
+	eventsDropped     *prometheus.CounterVec
+	eventsFailed      *prometheus.CounterVec
+	sessionEvents     *prometheus.CounterVec
+
+	hashDuration       *prometheus.HistogramVec
+	repositoryDuration *prometheus.HistogramVec
+	httpDuration       *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		tokensIssued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_issued_total",
+			Help:      "Выданные access токены по способу выдачи.",
+		}, []string{"kind"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "refreshes_total",
+			Help:      "Попытки обновления пары токенов по исходу.",
+		}, []string{"outcome"}),
+		logouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logouts_total",
+			Help:      "Завершённые сессии: выход пользователя или завершение по sid.",
+		}, []string{"kind"}),
+		webhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_deliveries_total",
+			Help:      "Отправки вебхуков по HTTP статусу ответа, error если ответа не было.",
+		}, []string{"webhook", "status"}),
+		eventsDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "eventbus_dropped_total",
+			Help:      "События, потерянные шиной из-за переполнения.",
+		}, []string{"bus"}),
+		eventsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "eventbus_failed_total",
+			Help:      "События, которые подписчик не обработал за все попытки.",
+		}, []string{"bus", "subscriber"}),
+		sessionEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "session_events_total",
+			Help:      "События жизненного цикла сессий по типу.",
+		}, []string{"type"}),
+		hashDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "bcrypt_duration_seconds",
+			Help:      "Время вычисления и проверки bcrypt хешей.",
+			Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1},
+		}, []string{"op"}),
+		repositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_query_duration_seconds",
+			Help:      "Время запросов репозиториев к базе.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"repository", "method"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Время обработки HTTP запросов по шаблону роута.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.tokensIssued,
+		m.refreshes,
+		m.logouts,
+		m.webhookDeliveries,
+		m.eventsDropped,
+		m.eventsFailed,
+		m.sessionEvents,
+		m.hashDuration,
+		m.repositoryDuration,
+		m.httpDuration,
+	)
+
+	// нулевые значения, чтобы rate() и алерты работали до первого события
+	for _, kind := range []stateless.TokenKind{stateless.TokenKindSession, stateless.TokenKindRefresh, stateless.TokenKindStepUp, stateless.TokenKindClientCredentials} {
+		m.tokensIssued.WithLabelValues(string(kind))
+	}
+	for _, outcome := range []stateless.RefreshOutcome{stateless.RefreshSuccess, stateless.RefreshUAMismatch, stateless.RefreshNotFound, stateless.RefreshInvalid, stateless.RefreshInternalFail} {
+		m.refreshes.WithLabelValues(string(outcome))
+	}
+	for _, kind := range []stateless.SessionEndKind{stateless.SessionEndLogout, stateless.SessionEndByID} {
+		m.logouts.WithLabelValues(string(kind))
+	}
+
+	for _, eventType := range []stateless.EventType{stateless.EventSessionCreated, stateless.EventSessionRefreshed, stateless.EventSessionRevoked,
+		stateless.EventUserAgentMismatch, stateless.EventLogoutPerformed, stateless.EventUserIPChanged} {
+		m.sessionEvents.WithLabelValues(string(eventType))
+	}
+
+	return m
+}
+
+// Subscribe считает события сессий из шины
+func (m *Metrics) Subscribe(bus *eventbus.Bus[stateless.Event]) {
+	bus.Subscribe("metrics", func(ctx context.Context, event stateless.Event) error {
+		m.sessionEvents.WithLabelValues(string(event.EventType())).Inc()
+		return nil
+	})
+}
+
+// Handler отдаёт метрики для /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) TokenIssued(kind stateless.TokenKind) {
+	m.tokensIssued.WithLabelValues(string(kind)).Inc()
+}
+
+func (m *Metrics) RefreshCompleted(outcome stateless.RefreshOutcome) {
+	m.refreshes.WithLabelValues(string(outcome)).Inc()
+}
+
+func (m *Metrics) SessionEnded(kind stateless.SessionEndKind) {
+	m.logouts.WithLabelValues(string(kind)).Inc()
+}
+
+// WebhookDelivered status код ответа или "error", если запрос не дошёл
+func (m *Metrics) WebhookDelivered(webhook string, status string) {
+	m.webhookDeliveries.WithLabelValues(webhook, status).Inc()
+}
+
+func (m *Metrics) EventDropped(bus string) {
+	m.eventsDropped.WithLabelValues(bus).Inc()
+}
+
+func (m *Metrics) EventFailed(bus, subscriber string) {
+	m.eventsFailed.WithLabelValues(bus, subscriber).Inc()
+}
+
+func (m *Metrics) observeRepository(repository, method string, started time.Time) {
+	m.repositoryDuration.WithLabelValues(repository, method).Observe(time.Since(started).Seconds())
+}
+
+type SessionCounter interface {
+	CountSessions(ctx context.Context) (int, error)
+}
+
+// RegisterSessionGauge добавляет gauge auth_active_sessions, значение считается в базе при каждом опросе
+func (m *Metrics) RegisterSessionGauge(counter SessionCounter, timeout time.Duration) {
+	m.registry.MustRegister(&sessionCollector{
+		counter: counter,
+		timeout: timeout,
+		desc:    prometheus.NewDesc(namespace+"_active_sessions", "Сессии с действующим refresh токеном.", nil, nil),
+	})
+}
+
+type sessionCollector struct {
+	counter SessionCounter
+	timeout time.Duration
+	desc    *prometheus.Desc
+}
+
+func (c *sessionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *sessionCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	count, err := c.counter.CountSessions(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count))
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/auth/stateless/statelesstest"
+)
+
+func TestActiveSessionsGaugeFollowsLogoutAndRotation(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	svc := statelesstest.NewService(t, statelesstest.Options{Metrics: m})
+	m.RegisterSessionGauge(svc.Sessions, time.Second)
+
+	const userID = stateless.UserID("123e4567-e89b-12d3-a456-426614174000")
+	login := func() stateless.TokenPair {
+		t.Helper()
+		tokens, err := svc.TestAuthenticateUser(ctx, stateless.TestAuthCommand{UserId: userID, UserAgent: "test", IP: "127.0.0.1"})
+		if err != nil {
+			t.Fatalf("TestAuthenticateUser: %v", err)
+		}
+		return tokens
+	}
+	first, second := login(), login()
+	assertActiveSessions(t, m, 2)
+
+	err := svc.Logout(ctx, stateless.LogoutCommand{RefreshToken: first.RefreshToken, UserID: userID, UserAgent: "test", IP: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	assertActiveSessions(t, m, 1)
+
+	// ротация заменяет строку, а не добавляет новую
+	if _, err := svc.RefreshTokens(ctx, stateless.RefreshTokenCommand{
+		AccessToken: second.AccessToken, RefreshToken: second.RefreshToken, UserAgent: "test", IP: "127.0.0.1",
+	}); err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+	assertActiveSessions(t, m, 1)
+
+	// повторный выход тем же токеном ничего не удаляет
+	err = svc.Logout(ctx, stateless.LogoutCommand{RefreshToken: first.RefreshToken, UserID: userID})
+	if err != stateless.ErrSessionNotFound {
+		t.Fatalf("second Logout error = %v, want ErrSessionNotFound", err)
+	}
+	assertActiveSessions(t, m, 1)
+}
+
+func assertActiveSessions(t *testing.T, m *Metrics, want float64) {
+	t.Helper()
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == namespace+"_active_sessions" {
+			if got := family.GetMetric()[0].GetGauge().GetValue(); got != want {
+				t.Fatalf("auth_active_sessions = %v, want %v", got, want)
+			}
+			return
+		}
+	}
+	t.Fatal("auth_active_sessions is not registered")
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"medods_test/internal/core/auth/stateless"
+	"time"
+)
+
+// authRepository замеряет время запросов stateless.AuthRepository.
+// GetSession включает bcrypt сравнения с каждой сессией пользователя
+type authRepository struct {
+	next    stateless.AuthRepository
+	metrics *Metrics
+}
+
+func InstrumentAuthRepository(repo stateless.AuthRepository, metrics *Metrics) stateless.AuthRepository {
+	return &authRepository{next: repo, metrics: metrics}
+}
+
+func (r *authRepository) SaveSession(ctx context.Context, session stateless.SessionData) error {
+	defer r.metrics.observeRepository("auth", "SaveSession", time.Now())
+	return r.next.SaveSession(ctx, session)
+}
+
+func (r *authRepository) DeleteSession(ctx context.Context, userID stateless.UserID, refreshHash string) error {
+	defer r.metrics.observeRepository("auth", "DeleteSession", time.Now())
+	return r.next.DeleteSession(ctx, userID, refreshHash)
+}
+
+func (r *authRepository) DeleteSessionByID(ctx context.Context, userID stateless.UserID, sessionID stateless.SessionID) error {
+	defer r.metrics.observeRepository("auth", "DeleteSessionByID", time.Now())
+	return r.next.DeleteSessionByID(ctx, userID, sessionID)
+}
+
+func (r *authRepository) GetSession(ctx context.Context, userID stateless.UserID, refreshHash string) (stateless.SessionData, error) {
+	defer r.metrics.observeRepository("auth", "GetSession", time.Now())
+	return r.next.GetSession(ctx, userID, refreshHash)
+}
+
+func (r *authRepository) GetSessionByTokenPairID(ctx context.Context, userID stateless.UserID, tokenPairID stateless.TokenPairID) (stateless.SessionData, error) {
+	defer r.metrics.observeRepository("auth", "GetSessionByTokenPairID", time.Now())
+	return r.next.GetSessionByTokenPairID(ctx, userID, tokenPairID)
+}
+
+func (r *authRepository) ListSessions(ctx context.Context, userID stateless.UserID) ([]stateless.SessionData, error) {
+	defer r.metrics.observeRepository("auth", "ListSessions", time.Now())
+	return r.next.ListSessions(ctx, userID)
+}
+
+func (r *authRepository) CountSessions(ctx context.Context) (int, error) {
+	defer r.metrics.observeRepository("auth", "CountSessions", time.Now())
+	return r.next.CountSessions(ctx)
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"medods_test/internal/core/auth/stateless"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// authRepository создаёт спан на каждый запрос stateless.AuthRepository.
+// Спан GetSession включает bcrypt сравнения с каждой сессией пользователя
+type authRepository struct {
+	next    stateless.AuthRepository
+	tracing *Tracing
+}
+
+func InstrumentAuthRepository(repo stateless.AuthRepository, tracing *Tracing) stateless.AuthRepository {
+	return &authRepository{next: repo, tracing: tracing}
+}
+
+func (r *authRepository) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	return r.tracing.tracer.Start(ctx, "AuthRepository."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", method),
+		),
+	)
+}
+
+func (r *authRepository) SaveSession(ctx context.Context, session stateless.SessionData) (err error) {
+	ctx, span := r.start(ctx, "SaveSession")
+	defer func() { endSpan(span, err) }()
+	return r.next.SaveSession(ctx, session)
+}
+
+func (r *authRepository) DeleteSession(ctx context.Context, userID stateless.UserID, refreshHash string) (err error) {
+	ctx, span := r.start(ctx, "DeleteSession")
+	defer func() { endSpan(span, err) }()
+	return r.next.DeleteSession(ctx, userID, refreshHash)
+}
+
+func (r *authRepository) DeleteSessionByID(ctx context.Context, userID stateless.UserID, sessionID stateless.SessionID) (err error) {
+	ctx, span := r.start(ctx, "DeleteSessionByID")
+	defer func() { endSpan(span, err) }()
+	return r.next.DeleteSessionByID(ctx, userID, sessionID)
+}
+
+func (r *authRepository) GetSession(ctx context.Context, userID stateless.UserID, refreshHash string) (_ stateless.SessionData, err error) {
+	ctx, span := r.start(ctx, "GetSession")
+	defer func() { endSpan(span, err) }()
+	return r.next.GetSession(ctx, userID, refreshHash)
+}
+
+func (r *authRepository) GetSessionByTokenPairID(ctx context.Context, userID stateless.UserID, tokenPairID stateless.TokenPairID) (_ stateless.SessionData, err error) {
+	ctx, span := r.start(ctx, "GetSessionByTokenPairID")
+	defer func() { endSpan(span, err) }()
+	return r.next.GetSessionByTokenPairID(ctx, userID, tokenPairID)
+}
+
+func (r *authRepository) ListSessions(ctx context.Context, userID stateless.UserID) (_ []stateless.SessionData, err error) {
+	ctx, span := r.start(ctx, "ListSessions")
+	defer func() { endSpan(span, err) }()
+	return r.next.ListSessions(ctx, userID)
+}
+
+func (r *authRepository) CountSessions(ctx context.Context) (_ int, err error) {
+	ctx, span := r.start(ctx, "CountSessions")
+	defer func() { endSpan(span, err) }()
+	return r.next.CountSessions(ctx)
+}
@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"medods_test/internal/core/auth/stateless"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const instrumentationName = "medods_test/auth_service"
+
+// Экспортёры Config.Exporter
+const (
+	ExporterNone = "none"
+	// ExporterOTLP адрес коллектора задаётся стандартными переменными OTEL_EXPORTER_OTLP_*
+	ExporterOTLP = "otlp"
+)
+
+type Config struct {
+	Exporter    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// Tracing источник спанов для всех слоёв, реализует stateless.Tracer
+type Tracing struct {
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+	shutdown func(ctx context.Context) error
+}
+
+// New по умолчанию (ExporterNone) спаны не создаются и ничего не отправляется
+func New(ctx context.Context, conf Config) (*Tracing, error) {
+	switch conf.Exporter {
+	case ExporterNone, "":
+		return newTracing(noop.NewTracerProvider(), nil), nil
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+		provider := sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(conf.ServiceName))),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio))),
+		)
+		return newTracing(provider, provider.Shutdown), nil
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter: %s", conf.Exporter)
+	}
+}
+
+func newTracing(provider trace.TracerProvider, shutdown func(ctx context.Context) error) *Tracing {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return &Tracing{
+		provider: provider,
+		tracer:   provider.Tracer(instrumentationName),
+		shutdown: shutdown,
+	}
+}
+
+// Shutdown отправляет накопленные спаны
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t.shutdown == nil {
+		return nil
+	}
+	return t.shutdown(ctx)
+}
+
+func (t *Tracing) Start(ctx context.Context, name string) (context.Context, stateless.Span) {
+	ctx, span := t.tracer.Start(ctx, name)
+	return ctx, spanEnder{span}
+}
+
+type spanEnder struct {
+	span trace.Span
+}
+
+func (s spanEnder) End(err error) {
+	endSpan(s.span, err)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,145 @@
+package tracing_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"medods_test/internal/adapters/events"
+	"medods_test/internal/adapters/tracing"
+	"medods_test/internal/adapters/webhook"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/auth/stateless/statelesstest"
+	"medods_test/pkg/eventbus"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type nopWebhookMetrics struct{}
+
+func (nopWebhookMetrics) WebhookDelivered(string, string) {}
+
+// TestRefreshTraceReachesWebhook refresh с новым IP: спаны HTTP, сервиса, хранилища и вызова вебхука
+// принадлежат одной трассе, а traceparent вебхука указывает на клиентский спан этой трассы
+func TestRefreshTraceReachesWebhook(t *testing.T) {
+	ctx := context.Background()
+	tr, exporter := tracing.NewInMemory()
+	t.Cleanup(func() { tr.Shutdown(context.Background()) })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	traceparents := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+	}))
+	t.Cleanup(receiver.Close)
+
+	bus := eventbus.NewBus(eventbus.Options[stateless.Event]{Workers: 1, QueueSize: 8, MaxAttempts: 1})
+	t.Cleanup(func() { bus.Close(context.Background()) })
+	webhook.NewUserIPChanged(&http.Client{Transport: tr.Transport(nil)}, receiver.URL, events.FormatJSON,
+		"/auth_service", nopWebhookMetrics{}, logger).Subscribe(bus)
+
+	svc := statelesstest.NewService(t, statelesstest.Options{
+		Tracer: tr,
+		Events: bus,
+		WrapSessions: func(repo stateless.AuthRepository) stateless.AuthRepository {
+			return tracing.InstrumentAuthRepository(repo, tr)
+		},
+	})
+	tokens, err := svc.TestAuthenticateUser(ctx, stateless.TestAuthCommand{
+		UserId: "123e4567-e89b-12d3-a456-426614174000", UserAgent: "test", IP: "10.0.0.1",
+	})
+	if err != nil {
+		t.Fatalf("TestAuthenticateUser: %v", err)
+	}
+	exporter.Reset()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		_, err := svc.RefreshTokens(r.Context(), stateless.RefreshTokenCommand{
+			AccessToken: tokens.AccessToken, RefreshToken: tokens.RefreshToken, UserAgent: "test", IP: "10.0.0.2",
+		})
+		if err != nil {
+			t.Errorf("RefreshTokens: %v", err)
+		}
+	})
+	tr.InstrumentMux(mux, mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/auth/refresh", nil))
+
+	var traceparent string
+	select {
+	case traceparent = <-traceparents:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+	webhookSpan := waitForSpan(t, exporter, func(s tracetest.SpanStub) bool {
+		return s.SpanKind == trace.SpanKindClient && strings.HasPrefix(s.Name, http.MethodPost+" ")
+	})
+
+	spans := exporter.GetSpans()
+	root := findSpan(t, spans, func(s tracetest.SpanStub) bool { return s.SpanKind == trace.SpanKindServer })
+	traceID := root.SpanContext.TraceID()
+	for _, name := range []string{"StatelessAuthService.RefreshTokens", "AuthRepository.GetSession"} {
+		span := findSpan(t, spans, func(s tracetest.SpanStub) bool { return s.Name == name })
+		if span.SpanContext.TraceID() != traceID {
+			t.Errorf("%s is in trace %s, want %s", name, span.SpanContext.TraceID(), traceID)
+		}
+	}
+	if !descendsFrom(spans, webhookSpan, root) {
+		t.Fatalf("webhook span %s (parent %s) is not a descendant of the refresh request span", webhookSpan.Name, webhookSpan.Parent.SpanID())
+	}
+
+	want := "00-" + traceID.String() + "-" + webhookSpan.SpanContext.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Fatalf("webhook traceparent = %q, want %q", traceparent, want)
+	}
+}
+
+// waitForSpan клиентский спан завершается после ответа вебхука, уже в горутине шины
+func waitForSpan(t *testing.T, exporter *tracetest.InMemoryExporter, match func(tracetest.SpanStub) bool) tracetest.SpanStub {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, s := range exporter.GetSpans() {
+			if match(s) {
+				return s
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("span was not exported")
+	return tracetest.SpanStub{}
+}
+
+func findSpan(t *testing.T, spans tracetest.SpanStubs, match func(tracetest.SpanStub) bool) tracetest.SpanStub {
+	t.Helper()
+	for _, s := range spans {
+		if match(s) {
+			return s
+		}
+	}
+	t.Fatalf("span not found among %d exported spans", len(spans))
+	return tracetest.SpanStub{}
+}
+
+func descendsFrom(spans tracetest.SpanStubs, span, ancestor tracetest.SpanStub) bool {
+	byID := make(map[trace.SpanID]tracetest.SpanStub, len(spans))
+	for _, s := range spans {
+		byID[s.SpanContext.SpanID()] = s
+	}
+	for span.Parent.IsValid() {
+		if span.Parent.SpanID() == ancestor.SpanContext.SpanID() {
+			return true
+		}
+		parent, ok := byID[span.Parent.SpanID()]
+		if !ok {
+			return false
+		}
+		span = parent
+	}
+	return false
+}
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 
 	mw "medods_test/internal/adapters/auth/stateless/http"
+	"medods_test/internal/core/auth/principal"
+	"medods_test/internal/core/auth/stateless"
 	"medods_test/pkg/httperror"
+	"medods_test/pkg/router"
 	"net/http"
 	"log/slog"
 )
@@ -21,8 +24,8 @@ func NewHandler(authMiddleware mw.MiddlewareFactory, logger *slog.Logger) *UserH
 	}
 }
 
-func (h *UserHttpHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/auth/me", h.authMiddleware.Wrap(h.handleMe))
+func (h *UserHttpHandler) RegisterRoutes(rt *router.Router) {
+	rt.HandleFunc("GET /auth/me", h.authMiddleware.RequireScopes(h.handleMe, stateless.ScopeProfileRead))
 }
 
 // handleMe godoc
@@ -32,15 +35,15 @@ func (h *UserHttpHandler) RegisterRoutes(mux *http.ServeMux) {
 // @Tags auth
 // @Produce json
 // @Success 200 {object} map[string]string
-// @Failure 401 {object} httperror.ErrorResponse "unauthorized"
-// @Router /auth/me [get]
+// @Failure 401 {object} httperror.Problem "unauthorized"
+// @Router /v1/auth/me [get]
 // @Security Bearer
 func (h *UserHttpHandler) handleMe(w http.ResponseWriter, r *http.Request) {
 
-	userID := r.Context().Value("user_id")
-	if userID == nil {
-		httperror.WriteJSONError(w, http.StatusUnauthorized, "unauthorized")
+	caller, ok := principal.UserFromContext(r.Context())
+	if !ok {
+		httperror.Write(w, r, http.StatusUnauthorized, httperror.CodeUnauthorized, "")
 		return
 	}
-	json.NewEncoder(w).Encode(map[string]interface{}{"user_id": userID})
+	json.NewEncoder(w).Encode(map[string]interface{}{"user_id": caller.UserID})
 }
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"medods_test/internal/core/user"
+)
+
+type Config struct {
+	Prefix string
+}
+
+type PostgresUserRepository struct {
+	db   *sql.DB
+	conf *Config
+}
+
+func NewPostgresUserRepository(db *sql.DB, conf *Config) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db, conf: conf}
+}
+
+func (r *PostgresUserRepository) GetProfile(ctx context.Context, id string) (user.Profile, error) {
+	var p user.Profile
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, email, email_verified, name, given_name, family_name, updated_at FROM `+r.conf.Prefix+`users WHERE id = $1`, id).
+		Scan(&p.ID, &p.Email, &p.EmailVerified, &p.Name, &p.GivenName, &p.FamilyName, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user.Profile{}, user.ErrUserNotFound
+	}
+	return p, err
+}
+
+func (r *PostgresUserRepository) CreateUser(ctx context.Context, p user.Profile) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO `+r.conf.Prefix+`users (id, email, email_verified, name, given_name, family_name, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		p.ID, p.Email, p.EmailVerified, p.Name, p.GivenName, p.FamilyName, p.UpdatedAt)
+	return err
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"medods_test/internal/adapters/events"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/eventbus"
+	"net/http"
+	"strconv"
+)
+
+// Metrics учёт отправок по коду ответа
+type Metrics interface {
+	WebhookDelivered(webhook string, status string)
+}
+
+const userIPChangedName = "user_ip_changed"
+
+// UserIPChanged отправляет POST на URL при смене IP в refresh. Ответ не 200 считается ошибкой и повторяется шиной.
+// В формате json тело прежнее, {user_id, new_ip}, в форматах CloudEvents — событие целиком
+type UserIPChanged struct {
+	client  *http.Client
+	url     string
+	format  events.Format
+	encoder *events.Encoder
+	metrics Metrics
+	logger  *slog.Logger
+}
+
+func NewUserIPChanged(client *http.Client, url string, format events.Format, source string, metrics Metrics, logger *slog.Logger) *UserIPChanged {
+	return &UserIPChanged{
+		client:  client,
+		url:     url,
+		format:  format,
+		encoder: events.NewEncoder(format, source),
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+func (w *UserIPChanged) Subscribe(bus *eventbus.Bus[stateless.Event]) {
+	if w.url == "" {
+		w.logger.Warn("UserIPChangedWebhookUrl is not configured, webhook calls are disabled")
+		return
+	}
+	eventbus.On(bus, userIPChangedName+"_webhook", w.deliver)
+}
+
+type userIPChangedBody struct {
+	UserID stateless.UserID `json:"user_id"`
+	NewIP  string           `json:"new_ip"`
+}
+
+func (w *UserIPChanged) encode(event stateless.UserIPChangedEvent) (events.Message, error) {
+	if w.format != events.FormatJSON {
+		return w.encoder.Encode(event)
+	}
+	body, err := json.Marshal(userIPChangedBody{UserID: event.UserID, NewIP: event.NewIP})
+	if err != nil {
+		return events.Message{}, err
+	}
+	return events.Message{ID: event.ID, Header: map[string]string{"Content-Type": "application/json"}, Body: body}, nil
+}
+
+func (w *UserIPChanged) deliver(ctx context.Context, event stateless.UserIPChangedEvent) error {
+	msg, err := w.encode(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(msg.Body))
+	if err != nil {
+		return err
+	}
+	for name, value := range msg.Header {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		w.metrics.WebhookDelivered(userIPChangedName, "error")
+		w.logger.WarnContext(ctx, "failed to call UserIPChangedWebhook", "error", err, "correlation_id", event.CorrelationID)
+		return err
+	}
+	defer resp.Body.Close()
+	w.metrics.WebhookDelivered(userIPChangedName, strconv.Itoa(resp.StatusCode))
+
+	if resp.StatusCode != http.StatusOK {
+		w.logger.WarnContext(ctx, "UserIPChangedWebhook responded with error", "status", resp.StatusCode, "correlation_id", event.CorrelationID)
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	w.logger.InfoContext(ctx, "UserIPChangedWebhook called successfully", "user_id", event.UserID, "new_ip", event.NewIP)
+	return nil
+}
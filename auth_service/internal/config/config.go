@@ -3,7 +3,7 @@ package config
 import (
 	"fmt"
 	"github.com/kelseyhightower/envconfig"
-	"encoding/json"
+	"time"
 )
 
 type Config struct {
@@ -11,8 +11,25 @@ type Config struct {
 	Host                     string `envconfig:"HOST" default:"localhost"`
 	Debug                    bool   `envconfig:"DEBUG" default:"false"`
 	UserIPChangedWebhookUrl  string `envconfig:"USER_IP_CHANGED_WEBHOOK_URL" default:""`
+	UserIPChangedWebhookFormat string `envconfig:"USER_IP_CHANGED_WEBHOOK_FORMAT" default:"json"`
 	Database                 DatabaseConfig
 	JWT                      JWTConfig
+	MFA                      MFAConfig
+	WebAuthn                 WebAuthnConfig
+	OAuth                    OAuthConfig
+	OIDC                     OIDCConfig
+	Federation               FederationConfig
+	APIKey                   APIKeyConfig
+	Tracing                  TracingConfig
+	Log                      LogConfig
+	Audit                    AuditConfig
+	Health                   HealthConfig
+	Shutdown                 ShutdownConfig
+	EventBus                 EventBusConfig
+	Events                   EventsConfig
+	GRPC                     GRPCConfig
+	HTTP                     HTTPConfig
+	Metrics                  MetricsConfig
 }
 
 type DatabaseConfig struct {
@@ -26,8 +43,124 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	AccessSecret  string `envconfig:"JWT_ACCESS_SECRET" default:"secret"`
-	RefreshSecret string `envconfig:"JWT_REFRESH_SECRET" default:"refresh_secret"`
+	AccessSecret  string        `envconfig:"JWT_ACCESS_SECRET" default:"secret"`
+	RefreshSecret string        `envconfig:"JWT_REFRESH_SECRET" default:"refresh_secret"`
+	AccessTTL     time.Duration `envconfig:"JWT_ACCESS_TTL" default:"15m"`
+	// AccessAlg HS512 подписывает access токены JWT_ACCESS_SECRET, RS256 — ключом OIDC из /.well-known/jwks.json
+	AccessAlg string `envconfig:"JWT_ACCESS_ALG" default:"HS512"`
+	// AccessAudience значения aud в access токенах, по умолчанию claim не пишется
+	AccessAudience []string `envconfig:"JWT_ACCESS_AUDIENCE" default:""`
+}
+
+type MFAConfig struct {
+	Issuer       string        `envconfig:"MFA_ISSUER" default:"medods"`
+	PendingTTL   time.Duration `envconfig:"MFA_PENDING_TTL" default:"5m"`
+	StepUpMaxAge time.Duration `envconfig:"MFA_STEP_UP_MAX_AGE" default:"10m"`
+}
+
+type WebAuthnConfig struct {
+	RPID          string        `envconfig:"WEBAUTHN_RP_ID" default:"localhost"`
+	RPDisplayName string        `envconfig:"WEBAUTHN_RP_DISPLAY_NAME" default:"Medods"`
+	RPOrigins     []string      `envconfig:"WEBAUTHN_RP_ORIGINS" default:"http://localhost:8080"`
+	CeremonyTTL   time.Duration `envconfig:"WEBAUTHN_CEREMONY_TTL" default:"5m"`
+}
+
+type OAuthConfig struct {
+	CodeTTL time.Duration `envconfig:"OAUTH_CODE_TTL" default:"1m"`
+}
+
+type OIDCConfig struct {
+	Issuer         string        `envconfig:"OIDC_ISSUER" default:"http://localhost:8080"`
+	SigningKeyFile string        `envconfig:"OIDC_SIGNING_KEY_FILE" default:""`
+	IDTokenTTL     time.Duration `envconfig:"OIDC_ID_TOKEN_TTL" default:"1h"`
+}
+
+type FederationConfig struct {
+	// ProvidersFile JSON файл со списком внешних OIDC провайдеров, без него вход через них выключен
+	ProvidersFile string        `envconfig:"FEDERATION_PROVIDERS_FILE" default:""`
+	RedirectURL   string        `envconfig:"FEDERATION_REDIRECT_URL" default:"http://localhost:8080/v1/auth/federation/callback"`
+	StateTTL      time.Duration `envconfig:"FEDERATION_STATE_TTL" default:"10m"`
+}
+
+type APIKeyConfig struct {
+	DefaultTTL         time.Duration `envconfig:"API_KEY_DEFAULT_TTL" default:"2160h"`
+	LastUsedResolution time.Duration `envconfig:"API_KEY_LAST_USED_RESOLUTION" default:"1m"`
+}
+
+type TracingConfig struct {
+	// Exporter none или otlp, адрес коллектора берётся из стандартных OTEL_EXPORTER_OTLP_*
+	Exporter    string  `envconfig:"TRACING_EXPORTER" default:"none"`
+	ServiceName string  `envconfig:"OTEL_SERVICE_NAME" default:"auth_service"`
+	SampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+}
+
+type LogConfig struct {
+	// Format text или json
+	Format string `envconfig:"LOG_FORMAT" default:"text"`
+	// Level debug, info, warn или error. DEBUG=true включает debug независимо от него
+	Level string `envconfig:"LOG_LEVEL" default:"info"`
+}
+
+type AuditConfig struct {
+	DefaultLimit int `envconfig:"AUDIT_DEFAULT_LIMIT" default:"50"`
+	MaxLimit     int `envconfig:"AUDIT_MAX_LIMIT" default:"500"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `envconfig:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+}
+
+type ShutdownConfig struct {
+	// Timeout срок на остановку всех компонентов, включая DrainDelay
+	Timeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"30s"`
+	// DrainDelay сколько /readyz отвечает 503 перед остановкой сервера, чтобы балансировщик успел вывести под
+	DrainDelay time.Duration `envconfig:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+}
+
+type EventBusConfig struct {
+	Workers   int `envconfig:"EVENTBUS_WORKERS" default:"4"`
+	QueueSize int `envconfig:"EVENTBUS_QUEUE_SIZE" default:"1024"`
+	// PublishTimeout сколько запрос ждёт места в заполненной очереди, 0 отбрасывает событие сразу
+	PublishTimeout time.Duration `envconfig:"EVENTBUS_PUBLISH_TIMEOUT" default:"0"`
+	MaxAttempts    int           `envconfig:"EVENTBUS_MAX_ATTEMPTS" default:"5"`
+	RetryBase      time.Duration `envconfig:"EVENTBUS_RETRY_BASE" default:"200ms"`
+	RetryMax       time.Duration `envconfig:"EVENTBUS_RETRY_MAX" default:"10s"`
+}
+
+type HTTPConfig struct {
+	// LegacyRoutes обслуживает API и по старым путям без /v1, с заголовком Deprecation
+	LegacyRoutes bool `envconfig:"HTTP_LEGACY_ROUTES" default:"true"`
+	// LegacyDeprecatedAt с какого момента старые пути считаются устаревшими, RFC 3339
+	LegacyDeprecatedAt time.Time `envconfig:"HTTP_LEGACY_DEPRECATED_AT" default:"2026-10-19T00:00:00Z"`
+	// LegacySunset когда старые пути отключатся, пустое значение не отправляет заголовок Sunset
+	LegacySunset time.Time `envconfig:"HTTP_LEGACY_SUNSET"`
+}
+
+type GRPCConfig struct {
+	Port int `envconfig:"GRPC_PORT" default:"9090"`
+}
+
+type MetricsConfig struct {
+	// Port отдельный порт /metrics, чтобы метрики не были доступны вместе с API. 0 отключает эндпойнт
+	Port int `envconfig:"METRICS_PORT" default:"9464"`
+}
+
+type EventsConfig struct {
+	// Broker внешний брокер событий: none или nats. При none события в outbox не пишутся
+	Broker        string        `envconfig:"EVENTS_BROKER" default:"none"`
+	NatsURL       string        `envconfig:"NATS_URL" default:"nats://127.0.0.1:4222"`
+	NatsStream    string        `envconfig:"NATS_STREAM" default:"AUTH_EVENTS"`
+	SubjectPrefix string        `envconfig:"EVENTS_SUBJECT_PREFIX" default:"auth"`
+	// Format формат сообщений в брокере: json, cloudevents или cloudevents-binary
+	Format string `envconfig:"EVENTS_FORMAT" default:"json"`
+	// Source атрибут source событий CloudEvents, общий для брокера и вебхуков
+	Source string `envconfig:"EVENTS_SOURCE" default:"/auth_service"`
+	PollInterval  time.Duration `envconfig:"OUTBOX_POLL_INTERVAL" default:"1s"`
+	BatchSize     int           `envconfig:"OUTBOX_BATCH_SIZE" default:"100"`
+	RetryBase     time.Duration `envconfig:"OUTBOX_RETRY_BASE" default:"1s"`
+	RetryMax      time.Duration `envconfig:"OUTBOX_RETRY_MAX" default:"5m"`
+	// Retention сколько хранить отправленные сообщения, 0 не удаляет их
+	Retention time.Duration `envconfig:"OUTBOX_RETENTION" default:"168h"`
 }
 
 func (c *Config) Load() error {
@@ -35,6 +168,31 @@ func (c *Config) Load() error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	return c.validate()
+}
+
+// validate проверяет значения-перечисления, чтобы ошибка настройки останавливала запуск, а не всплывала при сборке компонентов
+func (c *Config) validate() error {
+	switch c.JWT.AccessAlg {
+	case "HS512", "RS256":
+	default:
+		return fmt.Errorf("invalid config: unsupported JWT_ACCESS_ALG %q, expected HS512 or RS256", c.JWT.AccessAlg)
+	}
+	switch c.Events.Broker {
+	case "", "none", "nats":
+	default:
+		return fmt.Errorf("invalid config: unsupported EVENTS_BROKER %q, expected none or nats", c.Events.Broker)
+	}
+	for _, f := range []struct{ name, value string }{
+		{"EVENTS_FORMAT", c.Events.Format},
+		{"USER_IP_CHANGED_WEBHOOK_FORMAT", c.UserIPChangedWebhookFormat},
+	} {
+		switch f.value {
+		case "", "json", "cloudevents", "cloudevents-binary":
+		default:
+			return fmt.Errorf("invalid config: unsupported %s %q, expected json, cloudevents or cloudevents-binary", f.name, f.value)
+		}
+	}
 	return nil
 }
 
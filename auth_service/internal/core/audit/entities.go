@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"time"
+)
+
+// Действия, попадающие в журнал
+const (
+	ActionLogin            = "login"
+	ActionRefresh          = "refresh"
+	ActionUAMismatch       = "ua_mismatch_logout"
+	ActionIPChanged        = "ip_changed"
+	ActionLogout           = "logout"
+	ActionAPIKeyCreated    = "admin.api_key_created"
+	ActionAPIKeyRevoked    = "admin.api_key_revoked"
+	ActionClientRegistered = "admin.oauth_client_registered"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	// OutcomePending первый фактор принят, ожидается второй
+	OutcomePending = "pending"
+)
+
+const ScopeAuditRead = "audit:read"
+
+// Event то, что сервисы сообщают журналу. Actor кто выполнил действие, Subject над кем
+type Event struct {
+	Action    string
+	Actor     string
+	Subject   string
+	IP        string
+	UserAgent string
+	Outcome   string
+	Reason    string
+}
+
+// Entry запись журнала. Hash считается от полей записи и PrevHash предыдущей,
+// поэтому изменение или удаление любой записи ломает цепочку
+type Entry struct {
+	Seq        int64
+	OccurredAt time.Time
+	Event
+	PrevHash string
+	Hash     string
+}
+
+type Filter struct {
+	Action  string
+	Actor   string
+	Subject string
+	Outcome string
+	From    time.Time
+	To      time.Time
+	// BeforeSeq курсор страницы: записи с меньшим Seq, 0 означает с конца журнала
+	BeforeSeq int64
+	Limit     int
+}
+
+type Page struct {
+	Entries []Entry
+	// NextBefore значение Filter.BeforeSeq для следующей страницы, 0 на последней
+	NextBefore int64
+}
+
+type Problem struct {
+	Seq    int64
+	Reason string
+}
+
+// Anchor последняя запись прошлой проверки, хранится вне базы.
+// Без неё обрезку хвоста журнала по самой цепочке не обнаружить
+type Anchor struct {
+	Seq  int64
+	Hash string
+}
+
+// VerifyReport LastSeq и LastHash становятся Anchor следующей проверки
+type VerifyReport struct {
+	Checked  int64
+	LastSeq  int64
+	LastHash string
+	Problems []Problem
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Record добавляет событие в журнал. Ошибка записи логируется и не прерывает саму операцию
+func (s *AuditService) Record(ctx context.Context, event Event) {
+	// запись не должна теряться, если клиент уже закрыл соединение
+	ctx = context.WithoutCancel(ctx)
+	err := s.repo.Append(ctx, func(last *Entry) (Entry, error) {
+		entry := Entry{
+			Seq: 1,
+			// база хранит микросекунды, хеш должен считаться от того же значения, что будет прочитано
+			OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+			Event:      event,
+		}
+		if last != nil {
+			entry.Seq = last.Seq + 1
+			entry.PrevHash = last.Hash
+		}
+		hash, err := entryHash(entry)
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.Hash = hash
+		return entry, nil
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to write audit log", "error", err, "action", event.Action, "subject", event.Subject)
+	}
+}
+
+// Query возвращает страницу записей от новых к старым
+func (s *AuditService) Query(ctx context.Context, filter Filter) (Page, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = s.conf.DefaultLimit
+	}
+	if limit > s.conf.MaxLimit {
+		limit = s.conf.MaxLimit
+	}
+
+	// лишняя запись показывает, есть ли следующая страница
+	filter.Limit = limit + 1
+	entries, err := s.repo.Query(ctx, filter)
+	if err != nil {
+		return Page{}, err
+	}
+	page := Page{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextBefore = entries[limit-1].Seq
+	}
+	return page, nil
+}
+
+// Verify проходит журнал целиком и сообщает о пропусках, изменённых записях и разрывах цепочки.
+// Ненулевой anchor дополнительно проверяет, что запись из прошлой проверки на месте и не обрезана
+func (s *AuditService) Verify(ctx context.Context, anchor Anchor) (VerifyReport, error) {
+	var report VerifyReport
+	err := s.repo.Scan(ctx, func(entry Entry) error {
+		if anchor.Seq != 0 && entry.Seq == anchor.Seq && entry.Hash != anchor.Hash {
+			report.Problems = append(report.Problems, Problem{Seq: entry.Seq, Reason: "hash differs from anchor"})
+		}
+		report.Checked++
+		if entry.Seq != report.LastSeq+1 {
+			report.Problems = append(report.Problems, Problem{
+				Seq:    entry.Seq,
+				Reason: fmt.Sprintf("gap: expected seq %d", report.LastSeq+1),
+			})
+		}
+		if entry.PrevHash != report.LastHash {
+			report.Problems = append(report.Problems, Problem{Seq: entry.Seq, Reason: "prev_hash does not match previous entry"})
+		}
+		hash, err := entryHash(entry)
+		if err != nil {
+			return err
+		}
+		if hash != entry.Hash {
+			report.Problems = append(report.Problems, Problem{Seq: entry.Seq, Reason: "hash mismatch: entry was modified"})
+		}
+		report.LastSeq = entry.Seq
+		report.LastHash = entry.Hash
+		return nil
+	})
+	if err == nil && report.LastSeq < anchor.Seq {
+		report.Problems = append(report.Problems, Problem{
+			Seq:    anchor.Seq,
+			Reason: fmt.Sprintf("truncated: log ends at seq %d", report.LastSeq),
+		})
+	}
+	return report, err
+}
+
+// hashedEntry порядок и имена полей входят в формат хеша, менять их нельзя
+type hashedEntry struct {
+	Seq        int64  `json:"seq"`
+	OccurredAt string `json:"occurred_at"`
+	Action     string `json:"action"`
+	Actor      string `json:"actor"`
+	Subject    string `json:"subject"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"user_agent"`
+	Outcome    string `json:"outcome"`
+	Reason     string `json:"reason"`
+	PrevHash   string `json:"prev_hash"`
+}
+
+func entryHash(entry Entry) (string, error) {
+	data, err := json.Marshal(hashedEntry{
+		Seq:        entry.Seq,
+		OccurredAt: entry.OccurredAt.UTC().Format(time.RFC3339Nano),
+		Action:     entry.Action,
+		Actor:      entry.Actor,
+		Subject:    entry.Subject,
+		IP:         entry.IP,
+		UserAgent:  entry.UserAgent,
+		Outcome:    entry.Outcome,
+		Reason:     entry.Reason,
+		PrevHash:   entry.PrevHash,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+)
+
+// AuditRepository хранит журнал только на добавление.
+// Append в одной транзакции и под блокировкой берёт последнюю запись (nil для пустого журнала),
+// получает от link новую запись и сохраняет её, чтобы параллельные записи не разветвили цепочку
+type AuditRepository interface {
+	Append(ctx context.Context, link func(last *Entry) (Entry, error)) error
+	Query(ctx context.Context, filter Filter) ([]Entry, error)
+	// Scan обходит все записи по возрастанию Seq
+	Scan(ctx context.Context, fn func(entry Entry) error) error
+}
+
+type Config struct {
+	DefaultLimit int
+	MaxLimit     int
+}
+
+type AuditService struct {
+	repo   AuditRepository
+	conf   Config
+	logger *slog.Logger
+}
+
+func NewAuditService(repo AuditRepository, conf Config, logger *slog.Logger) *AuditService {
+	return &AuditService{repo: repo, conf: conf, logger: logger}
+}
@@ -0,0 +1,31 @@
+package apikey
+
+import (
+	"time"
+)
+
+// KeyPrefix отличает API ключи от других секретов в логах и сканерах утечек
+const KeyPrefix = "mdk_"
+
+const ScopeAPIKeysManage = "apikeys:manage"
+
+// APIKey ключ для вызовов сервис-сервис. Сам ключ не хранится, только хеш секретной части.
+// Prefix открытая часть ключа, по которой он ищется в базе
+type APIKey struct {
+	ID         string
+	Name       string
+	Prefix     string
+	SecretHash string
+	Scopes     []string
+	CreatedBy  string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	RevokedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// CreatedKey результат создания, Secret показывается один раз
+type CreatedKey struct {
+	Key    APIKey
+	Secret string
+}
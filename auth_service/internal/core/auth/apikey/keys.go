@@ -0,0 +1,149 @@
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"medods_test/internal/core/audit"
+	"strings"
+	"time"
+)
+
+// длина открытой части ключа после KeyPrefix
+const prefixLength = 12
+
+type CreateCommand struct {
+	Name   string
+	Scopes []string
+	// TTL срок жизни ключа, нулевой означает Config.DefaultTTL
+	TTL       time.Duration
+	CreatedBy string
+}
+
+// Create выпускает ключ вида "mdk_<prefix>_<secret>"
+func (s *APIKeyService) Create(ctx context.Context, cmd CreateCommand) (_ CreatedKey, err error) {
+	var id string
+	defer func() {
+		s.recordAdminAction(ctx, audit.Event{
+			Action:  audit.ActionAPIKeyCreated,
+			Actor:   cmd.CreatedBy,
+			Subject: "api_key:" + id,
+			Reason:  "name=" + cmd.Name + " scopes=" + strings.Join(cmd.Scopes, " "),
+		}, err)
+	}()
+
+	id, err = s.idGenerator.Generate()
+	if err != nil {
+		return CreatedKey{}, err
+	}
+	secret, err := s.secrets.Generate()
+	if err != nil {
+		return CreatedKey{}, err
+	}
+
+	ttl := cmd.TTL
+	if ttl <= 0 {
+		ttl = s.conf.DefaultTTL
+	}
+	now := time.Now()
+	scopes := cmd.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	key := APIKey{
+		ID:         id,
+		Name:       cmd.Name,
+		Prefix:     strings.ReplaceAll(id, "-", "")[:prefixLength],
+		SecretHash: hashSecret(secret),
+		Scopes:     scopes,
+		CreatedBy:  cmd.CreatedBy,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	if err := s.repo.SaveKey(ctx, key); err != nil {
+		return CreatedKey{}, err
+	}
+
+	s.logger.InfoContext(ctx, "api key created", "api_key_id", key.ID, "name", key.Name, "created_by", key.CreatedBy)
+	return CreatedKey{Key: key, Secret: KeyPrefix + key.Prefix + "_" + secret}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]APIKey, error) {
+	return s.repo.ListKeys(ctx)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id string, revokedBy string) (err error) {
+	defer func() {
+		s.recordAdminAction(ctx, audit.Event{
+			Action:  audit.ActionAPIKeyRevoked,
+			Actor:   revokedBy,
+			Subject: "api_key:" + id,
+		}, err)
+	}()
+
+	if err := s.repo.RevokeKey(ctx, id, time.Now()); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "api key revoked", "api_key_id", id)
+	return nil
+}
+
+// Authenticate проверяет ключ из заголовка X-API-Key и отмечает время его использования
+func (s *APIKeyService) Authenticate(ctx context.Context, raw string) (APIKey, error) {
+	prefix, secret, ok := parseKey(raw)
+	if !ok {
+		return APIKey{}, ErrAPIKeyInvalid
+	}
+	key, err := s.repo.GetKeyByPrefix(ctx, prefix)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return APIKey{}, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return APIKey{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.SecretHash), []byte(hashSecret(secret))) != 1 {
+		return APIKey{}, ErrAPIKeyInvalid
+	}
+
+	now := time.Now()
+	if !key.RevokedAt.IsZero() {
+		return APIKey{}, ErrAPIKeyRevoked
+	}
+	if now.After(key.ExpiresAt) {
+		return APIKey{}, ErrAPIKeyExpired
+	}
+
+	if now.Sub(key.LastUsedAt) >= s.conf.LastUsedResolution {
+		if err := s.repo.TouchKey(ctx, key.ID, now); err != nil {
+			s.logger.WarnContext(ctx, "failed to update api key last_used_at", "error", err, "api_key_id", key.ID)
+		}
+		key.LastUsedAt = now
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) recordAdminAction(ctx context.Context, event audit.Event, err error) {
+	event.Outcome = audit.OutcomeSuccess
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+		event.Reason = err.Error()
+	}
+	s.audit.Record(ctx, event)
+}
+
+func parseKey(raw string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(raw, KeyPrefix)
+	if !ok || len(rest) < prefixLength+2 || rest[prefixLength] != '_' {
+		return "", "", false
+	}
+	return rest[:prefixLength], rest[prefixLength+1:], true
+}
+
+// hashSecret секрет случайный и длинный, поэтому медленный bcrypt для него не нужен
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
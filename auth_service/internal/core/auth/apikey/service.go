@@ -0,0 +1,56 @@
+package apikey
+
+import (
+	"context"
+	"log/slog"
+	"medods_test/internal/core/audit"
+	"time"
+)
+
+// APIKeyRepository GetKeyByPrefix и RevokeKey возвращают ErrAPIKeyNotFound, если ключа нет
+type APIKeyRepository interface {
+	SaveKey(ctx context.Context, key APIKey) error
+	GetKeyByPrefix(ctx context.Context, prefix string) (APIKey, error)
+	ListKeys(ctx context.Context) ([]APIKey, error)
+	RevokeKey(ctx context.Context, id string, at time.Time) error
+	TouchKey(ctx context.Context, id string, at time.Time) error
+}
+
+type StringIdGenerator interface {
+	Generate() (string, error)
+}
+
+type SecretGenerator interface {
+	Generate() (string, error)
+}
+
+// AuditRecorder журнал аудита административных действий
+type AuditRecorder interface {
+	Record(ctx context.Context, event audit.Event)
+}
+
+type Config struct {
+	DefaultTTL time.Duration
+	// LastUsedResolution как часто обновляется last_used_at, чтобы не писать в базу на каждый запрос
+	LastUsedResolution time.Duration
+}
+
+type APIKeyService struct {
+	repo        APIKeyRepository
+	idGenerator StringIdGenerator
+	secrets     SecretGenerator
+	conf        Config
+	audit       AuditRecorder
+	logger      *slog.Logger
+}
+
+func NewAPIKeyService(repo APIKeyRepository, idGenerator StringIdGenerator, secrets SecretGenerator, conf Config, audit AuditRecorder, logger *slog.Logger) *APIKeyService {
+	return &APIKeyService{
+		repo:        repo,
+		idGenerator: idGenerator,
+		secrets:     secrets,
+		conf:        conf,
+		audit:       audit,
+		logger:      logger,
+	}
+}
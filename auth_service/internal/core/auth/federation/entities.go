@@ -0,0 +1,62 @@
+package federation
+
+import (
+	"time"
+
+	"medods_test/internal/core/auth/stateless"
+)
+
+// ExternalIdentity пользователь внешнего провайдера по данным проверенного ID токена
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+}
+
+// LinkedIdentity связь внешнего subject с пользователем из таблицы users
+type LinkedIdentity struct {
+	Provider  string
+	Subject   string
+	UserID    stateless.UserID
+	Email     string
+	CreatedAt time.Time
+}
+
+// AuthorizationRequest параметры перенаправления на провайдера
+type AuthorizationRequest struct {
+	State         string
+	Nonce         string
+	CodeChallenge string
+}
+
+// LoginState состояние входа между перенаправлением на провайдера и callback.
+// Сами значения state и привязки к браузеру не хранятся, только их хеши
+type LoginState struct {
+	StateHash string
+	// BindingHash хеш значения из cookie браузера, начавшего вход
+	BindingHash  string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	// LinkUserID заполнен, если пользователь привязывает внешний аккаунт к существующему
+	LinkUserID stateless.UserID
+	ExpiresAt  time.Time
+}
+
+type BeginResult struct {
+	RedirectTo string
+	// Binding отдаётся браузеру в cookie и должен вернуться в callback вместе со state
+	Binding string
+}
+
+type CallbackResult struct {
+	UserID stateless.UserID
+	// Tokens пустой при привязке аккаунта, у пользователя уже есть сессия
+	Tokens  stateless.TokenPair
+	Linked  bool
+	Created bool
+}
@@ -0,0 +1,14 @@
+package federation
+
+import (
+	"errors"
+)
+
+var (
+	ErrProviderNotFound      = errors.New("identity provider not found")
+	ErrStateNotFound         = errors.New("login state not found or expired")
+	ErrStateBindingMismatch  = errors.New("login state was issued to another browser")
+	ErrIdentityNotFound      = errors.New("external identity not linked")
+	ErrIdentityAlreadyLinked = errors.New("external identity already linked to another user")
+	ErrUpstreamLogin         = errors.New("upstream login failed")
+)
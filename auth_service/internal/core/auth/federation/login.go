@@ -0,0 +1,192 @@
+package federation
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/user"
+)
+
+type BeginCommand struct {
+	Provider string
+	// LinkUserID пользователь, к которому привязывается внешний аккаунт. Пустой для обычного входа
+	LinkUserID stateless.UserID
+}
+
+// Begin сохраняет state, nonce и PKCE verifier и возвращает адрес входа у провайдера.
+// Binding из результата привязывает вход к браузеру: без него чужой state, подсунутый жертве, не примется
+func (s *FederationService) Begin(ctx context.Context, cmd BeginCommand) (BeginResult, error) {
+	provider, ok := s.providers[cmd.Provider]
+	if !ok {
+		return BeginResult{}, ErrProviderNotFound
+	}
+
+	state, err := s.random.Generate()
+	if err != nil {
+		return BeginResult{}, err
+	}
+	nonce, err := s.random.Generate()
+	if err != nil {
+		return BeginResult{}, err
+	}
+	verifier, err := s.random.Generate()
+	if err != nil {
+		return BeginResult{}, err
+	}
+	binding, err := s.random.Generate()
+	if err != nil {
+		return BeginResult{}, err
+	}
+
+	err = s.states.SaveState(ctx, LoginState{
+		StateHash:    hashState(state),
+		BindingHash:  hashState(binding),
+		Provider:     cmd.Provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   cmd.LinkUserID,
+		ExpiresAt:    time.Now().Add(s.conf.StateTTL),
+	})
+	if err != nil {
+		return BeginResult{}, err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	authURL, err := provider.AuthCodeURL(ctx, AuthorizationRequest{
+		State:         state,
+		Nonce:         nonce,
+		CodeChallenge: base64.RawURLEncoding.EncodeToString(challenge[:]),
+	})
+	if err != nil {
+		return BeginResult{}, fmt.Errorf("%w: %w", ErrUpstreamLogin, err)
+	}
+	return BeginResult{RedirectTo: authURL, Binding: binding}, nil
+}
+
+type CallbackCommand struct {
+	State string
+	// Binding значение из cookie, выданной в Begin
+	Binding   string
+	Code      string
+	UserAgent string
+	IP        string
+}
+
+// Callback завершает вход: обменивает код у провайдера и находит пользователя по внешнему subject.
+// Незнакомый subject получает нового пользователя. Привязка к существующему аккаунту по email не делается,
+// это возможно только явно, через вход с LinkUserID
+func (s *FederationService) Callback(ctx context.Context, cmd CallbackCommand) (CallbackResult, error) {
+	if cmd.State == "" {
+		return CallbackResult{}, ErrStateNotFound
+	}
+	state, err := s.states.TakeState(ctx, hashState(cmd.State))
+	if err != nil {
+		return CallbackResult{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashState(cmd.Binding)), []byte(state.BindingHash)) != 1 {
+		return CallbackResult{}, ErrStateBindingMismatch
+	}
+	provider, ok := s.providers[state.Provider]
+	if !ok {
+		return CallbackResult{}, ErrProviderNotFound
+	}
+
+	identity, err := provider.Exchange(ctx, cmd.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return CallbackResult{}, fmt.Errorf("%w: %w", ErrUpstreamLogin, err)
+	}
+	identity.Provider = state.Provider
+
+	if state.LinkUserID != "" {
+		if err := s.link(ctx, state.LinkUserID, identity); err != nil {
+			return CallbackResult{}, err
+		}
+		return CallbackResult{UserID: state.LinkUserID, Linked: true}, nil
+	}
+
+	result := CallbackResult{}
+	linked, err := s.identities.GetIdentity(ctx, identity.Provider, identity.Subject)
+	switch {
+	case err == nil:
+		result.UserID = linked.UserID
+	case errors.Is(err, ErrIdentityNotFound):
+		result.UserID, err = s.provisionUser(ctx, identity)
+		if err != nil {
+			return CallbackResult{}, err
+		}
+		result.Created = true
+	default:
+		return CallbackResult{}, err
+	}
+
+	result.Tokens, err = s.tokens.AuthenticateExternalUser(ctx, stateless.ExternalAuthCommand{
+		UserID:    result.UserID,
+		UserAgent: cmd.UserAgent,
+		IP:        cmd.IP,
+	})
+	if err != nil {
+		return CallbackResult{}, err
+	}
+	return result, nil
+}
+
+// ListIdentities внешние аккаунты, привязанные к пользователю
+func (s *FederationService) ListIdentities(ctx context.Context, userID stateless.UserID) ([]LinkedIdentity, error) {
+	return s.identities.ListIdentities(ctx, userID)
+}
+
+func (s *FederationService) link(ctx context.Context, userID stateless.UserID, identity ExternalIdentity) error {
+	linked, err := s.identities.GetIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		if linked.UserID != userID {
+			return ErrIdentityAlreadyLinked
+		}
+		return nil
+	}
+	if !errors.Is(err, ErrIdentityNotFound) {
+		return err
+	}
+	return s.saveIdentity(ctx, userID, identity)
+}
+
+func (s *FederationService) provisionUser(ctx context.Context, identity ExternalIdentity) (stateless.UserID, error) {
+	profile, err := s.users.CreateUser(ctx, user.Profile{
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Name:          identity.Name,
+		GivenName:     identity.GivenName,
+		FamilyName:    identity.FamilyName,
+	})
+	if err != nil {
+		return "", err
+	}
+	userID := stateless.UserID(profile.ID)
+
+	if err := s.saveIdentity(ctx, userID, identity); err != nil {
+		return "", err
+	}
+	s.logger.InfoContext(ctx, "user created from external identity", "user_id", userID, "provider", identity.Provider)
+	return userID, nil
+}
+
+func (s *FederationService) saveIdentity(ctx context.Context, userID stateless.UserID, identity ExternalIdentity) error {
+	return s.identities.SaveIdentity(ctx, LinkedIdentity{
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		UserID:    userID,
+		Email:     identity.Email,
+		CreatedAt: time.Now(),
+	})
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
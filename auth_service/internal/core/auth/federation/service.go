@@ -0,0 +1,81 @@
+package federation
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/user"
+)
+
+// Provider внешний OIDC провайдер. Exchange обменивает код на токены и возвращает
+// данные пользователя из ID токена, проверив подпись, аудиторию и nonce
+type Provider interface {
+	AuthCodeURL(ctx context.Context, req AuthorizationRequest) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (ExternalIdentity, error)
+}
+
+// StateRepository TakeState удаляет состояние и возвращает ErrStateNotFound, если его нет или оно истекло
+type StateRepository interface {
+	SaveState(ctx context.Context, state LoginState) error
+	TakeState(ctx context.Context, stateHash string) (LoginState, error)
+}
+
+// IdentityRepository GetIdentity возвращает ErrIdentityNotFound, если subject ещё не привязан
+type IdentityRepository interface {
+	GetIdentity(ctx context.Context, provider, subject string) (LinkedIdentity, error)
+	SaveIdentity(ctx context.Context, identity LinkedIdentity) error
+	ListIdentities(ctx context.Context, userID stateless.UserID) ([]LinkedIdentity, error)
+}
+
+type UserProvisioner interface {
+	CreateUser(ctx context.Context, profile user.Profile) (user.Profile, error)
+}
+
+// TokenIssuer часть StatelessAuthService, которая выдаёт пару токенов после внешнего входа
+type TokenIssuer interface {
+	AuthenticateExternalUser(ctx context.Context, cmd stateless.ExternalAuthCommand) (stateless.TokenPair, error)
+}
+
+// RandomGenerator источник state, nonce и PKCE verifier
+type RandomGenerator interface {
+	Generate() (string, error)
+}
+
+type Config struct {
+	StateTTL time.Duration
+}
+
+type FederationService struct {
+	providers  map[string]Provider
+	states     StateRepository
+	identities IdentityRepository
+	users      UserProvisioner
+	tokens     TokenIssuer
+	random     RandomGenerator
+	conf       Config
+	logger     *slog.Logger
+}
+
+func NewFederationService(
+	providers map[string]Provider,
+	states StateRepository,
+	identities IdentityRepository,
+	users UserProvisioner,
+	tokens TokenIssuer,
+	random RandomGenerator,
+	conf Config,
+	logger *slog.Logger,
+) *FederationService {
+	return &FederationService{
+		providers:  providers,
+		states:     states,
+		identities: identities,
+		users:      users,
+		tokens:     tokens,
+		random:     random,
+		conf:       conf,
+		logger:     logger,
+	}
+}
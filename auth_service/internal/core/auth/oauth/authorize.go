@@ -0,0 +1,130 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"medods_test/internal/core/auth/stateless"
+)
+
+type AuthorizeCommand struct {
+	UserID stateless.UserID
+	// AuthTime и AMR берутся из access токена пользователя и попадают в ID токен
+	AuthTime time.Time
+	AMR      []string
+	// CallerScopes права токена пользователя, одобряющего запрос. Клиент не получит больше них,
+	// иначе урезанный токен с одним oauth:authorize выдавал бы через code flow любые права
+	CallerScopes        []string
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+type AuthorizeResult struct {
+	// RedirectURI заполнен, если клиент и адрес возврата проверены и ответ (в том числе ошибку) можно отдать редиректом
+	RedirectURI string
+	Code        string
+	State       string
+}
+
+// Authorize выдаёт код авторизации уже аутентифицированному пользователю.
+// Ошибки до проверки redirect_uri возвращаются с пустым RedirectURI и не должны отдаваться редиректом
+func (s *OAuthService) Authorize(ctx context.Context, cmd AuthorizeCommand) (AuthorizeResult, error) {
+	if cmd.ClientID == "" {
+		return AuthorizeResult{}, newError(ErrorInvalidRequest, "client_id is required")
+	}
+	client, err := s.clients.GetClient(ctx, cmd.ClientID)
+	if errors.Is(err, ErrClientNotFound) {
+		return AuthorizeResult{}, newError(ErrorInvalidRequest, "unknown client")
+	}
+	if err != nil {
+		return AuthorizeResult{}, err
+	}
+
+	redirectURI := cmd.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return AuthorizeResult{}, newError(ErrorInvalidRequest, "redirect_uri is not registered for client")
+	}
+
+	result := AuthorizeResult{RedirectURI: redirectURI, State: cmd.State}
+
+	if cmd.ResponseType != "code" {
+		return result, newError(ErrorUnsupportedResponseType, "only response_type=code is supported")
+	}
+	if !client.AllowsGrant(GrantAuthorizationCode) {
+		return result, newError(ErrorUnauthorizedClient, "client is not allowed to use authorization_code")
+	}
+	if cmd.CodeChallenge == "" {
+		return result, newError(ErrorInvalidRequest, "code_challenge is required")
+	}
+	if cmd.CodeChallengeMethod != "S256" {
+		return result, newError(ErrorInvalidRequest, "only code_challenge_method=S256 is supported")
+	}
+	scopes, err := resolveScopes(client, cmd.Scope)
+	if err != nil {
+		return result, err
+	}
+	scopes, err = limitToCaller(scopes, cmd.CallerScopes, strings.TrimSpace(cmd.Scope) != "")
+	if err != nil {
+		return result, err
+	}
+
+	code, err := s.secretAlgs.Generate()
+	if err != nil {
+		return result, err
+	}
+
+	err = s.codes.SaveCode(ctx, AuthorizationCode{
+		CodeHash:            hashCode(code),
+		ClientID:            client.ID,
+		UserID:              cmd.UserID,
+		RedirectURI:         redirectURI,
+		RedirectURIProvided: cmd.RedirectURI != "",
+		Scopes:              scopes,
+		CodeChallenge:       cmd.CodeChallenge,
+		Nonce:               cmd.Nonce,
+		AuthTime:            cmd.AuthTime,
+		AMR:                 cmd.AMR,
+		ExpiresAt:           time.Now().Add(s.conf.CodeTTL),
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Code = code
+	return result, nil
+}
+
+// limitToCaller ограничивает scope правами пользователя. Явно запрошенный scope сверх них отклоняется,
+// набор по умолчанию молча сужается. Scope OIDC открывают только данные самого пользователя и не ограничиваются
+func limitToCaller(scopes, callerScopes []string, requested bool) ([]string, error) {
+	limited := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		switch {
+		case scope == ScopeOpenID || scope == ScopeProfile || scope == ScopeEmail || slices.Contains(callerScopes, scope):
+			limited = append(limited, scope)
+		case requested:
+			return nil, newError(ErrorInvalidScope, "scope "+scope+" is not granted to the user token")
+		}
+	}
+	return limited, nil
+}
+
+// hashCode коды авторизации ищутся по хешу, поэтому вместо bcrypt используется sha256
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+)
+
+type RegisterClientCommand struct {
+	ID                     string
+	Name                   string
+	Public                 bool
+	RedirectURIs           []string
+	AllowedScopes          []string
+	GrantTypes             []string
+	PostLogoutRedirectURIs []string
+}
+
+// RegisterClient добавляет клиента в реестр и возвращает его секрет.
+// Секрет показывается один раз, в базе хранится только хеш
+func (s *OAuthService) RegisterClient(ctx context.Context, cmd RegisterClientCommand) (string, error) {
+	client := Client{
+		ID:            cmd.ID,
+		Name:          cmd.Name,
+		RedirectURIs:  cmd.RedirectURIs,
+		AllowedScopes: cmd.AllowedScopes,
+		GrantTypes:    cmd.GrantTypes,
+
+		PostLogoutRedirectURIs: cmd.PostLogoutRedirectURIs,
+	}
+
+	var secret string
+	if !cmd.Public {
+		var err error
+		secret, err = s.secretAlgs.Generate()
+		if err != nil {
+			return "", err
+		}
+		client.SecretHash, err = s.secretAlgs.GetHash(secret)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := s.clients.SaveClient(ctx, client); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// AuthenticateClient проверяет client_id и секрет. Публичные клиенты не должны передавать секрет
+func (s *OAuthService) AuthenticateClient(ctx context.Context, creds ClientCredentials) (Client, error) {
+	if creds.ID == "" {
+		return Client{}, newError(ErrorInvalidClient, "client authentication required")
+	}
+	client, err := s.clients.GetClient(ctx, creds.ID)
+	if errors.Is(err, ErrClientNotFound) {
+		return Client{}, newError(ErrorInvalidClient, "unknown client")
+	}
+	if err != nil {
+		return Client{}, err
+	}
+
+	if client.IsPublic() {
+		if creds.Secret != "" {
+			return Client{}, newError(ErrorInvalidClient, "public client must not use a secret")
+		}
+		return client, nil
+	}
+	if creds.Secret == "" || !s.secretAlgs.CompareHash(client.SecretHash, creds.Secret) {
+		return Client{}, newError(ErrorInvalidClient, "client authentication failed")
+	}
+	return client, nil
+}
+
+// resolveScopes проверяет запрошенные scope. Без явного запроса выдаются все разрешённые клиенту
+func resolveScopes(client Client, requested string) ([]string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return client.AllowedScopes, nil
+	}
+	scopes := strings.Fields(requested)
+	for _, scope := range scopes {
+		if !slices.Contains(client.AllowedScopes, scope) {
+			return nil, newError(ErrorInvalidScope, "scope "+scope+" is not allowed for client")
+		}
+	}
+	return scopes, nil
+}
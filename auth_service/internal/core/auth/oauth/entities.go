@@ -0,0 +1,100 @@
+package oauth
+
+import (
+	"slices"
+	"time"
+
+	"medods_test/internal/core/auth/stateless"
+)
+
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// Client зарегистрированное приложение. Клиент без SecretHash считается публичным
+// и может пользоваться только authorization_code с PKCE
+type Client struct {
+	ID            string
+	Name          string
+	SecretHash    string
+	RedirectURIs  []string
+	AllowedScopes []string
+	GrantTypes    []string
+	// PostLogoutRedirectURIs адреса, на которые разрешено вернуть пользователя после RP-initiated logout
+	PostLogoutRedirectURIs []string
+}
+
+func (c Client) IsPublic() bool {
+	return c.SecretHash == ""
+}
+
+func (c Client) AllowsGrant(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+type AuthorizationCode struct {
+	CodeHash            string
+	ClientID            string
+	UserID              stateless.UserID
+	RedirectURI         string
+	RedirectURIProvided bool
+	Scopes              []string
+	CodeChallenge       string
+	Nonce               string
+	AuthTime            time.Time
+	AMR                 []string
+	ExpiresAt           time.Time
+}
+
+// Стандартные scope OIDC
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// Значения token_type_hint из RFC 7009 и RFC 7662
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// IntrospectionResult ответ /oauth/introspect. Для неактивного токена заполнено только Active
+type IntrospectionResult struct {
+	Active      bool
+	Subject     stateless.UserID
+	ClientID    string
+	TokenType   string
+	TokenPairID stateless.TokenPairID
+	ExpiresAt   time.Time
+	Scopes      []string
+}
+
+// IDTokenClaims содержимое ID токена OIDC. iss, iat и exp добавляет реализация IDTokenAlgoHelper
+type IDTokenClaims struct {
+	Subject   stateless.UserID
+	Audience  string
+	Nonce     string
+	AuthTime  time.Time
+	AMR       []string
+	SessionID stateless.SessionID
+	// AccessToken используется только для вычисления at_hash
+	AccessToken string
+}
+
+// ClientCredentials данные аутентификации клиента из заголовка Basic или тела запроса
+type ClientCredentials struct {
+	ID     string
+	Secret string
+}
+
+type TokenResponse struct {
+	AccessToken  string
+	TokenType    string
+	ExpiresIn    int64
+	RefreshToken string
+	IDToken      string
+	Scopes       []string
+}
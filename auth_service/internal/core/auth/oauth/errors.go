@@ -0,0 +1,42 @@
+package oauth
+
+import (
+	"errors"
+)
+
+// Коды ошибок из RFC 6749, разделы 4.1.2.1 и 5.2
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorAccessDenied            = "access_denied"
+	ErrorServerError             = "server_error"
+	// ErrorUnsupportedTokenType из RFC 7009, раздел 2.2.1
+	ErrorUnsupportedTokenType = "unsupported_token_type"
+)
+
+var (
+	ErrClientNotFound = errors.New("oauth client not found")
+	ErrCodeNotFound   = errors.New("authorization code not found")
+)
+
+// Error ошибка протокола, которая отдаётся клиенту как есть
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+func newError(code, description string) *Error {
+	return &Error{Code: code, Description: description}
+}
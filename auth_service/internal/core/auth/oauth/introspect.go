@@ -0,0 +1,117 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+
+	"medods_test/internal/core/auth/stateless"
+)
+
+type IntrospectCommand struct {
+	Client        ClientCredentials
+	Token         string
+	TokenTypeHint string
+}
+
+// Introspect реализует RFC 7662. Спрашивать о токенах могут только конфиденциальные клиенты.
+// client_id токена не сравнивается с клиентом: ресурсные серверы проверяют токены, выданные другим клиентам,
+// и получают владельца в поле client_id. О недействительном токене отвечается active=false без подробностей
+func (s *OAuthService) Introspect(ctx context.Context, cmd IntrospectCommand) (IntrospectionResult, error) {
+	client, err := s.AuthenticateClient(ctx, cmd.Client)
+	if err != nil {
+		return IntrospectionResult{}, err
+	}
+	if client.IsPublic() {
+		return IntrospectionResult{}, newError(ErrorUnauthorizedClient, "public clients can not introspect tokens")
+	}
+	if cmd.Token == "" {
+		return IntrospectionResult{}, newError(ErrorInvalidRequest, "token is required")
+	}
+
+	token, tokenType, err := s.findActiveToken(ctx, cmd.Token, cmd.TokenTypeHint)
+	if err != nil || tokenType == "" {
+		return IntrospectionResult{Active: false}, err
+	}
+
+	return IntrospectionResult{
+		Active:      true,
+		Subject:     token.UserID,
+		ClientID:    token.ClientID,
+		TokenType:   tokenType,
+		TokenPairID: token.TokenPairID,
+		ExpiresAt:   token.ExpiresAt,
+		Scopes:      token.Scopes,
+	}, nil
+}
+
+type RevokeCommand struct {
+	Client        ClientCredentials
+	Token         string
+	TokenTypeHint string
+}
+
+// Revoke реализует RFC 7009: завершает сессию, к которой относится access или refresh токен.
+// Неизвестный токен не считается ошибкой, отозвать можно только токены своего клиента
+func (s *OAuthService) Revoke(ctx context.Context, cmd RevokeCommand) error {
+	client, err := s.AuthenticateClient(ctx, cmd.Client)
+	if err != nil {
+		return err
+	}
+	if cmd.Token == "" {
+		return newError(ErrorInvalidRequest, "token is required")
+	}
+
+	token, tokenType, err := s.findActiveToken(ctx, cmd.Token, cmd.TokenTypeHint)
+	if err != nil {
+		return err
+	}
+	if tokenType == "" {
+		return nil
+	}
+	if token.ClientID != client.ID {
+		return newError(ErrorUnauthorizedClient, "token was issued to another client")
+	}
+	if token.SessionID == "" {
+		return newError(ErrorUnsupportedTokenType, "client_credentials access tokens can not be revoked")
+	}
+
+	// сессию мог завершить параллельный запрос, для RFC 7009 это тоже успех
+	if err := s.tokens.EndSession(ctx, token.UserID, token.SessionID); err != nil && !errors.Is(err, stateless.ErrSessionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// findActiveToken определяет тип токена, начиная с подсказки клиента.
+// Пустой тип означает, что токен неизвестен, истёк или его сессия завершена
+func (s *OAuthService) findActiveToken(ctx context.Context, raw, hint string) (stateless.ActiveToken, string, error) {
+	order := []string{TokenTypeHintAccessToken, TokenTypeHintRefreshToken}
+	if hint == TokenTypeHintRefreshToken {
+		order = []string{TokenTypeHintRefreshToken, TokenTypeHintAccessToken}
+	}
+
+	for _, tokenType := range order {
+		var token stateless.ActiveToken
+		var err error
+		if tokenType == TokenTypeHintAccessToken {
+			token, err = s.tokens.IntrospectAccessToken(ctx, stateless.AccessToken(raw))
+		} else {
+			userID, refreshToken, ok := parseRefreshToken(raw)
+			if !ok {
+				continue
+			}
+			token, err = s.tokens.IntrospectRefreshToken(ctx, userID, refreshToken)
+		}
+
+		switch {
+		case err == nil:
+			return token, tokenType, nil
+		case errors.Is(err, stateless.ErrAccessTokenInvalid), errors.Is(err, stateless.ErrAccessTokenExpired),
+			errors.Is(err, stateless.ErrSessionNotFound):
+			continue
+		default:
+			return stateless.ActiveToken{}, "", err
+		}
+	}
+	return stateless.ActiveToken{}, "", nil
+}
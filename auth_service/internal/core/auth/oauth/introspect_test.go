@@ -0,0 +1,116 @@
+package oauth_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"medods_test/internal/core/auth/oauth"
+	"medods_test/internal/core/auth/oauth/oauthtest"
+	"medods_test/internal/core/auth/stateless"
+)
+
+// issueTokens выдаёт клиенту пару токенов через code flow
+func issueTokens(t *testing.T, svc *oauthtest.Service, client oauth.ClientCredentials) oauth.TokenResponse {
+	t.Helper()
+	response, err := exchange(svc, client, issueCode(t, svc, client.ID), testRedirect, testVerifier)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	return response
+}
+
+func introspect(t *testing.T, svc *oauthtest.Service, client oauth.ClientCredentials, token string) oauth.IntrospectionResult {
+	t.Helper()
+	result, err := svc.Introspect(context.Background(), oauth.IntrospectCommand{Client: client, Token: token})
+	if err != nil {
+		t.Fatalf("Introspect: %v", err)
+	}
+	return result
+}
+
+func revoke(svc *oauthtest.Service, client oauth.ClientCredentials, token string) error {
+	return svc.Revoke(context.Background(), oauth.RevokeCommand{Client: client, Token: token})
+}
+
+func TestIntrospectInactiveTokens(t *testing.T) {
+	tests := []struct {
+		name           string
+		accessTokenTTL time.Duration
+		// end делает токен недействительным и возвращает его
+		end func(t *testing.T, svc *oauthtest.Service, tokens oauth.TokenResponse) string
+	}{
+		{
+			name:           "expired access token",
+			accessTokenTTL: -time.Minute,
+			end: func(_ *testing.T, _ *oauthtest.Service, tokens oauth.TokenResponse) string {
+				return tokens.AccessToken
+			},
+		},
+		{
+			name: "access token after logout",
+			end: func(t *testing.T, svc *oauthtest.Service, tokens oauth.TokenResponse) string {
+				logout(t, svc, tokens)
+				return tokens.AccessToken
+			},
+		},
+		{
+			name: "refresh token after logout",
+			end: func(t *testing.T, svc *oauthtest.Service, tokens oauth.TokenResponse) string {
+				logout(t, svc, tokens)
+				return tokens.RefreshToken
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := oauthtest.NewService(t, oauthtest.Options{AccessTokenTTL: tt.accessTokenTTL})
+			client := registerClient(t, svc, "web", false)
+			token := tt.end(t, svc, issueTokens(t, svc, client))
+
+			if result := introspect(t, svc, client, token); result.Active {
+				t.Fatalf("introspection = %+v, want active=false", result)
+			}
+		})
+	}
+}
+
+func logout(t *testing.T, svc *oauthtest.Service, tokens oauth.TokenResponse) {
+	t.Helper()
+	userID, refreshToken, _ := strings.Cut(tokens.RefreshToken, ".")
+	err := svc.Auth.Logout(context.Background(), stateless.LogoutCommand{
+		UserID:       stateless.UserID(userID),
+		RefreshToken: stateless.RefreshToken(refreshToken),
+	})
+	if err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+}
+
+func TestRevokeTokenOfAnotherClient(t *testing.T) {
+	svc := oauthtest.NewService(t, oauthtest.Options{})
+	owner := registerClient(t, svc, "web", false)
+	other := registerClient(t, svc, "other", false)
+	tokens := issueTokens(t, svc, owner)
+
+	assertOAuthError(t, revoke(svc, other, tokens.RefreshToken), oauth.ErrorUnauthorizedClient)
+	if result := introspect(t, svc, owner, tokens.AccessToken); !result.Active {
+		t.Fatal("token was revoked by another client")
+	}
+}
+
+func TestRevokeIsIdempotent(t *testing.T) {
+	svc := oauthtest.NewService(t, oauthtest.Options{})
+	client := registerClient(t, svc, "web", false)
+	tokens := issueTokens(t, svc, client)
+
+	for i := range 2 {
+		if err := revoke(svc, client, tokens.RefreshToken); err != nil {
+			t.Fatalf("revoke #%d: %v", i+1, err)
+		}
+	}
+	if result := introspect(t, svc, client, tokens.AccessToken); result.Active {
+		t.Fatal("access token is active after its session was revoked")
+	}
+}
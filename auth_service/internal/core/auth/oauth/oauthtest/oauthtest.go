@@ -0,0 +1,120 @@
+// Package oauthtest реализации портов oauth в памяти для тестов сервиса.
+// Хранилище кодов, как и postgres, удаляет код при чтении
+package oauthtest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"medods_test/internal/adapters/auth/stateless/jwthelper"
+	"medods_test/internal/core/auth/oauth"
+	"medods_test/internal/core/auth/stateless/statelesstest"
+	"medods_test/internal/core/user"
+	"medods_test/pkg/unikelongstring"
+)
+
+type ClientRepository struct {
+	mu      sync.Mutex
+	clients map[string]oauth.Client
+}
+
+func NewClientRepository() *ClientRepository {
+	return &ClientRepository{clients: map[string]oauth.Client{}}
+}
+
+func (r *ClientRepository) SaveClient(_ context.Context, client oauth.Client) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[client.ID] = client
+	return nil
+}
+
+func (r *ClientRepository) GetClient(_ context.Context, clientID string) (oauth.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	client, ok := r.clients[clientID]
+	if !ok {
+		return oauth.Client{}, oauth.ErrClientNotFound
+	}
+	return client, nil
+}
+
+type CodeRepository struct {
+	mu    sync.Mutex
+	codes map[string]oauth.AuthorizationCode
+}
+
+func NewCodeRepository() *CodeRepository {
+	return &CodeRepository{codes: map[string]oauth.AuthorizationCode{}}
+}
+
+func (r *CodeRepository) SaveCode(_ context.Context, code oauth.AuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes[code.CodeHash] = code
+	return nil
+}
+
+func (r *CodeRepository) ConsumeCode(_ context.Context, codeHash string) (oauth.AuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[codeHash]
+	if !ok {
+		return oauth.AuthorizationCode{}, oauth.ErrCodeNotFound
+	}
+	delete(r.codes, codeHash)
+	return code, nil
+}
+
+// Profiles отдаёт профиль с email для любого пользователя
+type Profiles struct{}
+
+func (Profiles) GetProfile(_ context.Context, id string) (user.Profile, error) {
+	return user.Profile{ID: id, Email: id + "@example.com", EmailVerified: true}, nil
+}
+
+// Service OAuth сервис поверх хранилищ в памяти и statelesstest.Service, который выдаёт токены
+type Service struct {
+	*oauth.OAuthService
+	Auth    *statelesstest.Service
+	Clients *ClientRepository
+	Codes   *CodeRepository
+}
+
+// Options нулевые поля заменяются значениями по умолчанию
+type Options struct {
+	// CodeTTL отрицательный выдаёт уже истёкшие коды
+	CodeTTL time.Duration
+	// AccessTokenTTL передаётся в statelesstest.Options
+	AccessTokenTTL time.Duration
+}
+
+func NewService(t testing.TB, opts Options) *Service {
+	t.Helper()
+	if opts.CodeTTL == 0 {
+		opts.CodeTTL = time.Minute
+	}
+	key, _, err := jwthelper.LoadSigningKey("")
+	if err != nil {
+		t.Fatalf("LoadSigningKey: %v", err)
+	}
+	s := &Service{
+		Auth:    statelesstest.NewService(t, statelesstest.Options{AccessTokenTTL: opts.AccessTokenTTL}),
+		Clients: NewClientRepository(),
+		Codes:   NewCodeRepository(),
+	}
+	s.OAuthService = oauth.NewOAuthService(
+		s.Clients,
+		s.Codes,
+		unikelongstring.NewULSHelper(),
+		s.Auth,
+		jwthelper.NewJWTIDTokenHelper(key, "https://auth.example.com", time.Minute),
+		Profiles{},
+		oauth.Config{CodeTTL: opts.CodeTTL, AccessTokenTTL: time.Minute},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return s
+}
@@ -0,0 +1,100 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/user"
+)
+
+// issueIDToken sid берётся из постоянного идентификатора сессии, чтобы logout работал и после refresh
+func (s *OAuthService) issueIDToken(code AuthorizationCode, tokens stateless.TokenPair) (string, error) {
+	return s.idTokens.Generate(IDTokenClaims{
+		Subject:     code.UserID,
+		Audience:    code.ClientID,
+		Nonce:       code.Nonce,
+		AuthTime:    code.AuthTime,
+		AMR:         code.AMR,
+		SessionID:   tokens.SessionID,
+		AccessToken: string(tokens.AccessToken),
+	})
+}
+
+// UserInfo возвращает claims для /userinfo: имя при scope profile, почту при scope email.
+// Если профиля нет, отдаётся только sub
+func (s *OAuthService) UserInfo(ctx context.Context, userID stateless.UserID, scopes []string) (map[string]any, error) {
+	claims := map[string]any{"sub": string(userID)}
+
+	profile, err := s.profiles.GetProfile(ctx, string(userID))
+	if errors.Is(err, user.ErrUserNotFound) {
+		return claims, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(scopes, ScopeProfile) {
+		setIfNotEmpty(claims, "name", profile.Name)
+		setIfNotEmpty(claims, "given_name", profile.GivenName)
+		setIfNotEmpty(claims, "family_name", profile.FamilyName)
+		if !profile.UpdatedAt.IsZero() {
+			claims["updated_at"] = profile.UpdatedAt.Unix()
+		}
+	}
+	if slices.Contains(scopes, ScopeEmail) && profile.Email != "" {
+		claims["email"] = profile.Email
+		claims["email_verified"] = profile.EmailVerified
+	}
+	return claims, nil
+}
+
+type EndSessionCommand struct {
+	IDTokenHint           string
+	ClientID              string
+	PostLogoutRedirectURI string
+	State                 string
+}
+
+// EndSession реализует RP-initiated logout: завершает сессию, на которую указывает sid из id_token_hint,
+// и возвращает адрес для перенаправления пользователя (пустой, если клиент его не передал)
+func (s *OAuthService) EndSession(ctx context.Context, cmd EndSessionCommand) (string, error) {
+	if cmd.IDTokenHint == "" {
+		return "", newError(ErrorInvalidRequest, "id_token_hint is required")
+	}
+	claims, err := s.idTokens.Validate(cmd.IDTokenHint)
+	if err != nil || claims.SessionID == "" {
+		return "", newError(ErrorInvalidRequest, "id_token_hint is invalid")
+	}
+	if cmd.ClientID != "" && cmd.ClientID != claims.Audience {
+		return "", newError(ErrorInvalidRequest, "client_id does not match id_token_hint")
+	}
+
+	var redirectURI string
+	if cmd.PostLogoutRedirectURI != "" {
+		client, err := s.clients.GetClient(ctx, claims.Audience)
+		if errors.Is(err, ErrClientNotFound) {
+			return "", newError(ErrorInvalidRequest, "unknown client")
+		}
+		if err != nil {
+			return "", err
+		}
+		if !slices.Contains(client.PostLogoutRedirectURIs, cmd.PostLogoutRedirectURI) {
+			return "", newError(ErrorInvalidRequest, "post_logout_redirect_uri is not registered for client")
+		}
+		redirectURI = cmd.PostLogoutRedirectURI
+	}
+
+	// повторный выход по тому же id_token_hint не ошибка, сессии уже нет
+	if err := s.tokens.EndSession(ctx, claims.Subject, claims.SessionID); err != nil && !errors.Is(err, stateless.ErrSessionNotFound) {
+		return "", err
+	}
+	return redirectURI, nil
+}
+
+func setIfNotEmpty(claims map[string]any, key, value string) {
+	if value != "" {
+		claims[key] = value
+	}
+}
@@ -0,0 +1,87 @@
+package oauth
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/user"
+)
+
+// ClientRepository реестр клиентов. GetClient возвращает ErrClientNotFound, если клиента нет
+type ClientRepository interface {
+	SaveClient(ctx context.Context, client Client) error
+	GetClient(ctx context.Context, clientID string) (Client, error)
+}
+
+// AuthorizationCodeRepository хранит хеши выданных кодов.
+// ConsumeCode удаляет код при чтении и возвращает ErrCodeNotFound, если кода нет
+type AuthorizationCodeRepository interface {
+	SaveCode(ctx context.Context, code AuthorizationCode) error
+	ConsumeCode(ctx context.Context, codeHash string) (AuthorizationCode, error)
+}
+
+type SecretAlgoHelper interface {
+	Generate() (string, error)
+	GetHash(secret string) (string, error)
+	CompareHash(hash, secret string) bool
+}
+
+// TokenIssuer часть StatelessAuthService, через которую выдаются, обновляются и завершаются сессии
+type TokenIssuer interface {
+	IssueTokenPair(ctx context.Context, cmd stateless.IssueTokenPairCommand) (stateless.TokenPair, error)
+	RefreshSession(ctx context.Context, cmd stateless.RefreshSessionCommand) (stateless.TokenPair, error)
+	IssueClientAccessToken(ctx context.Context, cmd stateless.ClientTokenCommand) (stateless.AccessToken, error)
+	EndSession(ctx context.Context, userID stateless.UserID, sessionID stateless.SessionID) error
+	IntrospectAccessToken(ctx context.Context, token stateless.AccessToken) (stateless.ActiveToken, error)
+	IntrospectRefreshToken(ctx context.Context, userID stateless.UserID, token stateless.RefreshToken) (stateless.ActiveToken, error)
+}
+
+// IDTokenAlgoHelper подписывает ID токены асимметричным ключом.
+// Validate проверяет подпись и издателя, но не срок жизни, как требуется для id_token_hint
+type IDTokenAlgoHelper interface {
+	Generate(claims IDTokenClaims) (string, error)
+	Validate(token string) (IDTokenClaims, error)
+}
+
+type ProfileProvider interface {
+	GetProfile(ctx context.Context, id string) (user.Profile, error)
+}
+
+type Config struct {
+	CodeTTL        time.Duration
+	AccessTokenTTL time.Duration
+}
+
+type OAuthService struct {
+	clients    ClientRepository
+	codes      AuthorizationCodeRepository
+	secretAlgs SecretAlgoHelper
+	tokens     TokenIssuer
+	idTokens   IDTokenAlgoHelper
+	profiles   ProfileProvider
+	conf       Config
+	logger     *slog.Logger
+}
+
+func NewOAuthService(
+	clients ClientRepository,
+	codes AuthorizationCodeRepository,
+	secretAlgs SecretAlgoHelper,
+	tokens TokenIssuer,
+	idTokens IDTokenAlgoHelper,
+	profiles ProfileProvider,
+	conf Config,
+	logger *slog.Logger) *OAuthService {
+	return &OAuthService{
+		clients:    clients,
+		codes:      codes,
+		secretAlgs: secretAlgs,
+		tokens:     tokens,
+		idTokens:   idTokens,
+		profiles:   profiles,
+		conf:       conf,
+		logger:     logger,
+	}
+}
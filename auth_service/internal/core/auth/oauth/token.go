@@ -0,0 +1,201 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"medods_test/internal/core/auth/stateless"
+)
+
+type TokenCommand struct {
+	GrantType    string
+	Client       ClientCredentials
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+	UserAgent    string
+	IP           string
+}
+
+// Token реализует эндпойнт /oauth/token для grant authorization_code, refresh_token и client_credentials
+func (s *OAuthService) Token(ctx context.Context, cmd TokenCommand) (TokenResponse, error) {
+	switch cmd.GrantType {
+	case GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials:
+	case "":
+		return TokenResponse{}, newError(ErrorInvalidRequest, "grant_type is required")
+	default:
+		return TokenResponse{}, newError(ErrorUnsupportedGrantType, "")
+	}
+
+	client, err := s.AuthenticateClient(ctx, cmd.Client)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	if !client.AllowsGrant(cmd.GrantType) {
+		return TokenResponse{}, newError(ErrorUnauthorizedClient, "grant type is not allowed for client")
+	}
+
+	switch cmd.GrantType {
+	case GrantAuthorizationCode:
+		return s.exchangeCode(ctx, client, cmd)
+	case GrantRefreshToken:
+		return s.refresh(ctx, client, cmd)
+	default:
+		return s.clientCredentials(ctx, client, cmd)
+	}
+}
+
+func (s *OAuthService) exchangeCode(ctx context.Context, client Client, cmd TokenCommand) (TokenResponse, error) {
+	if cmd.Code == "" || cmd.CodeVerifier == "" {
+		return TokenResponse{}, newError(ErrorInvalidRequest, "code and code_verifier are required")
+	}
+
+	code, err := s.codes.ConsumeCode(ctx, hashCode(cmd.Code))
+	if errors.Is(err, ErrCodeNotFound) {
+		return TokenResponse{}, newError(ErrorInvalidGrant, "authorization code is invalid")
+	}
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	if code.ClientID != client.ID || time.Now().After(code.ExpiresAt) {
+		return TokenResponse{}, newError(ErrorInvalidGrant, "authorization code is invalid")
+	}
+	if (code.RedirectURIProvided || cmd.RedirectURI != "") && cmd.RedirectURI != code.RedirectURI {
+		return TokenResponse{}, newError(ErrorInvalidGrant, "redirect_uri does not match")
+	}
+	if !verifyCodeChallenge(code.CodeChallenge, cmd.CodeVerifier) {
+		return TokenResponse{}, newError(ErrorInvalidGrant, "code_verifier does not match")
+	}
+
+	tokens, err := s.tokens.IssueTokenPair(ctx, stateless.IssueTokenPairCommand{
+		UserID:    code.UserID,
+		UserAgent: cmd.UserAgent,
+		IP:        cmd.IP,
+		ClientID:  client.ID,
+		AuthTime:  code.AuthTime,
+		AMR:       code.AMR,
+		Scopes:    grantedScopes(code.Scopes),
+	})
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	response := s.tokenPairResponse(code.UserID, tokens, code.Scopes)
+	if slices.Contains(code.Scopes, ScopeOpenID) {
+		response.IDToken, err = s.issueIDToken(code, tokens)
+		if err != nil {
+			return TokenResponse{}, err
+		}
+	}
+	return response, nil
+}
+
+func (s *OAuthService) refresh(ctx context.Context, client Client, cmd TokenCommand) (TokenResponse, error) {
+	userID, refreshToken, ok := parseRefreshToken(cmd.RefreshToken)
+	if !ok {
+		return TokenResponse{}, newError(ErrorInvalidGrant, "refresh token is invalid")
+	}
+
+	// scope при обновлении может только сузить права сессии (RFC 6749, раздел 6)
+	var scopes []string
+	if strings.TrimSpace(cmd.Scope) != "" {
+		scopes = strings.Fields(cmd.Scope)
+	}
+
+	tokens, err := s.tokens.RefreshSession(ctx, stateless.RefreshSessionCommand{
+		UserID:       userID,
+		RefreshToken: refreshToken,
+		UserAgent:    cmd.UserAgent,
+		IP:           cmd.IP,
+		ClientID:     client.ID,
+		Scopes:       scopes,
+	})
+	switch {
+	case errors.Is(err, stateless.ErrScopeNotGranted):
+		return TokenResponse{}, newError(ErrorInvalidScope, "requested scope exceeds originally granted scope")
+	case errors.Is(err, stateless.ErrSessionNotFound), errors.Is(err, stateless.ErrSessionClientMismatch):
+		return TokenResponse{}, newError(ErrorInvalidGrant, "refresh token is invalid")
+	case errors.Is(err, stateless.ErrUserAgentChanged):
+		return TokenResponse{}, newError(ErrorInvalidGrant, "user agent changed, session revoked")
+	case err != nil:
+		return TokenResponse{}, err
+	}
+
+	return s.tokenPairResponse(userID, tokens, tokens.Scopes), nil
+}
+
+func (s *OAuthService) clientCredentials(ctx context.Context, client Client, cmd TokenCommand) (TokenResponse, error) {
+	if client.IsPublic() {
+		return TokenResponse{}, newError(ErrorUnauthorizedClient, "public clients can not use client_credentials")
+	}
+	scopes, err := resolveScopes(client, cmd.Scope)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	accessToken, err := s.tokens.IssueClientAccessToken(ctx, stateless.ClientTokenCommand{
+		ClientID: client.ID,
+		Scopes:   grantedScopes(scopes),
+	})
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	return TokenResponse{
+		AccessToken: string(accessToken),
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.conf.AccessTokenTTL.Seconds()),
+		Scopes:      scopes,
+	}, nil
+}
+
+func (s *OAuthService) tokenPairResponse(userID stateless.UserID, tokens stateless.TokenPair, scopes []string) TokenResponse {
+	return TokenResponse{
+		AccessToken:  string(tokens.AccessToken),
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.conf.AccessTokenTTL.Seconds()),
+		RefreshToken: formatRefreshToken(userID, tokens.RefreshToken),
+		Scopes:       scopes,
+	}
+}
+
+// grantedScopes nil в токене означает права роли, поэтому клиенту без scope выдаётся пустой набор
+func grantedScopes(scopes []string) []string {
+	if scopes == nil {
+		return []string{}
+	}
+	return scopes
+}
+
+// Сессии ищутся по пользователю, поэтому в OAuth refresh токен вида "<user_id>.<refresh_token>"
+// заменяет пару access + refresh, которую принимает /auth/refresh
+func formatRefreshToken(userID stateless.UserID, token stateless.RefreshToken) string {
+	return string(userID) + "." + string(token)
+}
+
+func parseRefreshToken(token string) (stateless.UserID, stateless.RefreshToken, bool) {
+	userID, refresh, ok := strings.Cut(token, ".")
+	if !ok || userID == "" || refresh == "" || strings.Contains(refresh, ".") {
+		return "", "", false
+	}
+	return stateless.UserID(userID), stateless.RefreshToken(refresh), true
+}
+
+// verifyCodeChallenge проверка PKCE по RFC 7636 для метода S256
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
@@ -0,0 +1,225 @@
+package oauth_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"medods_test/internal/core/auth/oauth"
+	"medods_test/internal/core/auth/oauth/oauthtest"
+	"medods_test/internal/core/auth/stateless"
+)
+
+const (
+	testUserID   = "123e4567-e89b-12d3-a456-426614174000"
+	testRedirect = "https://client.example.com/callback"
+	testVerifier = "dBjftJeZ4CVP-mJ92K9xNS9mS0sNpOIuLuS2wXcxU2fEOkNYxDmN"
+)
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// registerClient регистрирует клиента со всеми grant и возвращает его учётные данные
+func registerClient(t *testing.T, svc *oauthtest.Service, id string, public bool) oauth.ClientCredentials {
+	t.Helper()
+	secret, err := svc.RegisterClient(context.Background(), oauth.RegisterClientCommand{
+		ID:            id,
+		Name:          id,
+		Public:        public,
+		RedirectURIs:  []string{testRedirect, testRedirect + "/other"},
+		AllowedScopes: []string{oauth.ScopeOpenID, stateless.ScopeProfileRead, stateless.ScopeMFAManage},
+		GrantTypes:    []string{oauth.GrantAuthorizationCode, oauth.GrantRefreshToken, oauth.GrantClientCredentials},
+	})
+	if err != nil {
+		t.Fatalf("RegisterClient: %v", err)
+	}
+	return oauth.ClientCredentials{ID: id, Secret: secret}
+}
+
+func authorize(svc *oauthtest.Service, clientID, scope string, callerScopes []string) (oauth.AuthorizeResult, error) {
+	return svc.Authorize(context.Background(), oauth.AuthorizeCommand{
+		UserID:              testUserID,
+		AuthTime:            time.Now(),
+		AMR:                 []string{stateless.AMRPassword},
+		CallerScopes:        callerScopes,
+		ResponseType:        "code",
+		ClientID:            clientID,
+		RedirectURI:         testRedirect,
+		Scope:               scope,
+		CodeChallenge:       challenge(testVerifier),
+		CodeChallengeMethod: "S256",
+	})
+}
+
+func issueCode(t *testing.T, svc *oauthtest.Service, clientID string) string {
+	t.Helper()
+	result, err := authorize(svc, clientID, "", stateless.PermissionsForRole(""))
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	return result.Code
+}
+
+func exchange(svc *oauthtest.Service, client oauth.ClientCredentials, code, redirectURI, verifier string) (oauth.TokenResponse, error) {
+	return svc.Token(context.Background(), oauth.TokenCommand{
+		GrantType:    oauth.GrantAuthorizationCode,
+		Client:       client,
+		Code:         code,
+		RedirectURI:  redirectURI,
+		CodeVerifier: verifier,
+		UserAgent:    "test",
+		IP:           "10.0.0.1",
+	})
+}
+
+func assertOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) || oauthErr.Code != code {
+		t.Fatalf("err = %v, want %s", err, code)
+	}
+}
+
+func TestTokenAuthorizationCodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		codeTTL time.Duration
+		token   func(svc *oauthtest.Service, client oauth.ClientCredentials, code string) error
+	}{
+		{
+			name: "wrong code_verifier",
+			token: func(svc *oauthtest.Service, client oauth.ClientCredentials, code string) error {
+				_, err := exchange(svc, client, code, testRedirect, strings.Repeat("x", 43))
+				return err
+			},
+		},
+		{
+			name: "reused code",
+			token: func(svc *oauthtest.Service, client oauth.ClientCredentials, code string) error {
+				if _, err := exchange(svc, client, code, testRedirect, testVerifier); err != nil {
+					t.Fatalf("first exchange: %v", err)
+				}
+				_, err := exchange(svc, client, code, testRedirect, testVerifier)
+				return err
+			},
+		},
+		{
+			name: "mismatched redirect_uri",
+			token: func(svc *oauthtest.Service, client oauth.ClientCredentials, code string) error {
+				_, err := exchange(svc, client, code, testRedirect+"/other", testVerifier)
+				return err
+			},
+		},
+		{
+			name:    "expired code",
+			codeTTL: -time.Second,
+			token: func(svc *oauthtest.Service, client oauth.ClientCredentials, code string) error {
+				_, err := exchange(svc, client, code, testRedirect, testVerifier)
+				return err
+			},
+		},
+		{
+			name: "code of another client",
+			token: func(svc *oauthtest.Service, _ oauth.ClientCredentials, code string) error {
+				other := registerClient(t, svc, "other", false)
+				_, err := exchange(svc, other, code, testRedirect, testVerifier)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := oauthtest.NewService(t, oauthtest.Options{CodeTTL: tt.codeTTL})
+			client := registerClient(t, svc, "web", false)
+			code := issueCode(t, svc, client.ID)
+
+			assertOAuthError(t, tt.token(svc, client, code), oauth.ErrorInvalidGrant)
+		})
+	}
+}
+
+func TestTokenExchangesCode(t *testing.T) {
+	svc := oauthtest.NewService(t, oauthtest.Options{})
+	client := registerClient(t, svc, "web", false)
+
+	response, err := exchange(svc, client, issueCode(t, svc, client.ID), testRedirect, testVerifier)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if response.AccessToken == "" || response.RefreshToken == "" || response.IDToken == "" {
+		t.Fatalf("response = %+v, want access, refresh and id tokens", response)
+	}
+}
+
+func TestTokenClientCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		public  bool
+		wantErr string
+	}{
+		{name: "confidential client"},
+		{name: "public client", public: true, wantErr: oauth.ErrorUnauthorizedClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := oauthtest.NewService(t, oauthtest.Options{})
+			client := registerClient(t, svc, "service", tt.public)
+
+			response, err := svc.Token(context.Background(), oauth.TokenCommand{
+				GrantType: oauth.GrantClientCredentials,
+				Client:    client,
+				Scope:     stateless.ScopeProfileRead,
+			})
+			if tt.wantErr != "" {
+				assertOAuthError(t, err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Token: %v", err)
+			}
+			if response.AccessToken == "" || response.RefreshToken != "" {
+				t.Fatalf("response = %+v, want access token only", response)
+			}
+		})
+	}
+}
+
+func TestAuthorizeLimitsScopesToCaller(t *testing.T) {
+	// токен, урезанный до одного oauth:authorize
+	callerScopes := []string{stateless.ScopeOAuthAuthorize}
+
+	t.Run("requested scope beyond caller is rejected", func(t *testing.T) {
+		svc := oauthtest.NewService(t, oauthtest.Options{})
+		client := registerClient(t, svc, "web", false)
+
+		result, err := authorize(svc, client.ID, oauth.ScopeOpenID+" "+stateless.ScopeProfileRead, callerScopes)
+		assertOAuthError(t, err, oauth.ErrorInvalidScope)
+		if result.RedirectURI != testRedirect {
+			t.Fatalf("RedirectURI = %q, want error redirected to client", result.RedirectURI)
+		}
+	})
+
+	t.Run("default scopes are narrowed", func(t *testing.T) {
+		svc := oauthtest.NewService(t, oauthtest.Options{})
+		client := registerClient(t, svc, "web", false)
+
+		result, err := authorize(svc, client.ID, "", callerScopes)
+		if err != nil {
+			t.Fatalf("Authorize: %v", err)
+		}
+		response, err := exchange(svc, client, result.Code, testRedirect, testVerifier)
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		if !slices.Equal(response.Scopes, []string{oauth.ScopeOpenID}) {
+			t.Fatalf("scopes = %v, want only %s", response.Scopes, oauth.ScopeOpenID)
+		}
+	})
+}
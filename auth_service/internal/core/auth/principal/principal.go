@@ -0,0 +1,65 @@
+package principal
+
+import (
+	"context"
+	"medods_test/internal/core/auth/stateless"
+)
+
+type AuthMethod string
+
+const (
+	AuthMethodJWT    AuthMethod = "jwt"
+	AuthMethodAPIKey AuthMethod = "api_key"
+)
+
+// Principal тот, от чьего имени выполняется запрос: пользователь, OAuth клиент или API ключ
+type Principal struct {
+	// UserID пустой у токенов client_credentials и API ключей
+	UserID      stateless.UserID
+	TokenPairID stateless.TokenPairID
+	ClientID    string
+	APIKeyID    string
+	Role        stateless.UserRole
+	Scopes      []string
+	AuthMethod  AuthMethod
+	// Token разобранный access токен, пустой для API ключей. Нужен step-up и выдаче токенов на его основе
+	Token stateless.AccessTokenPayload
+}
+
+// IsUser запрос выполняется от имени пользователя
+func (p Principal) IsUser() bool {
+	return p.UserID != ""
+}
+
+// Subject идентификатор для логов и поля created_by
+func (p Principal) Subject() string {
+	switch {
+	case p.UserID != "":
+		return string(p.UserID)
+	case p.APIKeyID != "":
+		return "api_key:" + p.APIKeyID
+	default:
+		return "client:" + p.ClientID
+	}
+}
+
+type contextKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext возвращает false, если запрос не прошёл через middleware аутентификации
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
+
+// UserFromContext как FromContext, но только для запросов от имени пользователя
+func UserFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := FromContext(ctx)
+	if !ok || !p.IsUser() {
+		return Principal{}, false
+	}
+	return p, true
+}
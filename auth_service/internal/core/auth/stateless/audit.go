@@ -0,0 +1,57 @@
+package stateless
+
+import (
+	"context"
+	"errors"
+	"medods_test/internal/core/audit"
+	"strings"
+)
+
+type loginAudit struct {
+	Actor     string
+	Subject   UserID
+	UserAgent string
+	IP        string
+	AMR       []string
+}
+
+// recordLogin пишет в журнал результат входа: успех, ожидание второго фактора или ошибку
+func (s *StatelessAuthService) recordLogin(ctx context.Context, login loginAudit, err error) {
+	event := audit.Event{
+		Action:    audit.ActionLogin,
+		Actor:     login.Actor,
+		Subject:   string(login.Subject),
+		IP:        login.IP,
+		UserAgent: login.UserAgent,
+		Outcome:   audit.OutcomeSuccess,
+		Reason:    "amr=" + strings.Join(login.AMR, ","),
+	}
+	if event.Actor == "" {
+		event.Actor = event.Subject
+	}
+	switch {
+	case errors.Is(err, ErrMFARequired):
+		event.Outcome = audit.OutcomePending
+		event.Reason = err.Error()
+	case err != nil:
+		event.Outcome = audit.OutcomeFailure
+		event.Reason = err.Error()
+	}
+	s.audit.Record(ctx, event)
+}
+
+// recordSessionEvent пишет в журнал событие жизненного цикла сессии, ошибка становится причиной неудачи
+func (s *StatelessAuthService) recordSessionEvent(ctx context.Context, event audit.Event, err error) {
+	if event.Actor == "" {
+		event.Actor = event.Subject
+	}
+	event.Outcome = audit.OutcomeSuccess
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+		if event.Reason != "" {
+			event.Reason += ": "
+		}
+		event.Reason += err.Error()
+	}
+	s.audit.Record(ctx, event)
+}
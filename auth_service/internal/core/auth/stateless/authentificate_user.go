@@ -2,6 +2,8 @@ package stateless
 
 import (
 	"context"
+	"errors"
+	"time"
 )
 
 type UserID string
@@ -14,8 +16,110 @@ type TestAuthCommand struct {
 	IP        string
 }
 
-func (s *StatelessAuthService) TestAuthenticateUser(ctx context.Context, cmd TestAuthCommand) (TokenPair, error) {
-	userID := cmd.UserId
+// TestAuthenticateUser выдаёт пару токенов по user_id.
+// Если у пользователя подключён TOTP, возвращается *MFARequiredError с промежуточным токеном
+func (s *StatelessAuthService) TestAuthenticateUser(ctx context.Context, cmd TestAuthCommand) (tokens TokenPair, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.TestAuthenticateUser")
+	defer func() { span.End(err) }()
+
+	return s.authenticateFirstFactor(ctx, cmd.UserId, cmd.UserAgent, cmd.IP, AMRPassword)
+}
+
+type ExternalAuthCommand struct {
+	UserID    UserID
+	UserAgent string
+	IP        string
+}
+
+// AuthenticateExternalUser выдаёт пару токенов пользователю, вошедшему через внешний OIDC провайдер.
+// Внешний вход считается первым фактором, поэтому подключённый TOTP так же требуется
+func (s *StatelessAuthService) AuthenticateExternalUser(ctx context.Context, cmd ExternalAuthCommand) (tokens TokenPair, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.AuthenticateExternalUser")
+	defer func() { span.End(err) }()
+
+	return s.authenticateFirstFactor(ctx, cmd.UserID, cmd.UserAgent, cmd.IP, AMRFederated)
+}
+
+func (s *StatelessAuthService) authenticateFirstFactor(ctx context.Context, userID UserID, userAgent, ip, amr string) (_ TokenPair, err error) {
+	defer func() {
+		s.recordLogin(ctx, loginAudit{Subject: userID, UserAgent: userAgent, IP: ip, AMR: []string{amr}}, err)
+	}()
+
+	mfaEnabled, err := s.isMFAEnabled(ctx, userID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	if mfaEnabled {
+		pendingToken, err := s.mfaPendingTokenAlgs.Generate(MFAPendingPayload{
+			UserID:    userID,
+			UserAgent: userAgent,
+		})
+		if err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, &MFARequiredError{PendingToken: pendingToken}
+	}
+
+	return s.issueTokenPair(ctx, issueTokenPairParams{
+		UserID:    userID,
+		UserAgent: userAgent,
+		IP:        ip,
+		AMR:       []string{amr},
+	})
+}
+
+type IssueTokenPairCommand struct {
+	UserID    UserID
+	UserAgent string
+	IP        string
+	ClientID  string
+	// AuthTime и AMR переносятся из исходного входа пользователя, нулевой AuthTime означает текущий момент
+	AuthTime time.Time
+	AMR      []string
+	// Scopes nil означает права роли пользователя
+	Scopes []string
+}
+
+// IssueTokenPair выдаёт пару токенов пользователю, который уже прошёл аутентификацию другим способом
+// (например, OAuth клиенту по коду авторизации)
+func (s *StatelessAuthService) IssueTokenPair(ctx context.Context, cmd IssueTokenPairCommand) (tokens TokenPair, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.IssueTokenPair")
+	defer func() { span.End(err) }()
+	defer func() {
+		s.recordLogin(ctx, loginAudit{
+			Actor:     "client:" + cmd.ClientID,
+			Subject:   cmd.UserID,
+			UserAgent: cmd.UserAgent,
+			IP:        cmd.IP,
+			AMR:       cmd.AMR,
+		}, err)
+	}()
+
+	return s.issueTokenPair(ctx, issueTokenPairParams{
+		UserID:    cmd.UserID,
+		UserAgent: cmd.UserAgent,
+		IP:        cmd.IP,
+		ClientID:  cmd.ClientID,
+		AuthTime:  cmd.AuthTime,
+		AMR:       cmd.AMR,
+		Scopes:    cmd.Scopes,
+	})
+}
+
+type issueTokenPairParams struct {
+	UserID        UserID
+	UserAgent     string
+	IP            string
+	ClientID      string
+	MFAVerifiedAt time.Time
+	AuthTime      time.Time
+	AMR           []string
+	Scopes        []string
+}
+
+// issueTokenPair создаёт новую сессию и выдаёт для неё пару токенов без каких-либо проверок
+func (s *StatelessAuthService) issueTokenPair(ctx context.Context, params issueTokenPairParams) (TokenPair, error) {
 	str, err := s.tokenPairIDGenerator.Generate()
 	tokenPairID := TokenPairID(str)
 
@@ -23,10 +127,29 @@ func (s *StatelessAuthService) TestAuthenticateUser(ctx context.Context, cmd Tes
 		return TokenPair{}, err
 	}
 
+	sessionID, err := s.tokenPairIDGenerator.Generate()
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	authTime := params.AuthTime
+	if authTime.IsZero() {
+		authTime = time.Now()
+	}
+	scopes := params.Scopes
+	if scopes == nil {
+		scopes = PermissionsForRole(RoleUser)
+	}
+
 	accessTokenPayload := AccessTokenPayload{
-		UserID:      userID,
-		TokenPairID: tokenPairID,
-		Role:        UserRole("user"),
+		UserID:        params.UserID,
+		TokenPairID:   tokenPairID,
+		Role:          RoleUser,
+		ClientID:      params.ClientID,
+		MFAVerifiedAt: params.MFAVerifiedAt,
+		AuthTime:      authTime,
+		AMR:           params.AMR,
+		Scopes:        scopes,
 	}
 
 	accessToken, err := s.accessTokenAlgs.Generate(accessTokenPayload)
@@ -40,25 +163,51 @@ func (s *StatelessAuthService) TestAuthenticateUser(ctx context.Context, cmd Tes
 		return TokenPair{}, err
 	}
 
-	refreshTokenHash, err := s.refreshTokenAlgs.GetHash(string(refreshToken))
+	refreshTokenHash, err := s.hashRefreshToken(ctx, string(refreshToken))
 	if err != nil {
 		return TokenPair{}, err
 	}
 
 	sessionData := SessionData{
-		UserID:      userID,
+		UserID:      params.UserID,
+		SessionID:   SessionID(sessionID),
 		TokenPairID: accessTokenPayload.TokenPairID,
 		RefreshHash: refreshTokenHash,
-		UserAgent:   cmd.UserAgent,
-		IP:          cmd.IP,
+		UserAgent:   params.UserAgent,
+		IP:          params.IP,
+		ClientID:    params.ClientID,
+		AuthTime:    authTime,
+		AMR:         params.AMR,
+		Scopes:      scopes,
 	}
 	err = s.authRepo.SaveSession(ctx, sessionData)
 	if err != nil {
 		return TokenPair{}, err
 	}
+	s.metrics.TokenIssued(TokenKindSession)
+	s.events.Publish(ctx, SessionCreated{
+		EventMeta: s.newEventMeta(ctx, params.UserID, sessionData.SessionID, sessionData.TokenPairID),
+		ClientID:  params.ClientID,
+		UserAgent: params.UserAgent,
+		IP:        params.IP,
+		AMR:       params.AMR,
+	})
 
 	return TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		SessionID:    sessionData.SessionID,
+		Scopes:       scopes,
 	}, nil
 }
+
+func (s *StatelessAuthService) isMFAEnabled(ctx context.Context, userID UserID) (bool, error) {
+	enrollment, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if errors.Is(err, ErrMFANotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return enrollment.Confirmed, nil
+}
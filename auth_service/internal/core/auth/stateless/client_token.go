@@ -0,0 +1,34 @@
+package stateless
+
+import (
+	"context"
+)
+
+type ClientTokenCommand struct {
+	ClientID string
+	Scopes   []string
+}
+
+// IssueClientAccessToken выдаёт access токен самому OAuth клиенту (grant client_credentials).
+// Такой токен не привязан к пользователю и сессии, refresh токен для него не выдаётся
+func (s *StatelessAuthService) IssueClientAccessToken(ctx context.Context, cmd ClientTokenCommand) (token AccessToken, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.IssueClientAccessToken")
+	defer func() { span.End(err) }()
+
+	tokenID, err := s.tokenPairIDGenerator.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	token, err = s.accessTokenAlgs.Generate(AccessTokenPayload{
+		TokenPairID: TokenPairID(tokenID),
+		Role:        RoleClient,
+		ClientID:    cmd.ClientID,
+		Scopes:      cmd.Scopes,
+	})
+	if err != nil {
+		return "", err
+	}
+	s.metrics.TokenIssued(TokenKindClientCredentials)
+	return token, nil
+}
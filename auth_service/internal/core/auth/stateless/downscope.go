@@ -0,0 +1,44 @@
+package stateless
+
+import (
+	"context"
+)
+
+type DownscopeCommand struct {
+	Payload   AccessTokenPayload
+	Scopes    []string
+	UserAgent string
+	IP        string
+}
+
+// DownscopeTokenPair выдаёт отдельную сессию с частью прав текущего токена для интеграций,
+// которым не нужен полный доступ. Исходная сессия не меняется, но должна существовать: access токен
+// завершённой сессии живёт до истечения и не должен порождать новых. Клиент и вход (auth_time, amr)
+// берутся из сохранённой сессии
+func (s *StatelessAuthService) DownscopeTokenPair(ctx context.Context, cmd DownscopeCommand) (tokens TokenPair, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.DownscopeTokenPair")
+	defer func() { span.End(err) }()
+
+	if !HasScopes(cmd.Payload.GrantedScopes(), cmd.Scopes...) {
+		return TokenPair{}, ErrScopeNotGranted
+	}
+	session, err := s.authRepo.GetSessionByTokenPairID(ctx, cmd.Payload.UserID, cmd.Payload.TokenPairID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	scopes := cmd.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return s.issueTokenPair(ctx, issueTokenPairParams{
+		UserID:    cmd.Payload.UserID,
+		UserAgent: cmd.UserAgent,
+		IP:        cmd.IP,
+		ClientID:  session.ClientID,
+		AuthTime:  session.AuthTime,
+		AMR:       session.AMR,
+		Scopes:    scopes,
+	})
+}
@@ -0,0 +1,42 @@
+package stateless_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/auth/stateless/statelesstest"
+)
+
+func downscope(t *testing.T, svc *statelesstest.Service, tokens stateless.TokenPair) (stateless.TokenPair, error) {
+	t.Helper()
+	payload, err := svc.AccessTokens.Validate(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	return svc.DownscopeTokenPair(context.Background(), stateless.DownscopeCommand{
+		Payload:   payload,
+		Scopes:    payload.GrantedScopes()[:1],
+		UserAgent: "test",
+		IP:        "127.0.0.1",
+	})
+}
+
+func TestDownscopeRequiresLiveSession(t *testing.T) {
+	svc := statelesstest.NewService(t, statelesstest.Options{})
+	tokens := login(t, svc)
+
+	if _, err := downscope(t, svc, tokens); err != nil {
+		t.Fatalf("downscope of a live session: %v", err)
+	}
+
+	err := svc.Logout(context.Background(), stateless.LogoutCommand{RefreshToken: tokens.RefreshToken, UserID: testUserID})
+	if err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	// access токен ещё не истёк, но его сессия завершена
+	if _, err := downscope(t, svc, tokens); !errors.Is(err, stateless.ErrSessionNotFound) {
+		t.Fatalf("downscope after logout: err = %v, want ErrSessionNotFound", err)
+	}
+}
@@ -1,26 +1,113 @@
 package stateless
 
+import (
+	"time"
+)
+
 type AccessToken string
 
 type RefreshToken string
 
 type TokenPairID string
 
+type MFAPendingToken string
+
+type SessionID string
+
+// Значения amr (RFC 8176), которыми помечаются способы входа
+const (
+	// AMRPassword первый фактор, пока это заглушка TestAuthenticateUser
+	AMRPassword    = "pwd"
+	AMROTP         = "otp"
+	AMRMultiFactor = "mfa"
+	AMRHardwareKey = "hwk"
+	AMRUser        = "user"
+	// AMRFederated вход через внешний OIDC провайдер, в RFC 8176 отдельного значения нет
+	AMRFederated = "fed"
+)
+
 type AccessTokenPayload struct {
 	UserID      UserID
 	TokenPairID TokenPairID
 	Role        UserRole
+	// ClientID OAuth клиент, которому выдан токен. Пустой для токенов, выданных через /auth/*
+	ClientID string
+	// MFAVerifiedAt время последнего подтверждения второго фактора, нулевое если его не было
+	MFAVerifiedAt time.Time
+	// AuthTime время входа пользователя, не меняется при обновлении пары
+	AuthTime time.Time
+	AMR      []string
+	// Scopes nil у токенов, выпущенных до появления scope, см. GrantedScopes
+	Scopes []string
+	// ExpiresAt заполняется при разборе токена, при выпуске срок жизни задаёт AccessTokenAlgoHelper
+	ExpiresAt time.Time
 }
 
 type SessionData struct {
-	UserID      UserID
+	UserID UserID
+	// SessionID постоянный идентификатор сессии, в отличие от TokenPairID не меняется при обновлении пары
+	SessionID   SessionID
 	TokenPairID TokenPairID
 	RefreshHash string
 	UserAgent   string
 	IP          string
+	ClientID    string
+	AuthTime    time.Time
+	AMR         []string
+	Scopes      []string
 }
 
 type TokenPair struct {
 	AccessToken  AccessToken
 	RefreshToken RefreshToken
+	// SessionID и Scopes не отдаются клиенту, нужны для sid в ID токене OIDC и ответа OAuth
+	SessionID SessionID `json:"-"`
+	Scopes    []string  `json:"-"`
+}
+
+// ActiveToken сведения об активном токене для introspection и revocation.
+// Для токенов client_credentials UserID и SessionID пустые
+type ActiveToken struct {
+	UserID      UserID
+	SessionID   SessionID
+	TokenPairID TokenPairID
+	ClientID    string
+	Scopes      []string
+	// ExpiresAt нулевой для refresh токенов, у них нет срока жизни
+	ExpiresAt time.Time
+}
+
+// MFAPendingPayload содержимое промежуточного токена, выдаваемого после первого фактора
+type MFAPendingPayload struct {
+	UserID    UserID
+	UserAgent string
+}
+
+type TOTPEnrollment struct {
+	UserID         UserID
+	Secret         string
+	Confirmed      bool
+	LastUsedStep   int64
+	FailedAttempts int
+	LockedUntil    time.Time
+}
+
+type TOTPEnrollmentResult struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// PasskeyCredential публичный ключ WebAuthn, привязанный к пользователю
+type PasskeyCredential struct {
+	ID              []byte
+	UserID          UserID
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+	Transports      []string
+	// Flags байт флагов аутентификатора (UP, UV, BE, BS) на момент последнего использования
+	Flags         uint8
+	Attachment    string
+	CloneDetected bool
 }
@@ -5,7 +5,33 @@ import (
 )
 
 var (
-	ErrUserAgentChanged = errors.New("user agent changed")
-	ErrAccessTokenExpired     = errors.New("access token expired")
-	ErrAccessTokenInvalid	  = errors.New("access token invalid")
-)
\ No newline at end of file
+	ErrUserAgentChanged        = errors.New("user agent changed")
+	ErrAccessTokenExpired      = errors.New("access token expired")
+	ErrAccessTokenInvalid      = errors.New("access token invalid")
+	ErrSessionNotFound         = errors.New("session not found")
+	ErrSessionClientMismatch   = errors.New("session belongs to another client")
+	ErrMFARequired             = errors.New("mfa required")
+	ErrMFANotEnrolled          = errors.New("mfa not enrolled")
+	ErrMFAAlreadyEnrolled      = errors.New("mfa already enrolled")
+	ErrMFAInvalidCode          = errors.New("mfa code invalid")
+	ErrMFALocked               = errors.New("mfa temporarily locked")
+	ErrMFAPendingTokenInvalid  = errors.New("mfa pending token invalid")
+	ErrPasskeyNotFound         = errors.New("passkey not found")
+	ErrPasskeyCloned           = errors.New("passkey clone detected")
+	ErrPasskeyCeremonyNotFound = errors.New("passkey ceremony not found or expired")
+	ErrScopeNotGranted         = errors.New("requested scope exceeds granted scope")
+)
+
+// MFARequiredError возвращается при входе пользователя с включённым вторым фактором,
+// вместо пары токенов клиент получает промежуточный токен для /auth/mfa/verify
+type MFARequiredError struct {
+	PendingToken MFAPendingToken
+}
+
+func (e *MFARequiredError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *MFARequiredError) Is(target error) bool {
+	return target == ErrMFARequired
+}
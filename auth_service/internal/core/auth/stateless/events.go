@@ -1,7 +1,116 @@
 package stateless
 
+import (
+	"context"
+	"medods_test/pkg/requestid"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventSessionCreated    EventType = "session.created"
+	EventSessionRefreshed  EventType = "session.refreshed"
+	EventSessionRevoked    EventType = "session.revoked"
+	EventUserAgentMismatch EventType = "session.user_agent_mismatch"
+	EventLogoutPerformed   EventType = "session.logout"
+	EventUserIPChanged     EventType = "session.ip_changed"
+)
+
+// Event доменное событие жизненного цикла сессии
+type Event interface {
+	EventType() EventType
+	Metadata() EventMeta
+}
+
+// EventMeta общие поля всех событий. CorrelationID — идентификатор запроса, в котором произошло событие
+type EventMeta struct {
+	ID            string
+	OccurredAt    time.Time
+	CorrelationID string
+	UserID        UserID
+	SessionID     SessionID
+	TokenPairID   TokenPairID
+}
+
+func (m EventMeta) Metadata() EventMeta {
+	return m
+}
+
+type SessionCreated struct {
+	EventMeta
+	ClientID  string
+	UserAgent string
+	IP        string
+	AMR       []string
+}
+
+func (SessionCreated) EventType() EventType { return EventSessionCreated }
+
+// SessionRefreshed TokenPairID в метаданных — новая пара, PreviousTokenPairID — отозванная ротацией
+type SessionRefreshed struct {
+	EventMeta
+	PreviousTokenPairID TokenPairID
+	ClientID            string
+	UserAgent           string
+	IP                  string
+}
+
+func (SessionRefreshed) EventType() EventType { return EventSessionRefreshed }
+
+type RevokeReason string
+
+const (
+	RevokeReasonLogout            RevokeReason = "logout"
+	RevokeReasonEndSession        RevokeReason = "end_session"
+	RevokeReasonUserAgentMismatch RevokeReason = "user_agent_mismatch"
+)
+
+// SessionRevoked сессия завершена по любой причине, кроме ротации при refresh
+type SessionRevoked struct {
+	EventMeta
+	Reason RevokeReason
+}
+
+func (SessionRevoked) EventType() EventType { return EventSessionRevoked }
+
+type UserAgentMismatch struct {
+	EventMeta
+	ExpectedUserAgent string
+	ActualUserAgent   string
+	IP                string
+}
+
+func (UserAgentMismatch) EventType() EventType { return EventUserAgentMismatch }
+
+type LogoutPerformed struct {
+	EventMeta
+	UserAgent string
+	IP        string
+}
+
+func (LogoutPerformed) EventType() EventType { return EventLogoutPerformed }
+
 type UserIPChangedEvent struct {
-	UserID UserID
-	OldIP  string
-	NewIP  string
+	EventMeta
+	OldIP string
+	NewIP string
+}
+
+func (UserIPChangedEvent) EventType() EventType { return EventUserIPChanged }
+
+// newEventMeta ошибка генерации ID не должна ломать операцию, событие тогда уходит без ID
+func (s *StatelessAuthService) newEventMeta(ctx context.Context, userID UserID, sessionID SessionID, tokenPairID TokenPairID) EventMeta {
+	id, err := s.tokenPairIDGenerator.Generate()
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to generate event id", "error", err)
+	}
+	return EventMeta{
+		ID:            id,
+		OccurredAt:    time.Now().UTC(),
+		CorrelationID: requestid.FromContext(ctx),
+		UserID:        userID,
+		SessionID:     sessionID,
+		TokenPairID:   tokenPairID,
+	}
 }
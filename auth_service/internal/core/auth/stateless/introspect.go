@@ -0,0 +1,53 @@
+package stateless
+
+import (
+	"context"
+)
+
+// IntrospectAccessToken проверяет подпись и срок жизни access токена и то, что его сессия не завершена.
+// Токены client_credentials не привязаны к сессии и активны до истечения срока
+func (s *StatelessAuthService) IntrospectAccessToken(ctx context.Context, token AccessToken) (_ ActiveToken, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.IntrospectAccessToken")
+	defer func() { span.End(err) }()
+
+	payload, err := s.accessTokenAlgs.Validate(token)
+	if err != nil {
+		return ActiveToken{}, err
+	}
+
+	active := ActiveToken{
+		UserID:      payload.UserID,
+		TokenPairID: payload.TokenPairID,
+		ClientID:    payload.ClientID,
+		Scopes:      payload.GrantedScopes(),
+		ExpiresAt:   payload.ExpiresAt,
+	}
+	if payload.UserID == "" {
+		return active, nil
+	}
+
+	session, err := s.authRepo.GetSessionByTokenPairID(ctx, payload.UserID, payload.TokenPairID)
+	if err != nil {
+		return ActiveToken{}, err
+	}
+	active.SessionID = session.SessionID
+	return active, nil
+}
+
+// IntrospectRefreshToken ищет сессию по refresh токену, возвращает ErrSessionNotFound, если её нет
+func (s *StatelessAuthService) IntrospectRefreshToken(ctx context.Context, userID UserID, token RefreshToken) (_ ActiveToken, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.IntrospectRefreshToken")
+	defer func() { span.End(err) }()
+
+	session, err := s.authRepo.GetSession(ctx, userID, string(token))
+	if err != nil {
+		return ActiveToken{}, err
+	}
+	return ActiveToken{
+		UserID:      session.UserID,
+		SessionID:   session.SessionID,
+		TokenPairID: session.TokenPairID,
+		ClientID:    session.ClientID,
+		Scopes:      session.Scopes,
+	}, nil
+}
@@ -2,18 +2,71 @@ package stateless
 
 import (
 	"context"
+	"medods_test/internal/core/audit"
 )
 
-func (s *StatelessAuthService) Logout(ctx context.Context, RefreshToken RefreshToken, userId UserID) error {
-	refreshHash, err := s.refreshTokenAlgs.GetHash(string(RefreshToken))
+type LogoutCommand struct {
+	RefreshToken RefreshToken
+	UserID       UserID
+	// TokenPairID пары из access токена, которым выполнен выход, попадает в события
+	TokenPairID TokenPairID
+	UserAgent   string
+	IP          string
+}
+
+func (s *StatelessAuthService) Logout(ctx context.Context, cmd LogoutCommand) (err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.Logout")
+	defer func() { span.End(err) }()
+	// успех пишется в журнал, только если строка сессии действительно удалена:
+	// DeleteSessionByID вернёт ErrSessionNotFound, если её уже удалил параллельный запрос
+	defer func() {
+		s.recordSessionEvent(ctx, audit.Event{
+			Action:    audit.ActionLogout,
+			Subject:   string(cmd.UserID),
+			IP:        cmd.IP,
+			UserAgent: cmd.UserAgent,
+		}, err)
+	}()
+
+	session, err := s.authRepo.GetSession(ctx, cmd.UserID, string(cmd.RefreshToken))
 	if err != nil {
 		return err
 	}
-
-	err = s.authRepo.DeleteSession(ctx, userId, refreshHash)
-	if err != nil {
+	if err := s.authRepo.DeleteSessionByID(ctx, cmd.UserID, session.SessionID); err != nil {
 		return err
 	}
+	s.metrics.SessionEnded(SessionEndLogout)
+	s.events.Publish(ctx, LogoutPerformed{
+		EventMeta: s.newEventMeta(ctx, cmd.UserID, session.SessionID, cmd.TokenPairID),
+		UserAgent: cmd.UserAgent,
+		IP:        cmd.IP,
+	})
+	s.events.Publish(ctx, SessionRevoked{
+		EventMeta: s.newEventMeta(ctx, cmd.UserID, session.SessionID, cmd.TokenPairID),
+		Reason:    RevokeReasonLogout,
+	})
+	return nil
+}
+
+// EndSession завершает сессию по её постоянному идентификатору (RP-initiated logout)
+func (s *StatelessAuthService) EndSession(ctx context.Context, userID UserID, sessionID SessionID) (err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.EndSession")
+	defer func() { span.End(err) }()
+	defer func() {
+		s.recordSessionEvent(ctx, audit.Event{
+			Action:  audit.ActionLogout,
+			Subject: string(userID),
+			Reason:  "end_session " + string(sessionID),
+		}, err)
+	}()
 
+	if err := s.authRepo.DeleteSessionByID(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	s.metrics.SessionEnded(SessionEndByID)
+	s.events.Publish(ctx, SessionRevoked{
+		EventMeta: s.newEventMeta(ctx, userID, sessionID, ""),
+		Reason:    RevokeReasonEndSession,
+	})
 	return nil
 }
@@ -0,0 +1,85 @@
+package stateless_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"medods_test/internal/core/audit"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/auth/stateless/statelesstest"
+)
+
+const testUserID = stateless.UserID("123e4567-e89b-12d3-a456-426614174000")
+
+func login(t *testing.T, svc *statelesstest.Service) stateless.TokenPair {
+	t.Helper()
+	tokens, err := svc.TestAuthenticateUser(context.Background(), stateless.TestAuthCommand{UserId: testUserID, UserAgent: "test", IP: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("TestAuthenticateUser: %v", err)
+	}
+	return tokens
+}
+
+func logoutOutcomes(svc *statelesstest.Service) []string {
+	var outcomes []string
+	for _, event := range svc.Audit.Events() {
+		if event.Action == audit.ActionLogout {
+			outcomes = append(outcomes, event.Outcome)
+		}
+	}
+	return outcomes
+}
+
+func TestLogoutAuditOutcomeFollowsDeletedRow(t *testing.T) {
+	svc := statelesstest.NewService(t, statelesstest.Options{})
+	tokens := login(t, svc)
+	cmd := stateless.LogoutCommand{RefreshToken: tokens.RefreshToken, UserID: testUserID}
+
+	if err := svc.Logout(context.Background(), cmd); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if err := svc.Logout(context.Background(), cmd); !errors.Is(err, stateless.ErrSessionNotFound) {
+		t.Fatalf("second Logout error = %v, want ErrSessionNotFound", err)
+	}
+
+	want := []string{audit.OutcomeSuccess, audit.OutcomeFailure}
+	if got := logoutOutcomes(svc); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("logout outcomes = %v, want %v", got, want)
+	}
+}
+
+func TestConcurrentLogoutSucceedsOnce(t *testing.T) {
+	svc := statelesstest.NewService(t, statelesstest.Options{})
+	tokens := login(t, svc)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			svc.Logout(context.Background(), stateless.LogoutCommand{RefreshToken: tokens.RefreshToken, UserID: testUserID})
+		}()
+	}
+	wg.Wait()
+
+	successes := 0
+	for _, outcome := range logoutOutcomes(svc) {
+		if outcome == audit.OutcomeSuccess {
+			successes++
+		}
+	}
+	if successes != 1 {
+		t.Fatalf("successful logouts in audit = %d, want 1", successes)
+	}
+	logouts := 0
+	for _, eventType := range svc.Events.Types() {
+		if eventType == stateless.EventLogoutPerformed {
+			logouts++
+		}
+	}
+	if logouts != 1 {
+		t.Fatalf("LogoutPerformed events = %d, want 1", logouts)
+	}
+}
@@ -0,0 +1,51 @@
+package stateless
+
+import (
+	"errors"
+)
+
+// TokenKind чем был выдан access токен
+type TokenKind string
+
+const (
+	// TokenKindSession новая сессия: вход, OAuth код, passkey, внешний провайдер, downscope
+	TokenKindSession           TokenKind = "session"
+	TokenKindRefresh           TokenKind = "refresh"
+	TokenKindStepUp            TokenKind = "step_up"
+	TokenKindClientCredentials TokenKind = "client_credentials"
+)
+
+type RefreshOutcome string
+
+const (
+	RefreshSuccess      RefreshOutcome = "success"
+	RefreshUAMismatch   RefreshOutcome = "ua_mismatch"
+	RefreshNotFound     RefreshOutcome = "not_found"
+	RefreshInvalid      RefreshOutcome = "invalid"
+	RefreshInternalFail RefreshOutcome = "error"
+)
+
+type SessionEndKind string
+
+const (
+	SessionEndLogout SessionEndKind = "logout"
+	// SessionEndByID OIDC logout и отзыв токена клиентом
+	SessionEndByID SessionEndKind = "end_session"
+)
+
+// refreshOutcome относит результат обновления пары к одному из исходов для метрик
+func refreshOutcome(err error) RefreshOutcome {
+	switch {
+	case err == nil:
+		return RefreshSuccess
+	case errors.Is(err, ErrUserAgentChanged):
+		return RefreshUAMismatch
+	case errors.Is(err, ErrSessionNotFound):
+		return RefreshNotFound
+	case errors.Is(err, ErrAccessTokenInvalid), errors.Is(err, ErrAccessTokenExpired),
+		errors.Is(err, ErrSessionClientMismatch), errors.Is(err, ErrScopeNotGranted):
+		return RefreshInvalid
+	default:
+		return RefreshInternalFail
+	}
+}
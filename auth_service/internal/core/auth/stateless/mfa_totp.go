@@ -0,0 +1,155 @@
+package stateless
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	mfaMaxFailedAttempts = 5
+	mfaLockDuration      = 5 * time.Minute
+	mfaRecoveryCodeCount = 10
+)
+
+// EnrollTOTP создаёт новый неподтверждённый TOTP секрет. Уже подтверждённый секрет не перезаписывается
+func (s *StatelessAuthService) EnrollTOTP(ctx context.Context, userID UserID) (TOTPEnrollmentResult, error) {
+	existing, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if err != nil && !errors.Is(err, ErrMFANotEnrolled) {
+		return TOTPEnrollmentResult{}, err
+	}
+	if err == nil && existing.Confirmed {
+		return TOTPEnrollmentResult{}, ErrMFAAlreadyEnrolled
+	}
+
+	secret, err := s.totpAlgs.GenerateSecret()
+	if err != nil {
+		return TOTPEnrollmentResult{}, err
+	}
+
+	err = s.mfaRepo.SaveTOTP(ctx, TOTPEnrollment{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		return TOTPEnrollmentResult{}, err
+	}
+
+	return TOTPEnrollmentResult{
+		Secret:          secret,
+		ProvisioningURI: s.totpAlgs.ProvisioningURI(secret, string(userID)),
+	}, nil
+}
+
+// ConfirmTOTP подтверждает подключение TOTP первым кодом и выдаёт коды восстановления.
+// Коды возвращаются единственный раз, в базе хранятся только их хеши
+func (s *StatelessAuthService) ConfirmTOTP(ctx context.Context, userID UserID, code string) ([]string, error) {
+	enrollment, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.Confirmed {
+		return nil, ErrMFAAlreadyEnrolled
+	}
+
+	if err := s.checkTOTPCode(ctx, enrollment, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashes := make([]string, 0, mfaRecoveryCodeCount)
+	for range mfaRecoveryCodeCount {
+		code, err := s.totpAlgs.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := s.refreshTokenAlgs.GetHash(code)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+
+	// при параллельном подтверждении коды сохраняет только первый запрос
+	confirmed, err := s.mfaRepo.ConfirmTOTP(ctx, userID, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, ErrMFAAlreadyEnrolled
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP отключает второй фактор вместе с кодами восстановления
+func (s *StatelessAuthService) DisableTOTP(ctx context.Context, userID UserID) error {
+	return s.mfaRepo.DeleteTOTP(ctx, userID)
+}
+
+type StepUpCommand struct {
+	Payload AccessTokenPayload
+	Code    string
+}
+
+// StepUpMFA повторно проверяет второй фактор и выдаёт access токен той же пары с обновлённым временем проверки
+func (s *StatelessAuthService) StepUpMFA(ctx context.Context, cmd StepUpCommand) (token AccessToken, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.StepUpMFA")
+	defer func() { span.End(err) }()
+
+	enrollment, err := s.mfaRepo.GetTOTP(ctx, cmd.Payload.UserID)
+	if err != nil {
+		return "", err
+	}
+	if !enrollment.Confirmed {
+		return "", ErrMFANotEnrolled
+	}
+
+	if err := s.checkTOTPCode(ctx, enrollment, cmd.Code); err != nil {
+		return "", err
+	}
+
+	payload := cmd.Payload
+	payload.MFAVerifiedAt = time.Now()
+
+	token, err = s.accessTokenAlgs.Generate(payload)
+	if err != nil {
+		return "", err
+	}
+	s.metrics.TokenIssued(TokenKindStepUp)
+	return token, nil
+}
+
+// checkTOTPCode проверяет код с учётом блокировки после серии ошибок и запрета повторного использования кода.
+// Шаг принимает хранилище условным обновлением, поэтому из параллельных запросов с одним кодом проходит только один
+func (s *StatelessAuthService) checkTOTPCode(ctx context.Context, enrollment TOTPEnrollment, code string) error {
+	now := time.Now()
+	if enrollment.LockedUntil.After(now) {
+		return ErrMFALocked
+	}
+
+	step, ok := s.totpAlgs.Validate(enrollment.Secret, code, now)
+	if !ok {
+		return s.registerMFAFailure(ctx, enrollment.UserID)
+	}
+	used, err := s.mfaRepo.UseTOTPStep(ctx, enrollment.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return s.registerMFAFailure(ctx, enrollment.UserID)
+	}
+	return nil
+}
+
+func (s *StatelessAuthService) registerMFAFailure(ctx context.Context, userID UserID) error {
+	locked, err := s.mfaRepo.RegisterTOTPFailure(ctx, userID, mfaMaxFailedAttempts, time.Now().Add(mfaLockDuration))
+	if err != nil {
+		return err
+	}
+	if locked {
+		s.logger.WarnContext(ctx, "TOTP locked after failed attempts", "user_id", userID)
+	}
+	return ErrMFAInvalidCode
+}
@@ -0,0 +1,100 @@
+package stateless_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/internal/core/auth/stateless/statelesstest"
+	"medods_test/pkg/totp"
+)
+
+// enrollTOTP подключает TOTP кодом предыдущего шага, чтобы код текущего шага оставался неиспользованным
+func enrollTOTP(t *testing.T, svc *statelesstest.Service) string {
+	t.Helper()
+	ctx := context.Background()
+	enrollment, err := svc.EnrollTOTP(ctx, testUserID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now().Add(-30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ConfirmTOTP(ctx, testUserID, code); err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	return enrollment.Secret
+}
+
+func stepUp(svc *statelesstest.Service, code string) error {
+	_, err := svc.StepUpMFA(context.Background(), stateless.StepUpCommand{
+		Payload: stateless.AccessTokenPayload{UserID: testUserID},
+		Code:    code,
+	})
+	return err
+}
+
+// parallel запускает n вызовов одновременно и возвращает их ошибки
+func parallel(n int, call func() error) []error {
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = call()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func TestConcurrentTOTPReplayIsAcceptedOnce(t *testing.T) {
+	svc := statelesstest.NewService(t, statelesstest.Options{})
+	secret := enrollTOTP(t, svc)
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// меньше, чем допускается ошибок до блокировки, чтобы отказы были именно из-за повтора
+	accepted := 0
+	for _, err := range parallel(4, func() error { return stepUp(svc, code) }) {
+		switch {
+		case err == nil:
+			accepted++
+		case !errors.Is(err, stateless.ErrMFAInvalidCode):
+			t.Fatalf("StepUpMFA: %v", err)
+		}
+	}
+	if accepted != 1 {
+		t.Fatalf("code accepted %d times, want once", accepted)
+	}
+}
+
+func TestConcurrentWrongTOTPCodesLockEnrollment(t *testing.T) {
+	svc := statelesstest.NewService(t, statelesstest.Options{})
+	secret := enrollTOTP(t, svc)
+
+	// столько же параллельных ошибок, сколько допускается до блокировки: ни одна не должна потеряться
+	for _, err := range parallel(5, func() error { return stepUp(svc, "not-a-code") }) {
+		if !errors.Is(err, stateless.ErrMFAInvalidCode) {
+			t.Fatalf("wrong code: err = %v, want ErrMFAInvalidCode", err)
+		}
+	}
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stepUp(svc, code); !errors.Is(err, stateless.ErrMFALocked) {
+		t.Fatalf("valid code after lockout: err = %v, want ErrMFALocked", err)
+	}
+}
@@ -0,0 +1,68 @@
+package stateless
+
+import (
+	"context"
+	"time"
+)
+
+type VerifyMFACommand struct {
+	PendingToken MFAPendingToken
+	Code         string
+	RecoveryCode string
+	UserAgent    string
+	IP           string
+}
+
+// VerifyMFA обменивает промежуточный токен и TOTP код (или код восстановления) на пару токенов
+func (s *StatelessAuthService) VerifyMFA(ctx context.Context, cmd VerifyMFACommand) (tokens TokenPair, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.VerifyMFA")
+	defer func() { span.End(err) }()
+
+	pending, err := s.mfaPendingTokenAlgs.Validate(cmd.PendingToken)
+	if err != nil {
+		return TokenPair{}, ErrMFAPendingTokenInvalid
+	}
+
+	amr := []string{AMRPassword, AMROTP, AMRMultiFactor}
+	if cmd.RecoveryCode != "" {
+		amr = []string{AMRPassword, AMRMultiFactor}
+	}
+	defer func() {
+		s.recordLogin(ctx, loginAudit{Subject: pending.UserID, UserAgent: cmd.UserAgent, IP: cmd.IP, AMR: amr}, err)
+	}()
+
+	if pending.UserAgent != cmd.UserAgent {
+		return TokenPair{}, ErrUserAgentChanged
+	}
+
+	enrollment, err := s.mfaRepo.GetTOTP(ctx, pending.UserID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !enrollment.Confirmed {
+		return TokenPair{}, ErrMFANotEnrolled
+	}
+
+	if cmd.RecoveryCode != "" {
+		if enrollment.LockedUntil.After(time.Now()) {
+			return TokenPair{}, ErrMFALocked
+		}
+		used, err := s.mfaRepo.UseRecoveryCode(ctx, pending.UserID, cmd.RecoveryCode)
+		if err != nil {
+			return TokenPair{}, err
+		}
+		if !used {
+			return TokenPair{}, s.registerMFAFailure(ctx, pending.UserID)
+		}
+	} else if err := s.checkTOTPCode(ctx, enrollment, cmd.Code); err != nil {
+		return TokenPair{}, err
+	}
+
+	return s.issueTokenPair(ctx, issueTokenPairParams{
+		UserID:        pending.UserID,
+		UserAgent:     cmd.UserAgent,
+		IP:            cmd.IP,
+		MFAVerifiedAt: time.Now(),
+		AMR:           amr,
+	})
+}
@@ -0,0 +1,79 @@
+package stateless
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RegisterPasskey сохраняет ключ, прошедший проверку церемонии регистрации
+func (s *StatelessAuthService) RegisterPasskey(ctx context.Context, credential PasskeyCredential) error {
+	return s.passkeyRepo.SavePasskey(ctx, credential)
+}
+
+func (s *StatelessAuthService) ListPasskeys(ctx context.Context, userID UserID) ([]PasskeyCredential, error) {
+	return s.passkeyRepo.ListPasskeys(ctx, userID)
+}
+
+type PasskeyLoginCommand struct {
+	CredentialID []byte
+	SignCount    uint32
+	Flags        uint8
+	UserVerified bool
+	UserAgent    string
+	IP           string
+}
+
+// AuthenticateWithPasskey выдаёт пару токенов по ключу, подпись которого уже проверена адаптером.
+// Если счётчик подписей не вырос, ключ считается склонированным и блокируется
+func (s *StatelessAuthService) AuthenticateWithPasskey(ctx context.Context, cmd PasskeyLoginCommand) (tokens TokenPair, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.AuthenticateWithPasskey")
+	defer func() { span.End(err) }()
+
+	credential, err := s.passkeyRepo.GetPasskey(ctx, cmd.CredentialID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	defer func() {
+		s.recordLogin(ctx, loginAudit{
+			Subject:   credential.UserID,
+			UserAgent: cmd.UserAgent,
+			IP:        cmd.IP,
+			AMR:       []string{AMRHardwareKey},
+		}, err)
+	}()
+
+	if credential.CloneDetected {
+		return TokenPair{}, ErrPasskeyCloned
+	}
+
+	// сравнение счётчика выполняет сам UPDATE: при чтении и записи по отдельности два входа
+	// с одним и тем же счётчиком оба прошли бы проверку. Нулевые счётчики означают,
+	// что аутентификатор их не поддерживает (например, синхронизируемые passkey)
+	updated, err := s.passkeyRepo.UpdatePasskeyUsage(ctx, cmd.CredentialID, cmd.SignCount, cmd.Flags)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !updated {
+		s.logger.WarnContext(ctx, "passkey clone detected", slog.String("user_id", string(credential.UserID)),
+			slog.Any("stored_sign_count", credential.SignCount), slog.Any("sign_count", cmd.SignCount))
+		if err := s.passkeyRepo.MarkPasskeyCloned(ctx, cmd.CredentialID); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, ErrPasskeyCloned
+	}
+
+	params := issueTokenPairParams{
+		UserID:    credential.UserID,
+		UserAgent: cmd.UserAgent,
+		IP:        cmd.IP,
+		AMR:       []string{AMRHardwareKey, AMRUser},
+	}
+	// passkey с проверкой пользователя (биометрия, PIN) сама по себе является многофакторной
+	if cmd.UserVerified {
+		params.MFAVerifiedAt = time.Now()
+		params.AMR = append(params.AMR, AMRMultiFactor)
+	}
+
+	return s.issueTokenPair(ctx, params)
+}
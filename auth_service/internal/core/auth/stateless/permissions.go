@@ -0,0 +1,53 @@
+package stateless
+
+import (
+	"slices"
+)
+
+// Scope, которыми ограничивается доступ к собственным эндпойнтам сервиса
+const (
+	ScopeProfileRead      = "profile:read"
+	ScopeMFAManage        = "mfa:manage"
+	ScopePasskeysManage   = "passkeys:manage"
+	ScopeOAuthAuthorize   = "oauth:authorize"
+	ScopeIdentitiesManage = "identities:manage"
+)
+
+const (
+	RoleUser   UserRole = "user"
+	RoleClient UserRole = "client"
+)
+
+// rolePermissions scope, которые получает пара токенов, если они не запрошены явно
+var rolePermissions = map[UserRole][]string{
+	RoleUser: {
+		ScopeProfileRead,
+		ScopeMFAManage,
+		ScopePasskeysManage,
+		ScopeOAuthAuthorize,
+		ScopeIdentitiesManage,
+	},
+}
+
+// PermissionsForRole возвращает копию набора scope роли, для неизвестной роли пустой набор
+func PermissionsForRole(role UserRole) []string {
+	return slices.Clone(rolePermissions[role])
+}
+
+// GrantedScopes scope токена. Токены, выпущенные до появления scope, получают права своей роли
+func (p AccessTokenPayload) GrantedScopes() []string {
+	if p.Scopes == nil {
+		return PermissionsForRole(p.Role)
+	}
+	return p.Scopes
+}
+
+// HasScopes проверяет, что все required входят в granted
+func HasScopes(granted []string, required ...string) bool {
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package stateless
 import (
 	"context"
 	"log/slog"
+	"medods_test/internal/core/audit"
 )
 
 type RefreshTokenCommand struct {
@@ -12,33 +13,137 @@ type RefreshTokenCommand struct {
 	IP           string
 }
 
-func (s *StatelessAuthService) RefreshTokens(ctx context.Context, cmd RefreshTokenCommand) (TokenPair, error) {
+func (s *StatelessAuthService) RefreshTokens(ctx context.Context, cmd RefreshTokenCommand) (tokens TokenPair, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.RefreshTokens")
+	defer func() { span.End(err) }()
+	defer func() { s.metrics.RefreshCompleted(refreshOutcome(err)) }()
+
 	accessTokenPayload, err := s.accessTokenAlgs.Validate(cmd.AccessToken)
 	if err != nil && err != ErrAccessTokenExpired {
+		s.recordRefresh(ctx, refreshSessionParams{UserAgent: cmd.UserAgent, IP: cmd.IP}, err)
 		return TokenPair{}, err
 	}
 	if accessTokenPayload.UserID == "" {
+		s.recordRefresh(ctx, refreshSessionParams{UserAgent: cmd.UserAgent, IP: cmd.IP}, ErrAccessTokenInvalid)
 		return TokenPair{}, ErrAccessTokenInvalid
 	}
 
-	sessionData, err := s.authRepo.GetSession(ctx, accessTokenPayload.UserID, string(cmd.RefreshToken))
+	return s.refreshSession(ctx, refreshSessionParams{
+		UserID:       accessTokenPayload.UserID,
+		RefreshToken: cmd.RefreshToken,
+		UserAgent:    cmd.UserAgent,
+		IP:           cmd.IP,
+	})
+}
+
+type RefreshSessionCommand struct {
+	UserID       UserID
+	RefreshToken RefreshToken
+	UserAgent    string
+	IP           string
+	ClientID     string
+	// Scopes сужает права сессии, nil оставляет их прежними
+	Scopes []string
+}
+
+// RefreshSession обновляет пару без access токена, для OAuth клиентов.
+// Сессия должна принадлежать тому же клиенту, иначе возвращается ErrSessionClientMismatch.
+// Запрошенные Scopes должны входить в права сессии, иначе возвращается ErrScopeNotGranted
+func (s *StatelessAuthService) RefreshSession(ctx context.Context, cmd RefreshSessionCommand) (tokens TokenPair, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.RefreshSession")
+	defer func() { span.End(err) }()
+	defer func() { s.metrics.RefreshCompleted(refreshOutcome(err)) }()
+
+	return s.refreshSession(ctx, refreshSessionParams{
+		UserID:       cmd.UserID,
+		RefreshToken: cmd.RefreshToken,
+		UserAgent:    cmd.UserAgent,
+		IP:           cmd.IP,
+		ClientID:     cmd.ClientID,
+		CheckClient:  true,
+		Scopes:       cmd.Scopes,
+	})
+}
+
+type refreshSessionParams struct {
+	UserID       UserID
+	RefreshToken RefreshToken
+	UserAgent    string
+	IP           string
+	ClientID     string
+	CheckClient  bool
+	Scopes       []string
+}
+
+func (s *StatelessAuthService) refreshSession(ctx context.Context, params refreshSessionParams) (_ TokenPair, err error) {
+	defer func() { s.recordRefresh(ctx, params, err) }()
+
+	sessionData, err := s.authRepo.GetSession(ctx, params.UserID, string(params.RefreshToken))
 	if err != nil {
-		s.logger.Error("Ошибка обновления токена", slog.Any("err", err), "useId:"+accessTokenPayload.UserID)
+		s.logger.ErrorContext(ctx, "Ошибка обновления токена", slog.Any("err", err), slog.String("user_id", string(params.UserID)))
 		return TokenPair{}, err
 	}
 
-	s.Logout(ctx, cmd.RefreshToken, accessTokenPayload.UserID)
+	if params.CheckClient && sessionData.ClientID != params.ClientID {
+		return TokenPair{}, ErrSessionClientMismatch
+	}
 
-	if sessionData.UserAgent != cmd.UserAgent {
+	// сессии, созданные до появления scope: собственные получают права роли, OAuth клиентов не получают ничего
+	if sessionData.Scopes == nil {
+		sessionData.Scopes = []string{}
+		if sessionData.ClientID == "" {
+			sessionData.Scopes = PermissionsForRole(RoleUser)
+		}
+	}
+	if params.Scopes != nil {
+		if !HasScopes(sessionData.Scopes, params.Scopes...) {
+			return TokenPair{}, ErrScopeNotGranted
+		}
+		sessionData.Scopes = params.Scopes
+	}
+
+	// bcrypt хеш с новой солью не совпал бы с сохранённым, поэтому удаляется именно найденная строка.
+	// Из двух параллельных обновлений одним токеном строку удалит только одно
+	if err := s.authRepo.DeleteSession(ctx, params.UserID, sessionData.RefreshHash); err != nil {
+		return TokenPair{}, err
+	}
+
+	if sessionData.UserAgent != params.UserAgent {
+		s.recordSessionEvent(ctx, audit.Event{
+			Action:    audit.ActionUAMismatch,
+			Actor:     refreshActor(params),
+			Subject:   string(params.UserID),
+			IP:        params.IP,
+			UserAgent: params.UserAgent,
+			Reason:    "session user agent: " + sessionData.UserAgent,
+		}, nil)
+		s.events.Publish(ctx, UserAgentMismatch{
+			EventMeta:         s.newEventMeta(ctx, params.UserID, sessionData.SessionID, sessionData.TokenPairID),
+			ExpectedUserAgent: sessionData.UserAgent,
+			ActualUserAgent:   params.UserAgent,
+			IP:                params.IP,
+		})
+		s.events.Publish(ctx, SessionRevoked{
+			EventMeta: s.newEventMeta(ctx, params.UserID, sessionData.SessionID, sessionData.TokenPairID),
+			Reason:    RevokeReasonUserAgentMismatch,
+		})
 		return TokenPair{}, ErrUserAgentChanged
 	}
 
-	if sessionData.IP != cmd.IP {
-		s.userIPChangedPublisher.Publish(UserIPChangedEvent{
-			UserID: accessTokenPayload.UserID,
-			OldIP:  sessionData.IP,
-			NewIP:  cmd.IP,
+	if sessionData.IP != params.IP {
+		s.events.Publish(ctx, UserIPChangedEvent{
+			EventMeta: s.newEventMeta(ctx, params.UserID, sessionData.SessionID, sessionData.TokenPairID),
+			OldIP:     sessionData.IP,
+			NewIP:     params.IP,
 		})
+		s.recordSessionEvent(ctx, audit.Event{
+			Action:    audit.ActionIPChanged,
+			Actor:     refreshActor(params),
+			Subject:   string(params.UserID),
+			IP:        params.IP,
+			UserAgent: params.UserAgent,
+			Reason:    sessionData.IP + " -> " + params.IP,
+		}, nil)
 	}
 
 	newTokenPairID, err := s.tokenPairIDGenerator.Generate()
@@ -46,12 +151,17 @@ func (s *StatelessAuthService) RefreshTokens(ctx context.Context, cmd RefreshTok
 		return TokenPair{}, err
 	}
 
+	previousTokenPairID := sessionData.TokenPairID
 	sessionData.TokenPairID = TokenPairID(newTokenPairID)
 
-	accessTokenPayload = AccessTokenPayload{
-		UserID:      accessTokenPayload.UserID,
+	accessTokenPayload := AccessTokenPayload{
+		UserID:      params.UserID,
 		TokenPairID: sessionData.TokenPairID,
-		Role:        UserRole("user"),
+		Role:        RoleUser,
+		ClientID:    sessionData.ClientID,
+		AuthTime:    sessionData.AuthTime,
+		AMR:         sessionData.AMR,
+		Scopes:      sessionData.Scopes,
 	}
 
 	newAccessToken, err := s.accessTokenAlgs.Generate(accessTokenPayload)
@@ -65,7 +175,7 @@ func (s *StatelessAuthService) RefreshTokens(ctx context.Context, cmd RefreshTok
 		return TokenPair{}, err
 	}
 
-	newRefreshHash, err := s.refreshTokenAlgs.GetHash(string(newRefreshToken))
+	newRefreshHash, err := s.hashRefreshToken(ctx, string(newRefreshToken))
 	if err != nil {
 		return TokenPair{}, err
 	}
@@ -76,9 +186,45 @@ func (s *StatelessAuthService) RefreshTokens(ctx context.Context, cmd RefreshTok
 	if err != nil {
 		return TokenPair{}, err
 	}
+	s.metrics.TokenIssued(TokenKindRefresh)
+	s.events.Publish(ctx, SessionRefreshed{
+		EventMeta:           s.newEventMeta(ctx, params.UserID, sessionData.SessionID, sessionData.TokenPairID),
+		PreviousTokenPairID: previousTokenPairID,
+		ClientID:            sessionData.ClientID,
+		UserAgent:           params.UserAgent,
+		IP:                  params.IP,
+	})
 
 	return TokenPair{
 		AccessToken:  newAccessToken,
 		RefreshToken: newRefreshToken,
+		SessionID:    sessionData.SessionID,
+		Scopes:       sessionData.Scopes,
 	}, nil
 }
+
+func (s *StatelessAuthService) recordRefresh(ctx context.Context, params refreshSessionParams, err error) {
+	s.recordSessionEvent(ctx, audit.Event{
+		Action:    audit.ActionRefresh,
+		Actor:     refreshActor(params),
+		Subject:   string(params.UserID),
+		IP:        params.IP,
+		UserAgent: params.UserAgent,
+	}, err)
+}
+
+// refreshActor OAuth клиент обновляет сессию от своего имени, иначе это сам пользователь
+func refreshActor(params refreshSessionParams) string {
+	if params.CheckClient {
+		return "client:" + params.ClientID
+	}
+	return string(params.UserID)
+}
+
+// hashRefreshToken bcrypt заметно медленнее остальных шагов, поэтому выделен в отдельный спан
+func (s *StatelessAuthService) hashRefreshToken(ctx context.Context, token string) (hash string, err error) {
+	_, span := s.tracer.Start(ctx, "RefreshTokenAlgoHelper.GetHash")
+	defer func() { span.End(err) }()
+
+	return s.refreshTokenAlgs.GetHash(token)
+}
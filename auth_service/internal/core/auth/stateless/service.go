@@ -1,14 +1,54 @@
 package stateless
 
 import (
-	"log/slog"
 	"context"
+	"log/slog"
+	"medods_test/internal/core/audit"
+	"time"
 )
 
+// AuthRepository хранит сессии. GetSession возвращает ErrSessionNotFound, если подходящей сессии нет.
+// DeleteSession принимает сохранённый SessionData.RefreshHash: bcrypt хеш самого токена каждый раз новый.
+// DeleteSession и DeleteSessionByID возвращают ErrSessionNotFound, если ни одна строка не удалена,
+// события о завершении сессии публикуются только после настоящего удаления
 type AuthRepository interface {
 	SaveSession(ctx context.Context, session SessionData) error
 	DeleteSession(ctx context.Context, userID UserID, refreshHash string) error
+	DeleteSessionByID(ctx context.Context, userID UserID, sessionID SessionID) error
 	GetSession(ctx context.Context, userID UserID, refreshHash string) (SessionData, error)
+	GetSessionByTokenPairID(ctx context.Context, userID UserID, tokenPairID TokenPairID) (SessionData, error)
+	ListSessions(ctx context.Context, userID UserID) ([]SessionData, error)
+	// CountSessions число активных сессий всех пользователей, для метрик
+	CountSessions(ctx context.Context) (int, error)
+}
+
+// MFARepository хранит TOTP секреты и хеши кодов восстановления.
+// GetTOTP возвращает ErrMFANotEnrolled, если пользователь не подключал TOTP.
+// Состояние TOTP меняется атомарно в хранилище, без чтения перед записью:
+//   - ConfirmTOTP подтверждает TOTP и заменяет коды восстановления, false если он уже подтверждён;
+//   - UseTOTPStep сохраняет шаг, только если он больше последнего использованного и TOTP не заблокирован,
+//     сбрасывая счётчик ошибок, false если шаг не принят;
+//   - RegisterTOTPFailure увеличивает счётчик ошибок, а на maxAttempts сбрасывает его и блокирует TOTP до lockUntil,
+//     true если блокировка установлена этим вызовом
+type MFARepository interface {
+	SaveTOTP(ctx context.Context, enrollment TOTPEnrollment) error
+	GetTOTP(ctx context.Context, userID UserID) (TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID UserID, recoveryCodeHashes []string) (bool, error)
+	UseTOTPStep(ctx context.Context, userID UserID, step int64) (bool, error)
+	RegisterTOTPFailure(ctx context.Context, userID UserID, maxAttempts int, lockUntil time.Time) (bool, error)
+	DeleteTOTP(ctx context.Context, userID UserID) error
+	UseRecoveryCode(ctx context.Context, userID UserID, code string) (bool, error)
+}
+
+// PasskeyRepository хранит WebAuthn ключи. GetPasskey возвращает ErrPasskeyNotFound, если ключ не найден.
+// UpdatePasskeyUsage атомарно сохраняет счётчик, только если он вырос (или оба нулевые) и ключ не заблокирован,
+// и возвращает false, если строка не обновлена
+type PasskeyRepository interface {
+	SavePasskey(ctx context.Context, credential PasskeyCredential) error
+	GetPasskey(ctx context.Context, credentialID []byte) (PasskeyCredential, error)
+	ListPasskeys(ctx context.Context, userID UserID) ([]PasskeyCredential, error)
+	UpdatePasskeyUsage(ctx context.Context, credentialID []byte, signCount uint32, flags uint8) (bool, error)
+	MarkPasskeyCloned(ctx context.Context, credentialID []byte) error
 }
 
 type AccessTokenAlgoHelper interface {
@@ -16,41 +56,108 @@ type AccessTokenAlgoHelper interface {
 	Validate(token AccessToken) (AccessTokenPayload, error)
 }
 
+type MFAPendingTokenAlgoHelper interface {
+	Generate(payload MFAPendingPayload) (MFAPendingToken, error)
+	Validate(token MFAPendingToken) (MFAPendingPayload, error)
+}
+
 type RefreshTokenAlgoHelper interface {
 	Generate() (string, error)
 	GetHash(token string) (string, error)
 }
 
+type TOTPAlgoHelper interface {
+	GenerateSecret() (string, error)
+	ProvisioningURI(secret string, account string) string
+	// Validate возвращает номер временного шага, которому соответствует код
+	Validate(secret string, code string, at time.Time) (int64, bool)
+	GenerateRecoveryCode() (string, error)
+}
+
 type StringIdGenerator interface {
 	Generate() (string, error)
 }
 
-type UserIPChangedPublisher interface {
-	Publish(event UserIPChangedEvent)
+// EventPublisher доставляет события подписчикам асинхронно, Publish не должен надолго блокировать запрос.
+// ctx контекст запроса: подписчики получают его значения (например, трассу), но не отмену
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// EventPublishers передаёт событие каждому издателю по очереди
+type EventPublishers []EventPublisher
+
+func (p EventPublishers) Publish(ctx context.Context, event Event) {
+	for _, publisher := range p {
+		publisher.Publish(ctx, event)
+	}
+}
+
+// Metrics счётчики операций сервиса для мониторинга
+type Metrics interface {
+	TokenIssued(kind TokenKind)
+	RefreshCompleted(outcome RefreshOutcome)
+	SessionEnded(kind SessionEndKind)
+}
+
+// Tracer создаёт спаны трассировки для методов сервиса
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type Span interface {
+	// End завершает спан, ненулевая ошибка помечает его как неуспешный
+	End(err error)
+}
+
+// AuditRecorder журнал аудита входов, обновлений и выходов
+type AuditRecorder interface {
+	Record(ctx context.Context, event audit.Event)
 }
 
 type StatelessAuthService struct {
-	authRepo               AuthRepository
-	accessTokenAlgs        AccessTokenAlgoHelper
-	refreshTokenAlgs       RefreshTokenAlgoHelper
-	tokenPairIDGenerator   StringIdGenerator
-	logger                 *slog.Logger
-	userIPChangedPublisher UserIPChangedPublisher
+	authRepo             AuthRepository
+	mfaRepo              MFARepository
+	passkeyRepo          PasskeyRepository
+	accessTokenAlgs      AccessTokenAlgoHelper
+	mfaPendingTokenAlgs  MFAPendingTokenAlgoHelper
+	refreshTokenAlgs     RefreshTokenAlgoHelper
+	totpAlgs             TOTPAlgoHelper
+	tokenPairIDGenerator StringIdGenerator
+	logger               *slog.Logger
+	events               EventPublisher
+	metrics              Metrics
+	tracer               Tracer
+	audit                AuditRecorder
 }
 
 func NewStatelessAuthService(
 	authRepo AuthRepository,
+	mfaRepo MFARepository,
+	passkeyRepo PasskeyRepository,
 	accessTokenAlgs AccessTokenAlgoHelper,
+	mfaPendingTokenAlgs MFAPendingTokenAlgoHelper,
 	refreshTokenAlgs RefreshTokenAlgoHelper,
+	totpAlgs TOTPAlgoHelper,
 	tokenPairIDGenerator StringIdGenerator,
-	userIPChangedPublisher UserIPChangedPublisher,
+	events EventPublisher,
+	metrics Metrics,
+	tracer Tracer,
+	audit AuditRecorder,
 	logger *slog.Logger) *StatelessAuthService {
 	return &StatelessAuthService{
-		authRepo:               authRepo,
-		accessTokenAlgs:        accessTokenAlgs,
-		refreshTokenAlgs:       refreshTokenAlgs,
-		tokenPairIDGenerator:   tokenPairIDGenerator,
-		logger:                 logger,
-		userIPChangedPublisher: userIPChangedPublisher,
+		authRepo:             authRepo,
+		mfaRepo:              mfaRepo,
+		passkeyRepo:          passkeyRepo,
+		accessTokenAlgs:      accessTokenAlgs,
+		mfaPendingTokenAlgs:  mfaPendingTokenAlgs,
+		refreshTokenAlgs:     refreshTokenAlgs,
+		totpAlgs:             totpAlgs,
+		tokenPairIDGenerator: tokenPairIDGenerator,
+		logger:               logger,
+		events:               events,
+		metrics:              metrics,
+		tracer:               tracer,
+		audit:                audit,
 	}
 }
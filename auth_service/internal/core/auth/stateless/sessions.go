@@ -0,0 +1,20 @@
+package stateless
+
+import (
+	"context"
+)
+
+// ListSessions активные сессии пользователя. RefreshHash не возвращается
+func (s *StatelessAuthService) ListSessions(ctx context.Context, userID UserID) (sessions []SessionData, err error) {
+	ctx, span := s.tracer.Start(ctx, "StatelessAuthService.ListSessions")
+	defer func() { span.End(err) }()
+
+	sessions, err = s.authRepo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].RefreshHash = ""
+	}
+	return sessions, nil
+}
@@ -0,0 +1,399 @@
+// Package statelesstest реализации портов stateless в памяти для тестов сервиса и адаптеров.
+// Хранилища повторяют поведение postgres репозиториев, включая ErrSessionNotFound при пустом удалении
+package statelesstest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"medods_test/internal/adapters/auth/stateless/jwthelper"
+	"medods_test/internal/core/audit"
+	"medods_test/internal/core/auth/stateless"
+	"medods_test/pkg/guidgenerator"
+	"medods_test/pkg/totp"
+	"medods_test/pkg/unikelongstring"
+)
+
+// HashChecker сравнение bcrypt хеша с токеном, как в postgres.RefreshHashChecker
+type HashChecker interface {
+	CompareHash(hash, token string) bool
+}
+
+type AuthRepository struct {
+	mu       sync.Mutex
+	hashes   HashChecker
+	sessions []stateless.SessionData
+}
+
+func NewAuthRepository(hashes HashChecker) *AuthRepository {
+	return &AuthRepository{hashes: hashes}
+}
+
+func (r *AuthRepository) SaveSession(_ context.Context, session stateless.SessionData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions = append(r.sessions, session)
+	return nil
+}
+
+func (r *AuthRepository) DeleteSession(_ context.Context, userID stateless.UserID, refreshHash string) error {
+	return r.delete(func(s stateless.SessionData) bool {
+		return s.UserID == userID && s.RefreshHash == refreshHash
+	})
+}
+
+func (r *AuthRepository) DeleteSessionByID(_ context.Context, userID stateless.UserID, sessionID stateless.SessionID) error {
+	return r.delete(func(s stateless.SessionData) bool {
+		return s.UserID == userID && s.SessionID == sessionID
+	})
+}
+
+func (r *AuthRepository) delete(match func(stateless.SessionData) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	before := len(r.sessions)
+	r.sessions = slices.DeleteFunc(r.sessions, match)
+	if len(r.sessions) == before {
+		return stateless.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *AuthRepository) GetSession(_ context.Context, userID stateless.UserID, refreshToken string) (stateless.SessionData, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.sessions {
+		if s.UserID == userID && r.hashes.CompareHash(s.RefreshHash, refreshToken) {
+			return s, nil
+		}
+	}
+	return stateless.SessionData{}, stateless.ErrSessionNotFound
+}
+
+func (r *AuthRepository) GetSessionByTokenPairID(_ context.Context, userID stateless.UserID, tokenPairID stateless.TokenPairID) (stateless.SessionData, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.TokenPairID == tokenPairID {
+			return s, nil
+		}
+	}
+	return stateless.SessionData{}, stateless.ErrSessionNotFound
+}
+
+func (r *AuthRepository) ListSessions(_ context.Context, userID stateless.UserID) ([]stateless.SessionData, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []stateless.SessionData
+	for _, s := range r.sessions {
+		if s.UserID == userID {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// CountSessions для gauge auth_active_sessions
+func (r *AuthRepository) CountSessions(context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sessions), nil
+}
+
+type MFARepository struct {
+	mu            sync.Mutex
+	hashes        HashChecker
+	enrollments   map[stateless.UserID]stateless.TOTPEnrollment
+	recoveryCodes map[stateless.UserID][]string
+}
+
+func NewMFARepository(hashes HashChecker) *MFARepository {
+	return &MFARepository{
+		hashes:        hashes,
+		enrollments:   map[stateless.UserID]stateless.TOTPEnrollment{},
+		recoveryCodes: map[stateless.UserID][]string{},
+	}
+}
+
+func (r *MFARepository) SaveTOTP(_ context.Context, enrollment stateless.TOTPEnrollment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enrollments[enrollment.UserID] = enrollment
+	return nil
+}
+
+func (r *MFARepository) GetTOTP(_ context.Context, userID stateless.UserID) (stateless.TOTPEnrollment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment, ok := r.enrollments[userID]
+	if !ok {
+		return stateless.TOTPEnrollment{}, stateless.ErrMFANotEnrolled
+	}
+	return enrollment, nil
+}
+
+func (r *MFARepository) ConfirmTOTP(_ context.Context, userID stateless.UserID, recoveryCodeHashes []string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment, ok := r.enrollments[userID]
+	if !ok || enrollment.Confirmed {
+		return false, nil
+	}
+	enrollment.Confirmed = true
+	r.enrollments[userID] = enrollment
+	r.recoveryCodes[userID] = slices.Clone(recoveryCodeHashes)
+	return true, nil
+}
+
+func (r *MFARepository) UseTOTPStep(_ context.Context, userID stateless.UserID, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment, ok := r.enrollments[userID]
+	if !ok || step <= enrollment.LastUsedStep || enrollment.LockedUntil.After(time.Now()) {
+		return false, nil
+	}
+	enrollment.LastUsedStep = step
+	enrollment.FailedAttempts = 0
+	enrollment.LockedUntil = time.Time{}
+	r.enrollments[userID] = enrollment
+	return true, nil
+}
+
+func (r *MFARepository) RegisterTOTPFailure(_ context.Context, userID stateless.UserID, maxAttempts int, lockUntil time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enrollment, ok := r.enrollments[userID]
+	if !ok {
+		return false, stateless.ErrMFANotEnrolled
+	}
+	locked := enrollment.FailedAttempts+1 >= maxAttempts
+	if locked {
+		enrollment.FailedAttempts = 0
+		enrollment.LockedUntil = lockUntil
+	} else {
+		enrollment.FailedAttempts++
+	}
+	r.enrollments[userID] = enrollment
+	return locked, nil
+}
+
+func (r *MFARepository) DeleteTOTP(_ context.Context, userID stateless.UserID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.enrollments, userID)
+	delete(r.recoveryCodes, userID)
+	return nil
+}
+
+func (r *MFARepository) UseRecoveryCode(_ context.Context, userID stateless.UserID, code string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, hash := range r.recoveryCodes[userID] {
+		if r.hashes.CompareHash(hash, code) {
+			r.recoveryCodes[userID] = slices.Delete(r.recoveryCodes[userID], i, i+1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type PasskeyRepository struct {
+	mu       sync.Mutex
+	passkeys []stateless.PasskeyCredential
+}
+
+func NewPasskeyRepository() *PasskeyRepository {
+	return &PasskeyRepository{}
+}
+
+func (r *PasskeyRepository) SavePasskey(_ context.Context, credential stateless.PasskeyCredential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.passkeys = append(r.passkeys, credential)
+	return nil
+}
+
+func (r *PasskeyRepository) GetPasskey(_ context.Context, credentialID []byte) (stateless.PasskeyCredential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.index(credentialID); i >= 0 {
+		return r.passkeys[i], nil
+	}
+	return stateless.PasskeyCredential{}, stateless.ErrPasskeyNotFound
+}
+
+func (r *PasskeyRepository) ListPasskeys(_ context.Context, userID stateless.UserID) ([]stateless.PasskeyCredential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []stateless.PasskeyCredential
+	for _, c := range r.passkeys {
+		if c.UserID == userID {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (r *PasskeyRepository) UpdatePasskeyUsage(_ context.Context, credentialID []byte, signCount uint32, flags uint8) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.index(credentialID)
+	if i < 0 || r.passkeys[i].CloneDetected {
+		return false, nil
+	}
+	stored := r.passkeys[i].SignCount
+	if stored >= signCount && !(stored == 0 && signCount == 0) {
+		return false, nil
+	}
+	r.passkeys[i].SignCount = signCount
+	r.passkeys[i].Flags = flags
+	return true, nil
+}
+
+func (r *PasskeyRepository) MarkPasskeyCloned(_ context.Context, credentialID []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.index(credentialID); i >= 0 {
+		r.passkeys[i].CloneDetected = true
+	}
+	return nil
+}
+
+func (r *PasskeyRepository) index(credentialID []byte) int {
+	return slices.IndexFunc(r.passkeys, func(c stateless.PasskeyCredential) bool {
+		return bytes.Equal(c.ID, credentialID)
+	})
+}
+
+// Events запоминает опубликованные события
+type Events struct {
+	mu     sync.Mutex
+	events []stateless.Event
+}
+
+func (e *Events) Publish(_ context.Context, event stateless.Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+// Types типы опубликованных событий по порядку
+func (e *Events) Types() []stateless.EventType {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	types := make([]stateless.EventType, 0, len(e.events))
+	for _, event := range e.events {
+		types = append(types, event.EventType())
+	}
+	return types
+}
+
+// Audit запоминает записи журнала аудита
+type Audit struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (a *Audit) Record(_ context.Context, event audit.Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, event)
+}
+
+func (a *Audit) Events() []audit.Event {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.events)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) TokenIssued(stateless.TokenKind)           {}
+func (nopMetrics) RefreshCompleted(stateless.RefreshOutcome) {}
+func (nopMetrics) SessionEnded(stateless.SessionEndKind)     {}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string) (context.Context, stateless.Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) End(error) {}
+
+// Service сервис поверх хранилищ в памяти с настоящими алгоритмами токенов
+type Service struct {
+	*stateless.StatelessAuthService
+	// AccessTokens проверяет выданные сервисом access токены, например для MiddlewareFactory
+	AccessTokens stateless.AccessTokenAlgoHelper
+	Sessions     *AuthRepository
+	MFA          *MFARepository
+	Passkeys     *PasskeyRepository
+	Events       *Events
+	Audit        *Audit
+}
+
+// Options nil поля заменяются заглушками
+type Options struct {
+	Metrics stateless.Metrics
+	Tracer  stateless.Tracer
+	Events  stateless.EventPublisher
+	// WrapSessions оборачивает хранилище сессий, как декораторы метрик и трассировки в bootstrap
+	WrapSessions func(stateless.AuthRepository) stateless.AuthRepository
+	// AccessTokenTTL по умолчанию минута, отрицательный выдаёт уже истёкшие access токены
+	AccessTokenTTL time.Duration
+}
+
+func NewService(t testing.TB, opts Options) *Service {
+	t.Helper()
+	if opts.AccessTokenTTL == 0 {
+		opts.AccessTokenTTL = time.Minute
+	}
+	refreshTokens := unikelongstring.NewULSHelper()
+	s := &Service{
+		AccessTokens: jwthelper.NewJWTAccessTokenHelper("test-secret", opts.AccessTokenTTL),
+		Sessions:     NewAuthRepository(refreshTokens),
+		MFA:          NewMFARepository(refreshTokens),
+		Passkeys:     NewPasskeyRepository(),
+		Events:       &Events{},
+		Audit:        &Audit{},
+	}
+	var metrics stateless.Metrics = nopMetrics{}
+	if opts.Metrics != nil {
+		metrics = opts.Metrics
+	}
+	var tracer stateless.Tracer = nopTracer{}
+	if opts.Tracer != nil {
+		tracer = opts.Tracer
+	}
+	var sessions stateless.AuthRepository = s.Sessions
+	if opts.WrapSessions != nil {
+		sessions = opts.WrapSessions(sessions)
+	}
+	events := stateless.EventPublishers{s.Events}
+	if opts.Events != nil {
+		events = append(events, opts.Events)
+	}
+	s.StatelessAuthService = stateless.NewStatelessAuthService(
+		sessions,
+		s.MFA,
+		s.Passkeys,
+		s.AccessTokens,
+		jwthelper.NewJWTMFAPendingTokenHelper("test-secret", time.Minute),
+		refreshTokens,
+		totp.NewTOTPHelper("test"),
+		guidgenerator.GuidGenerator{},
+		events,
+		metrics,
+		tracer,
+		s.Audit,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return s
+}
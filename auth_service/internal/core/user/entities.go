@@ -0,0 +1,16 @@
+package user
+
+import (
+	"time"
+)
+
+// Profile данные пользователя, которые отдаются клиентам (например, через /userinfo)
+type Profile struct {
+	ID            string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,9 @@
+package user
+
+import (
+	"errors"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+)
@@ -0,0 +1,46 @@
+package user
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// UserRepository GetProfile возвращает ErrUserNotFound, если пользователя нет
+type UserRepository interface {
+	GetProfile(ctx context.Context, id string) (Profile, error)
+	CreateUser(ctx context.Context, profile Profile) error
+}
+
+type IdGenerator interface {
+	Generate() (string, error)
+}
+
+type UserService struct {
+	repo        UserRepository
+	idGenerator IdGenerator
+	logger      *slog.Logger
+}
+
+func NewUserService(repo UserRepository, idGenerator IdGenerator, logger *slog.Logger) *UserService {
+	return &UserService{repo: repo, idGenerator: idGenerator, logger: logger}
+}
+
+func (s *UserService) GetProfile(ctx context.Context, id string) (Profile, error) {
+	return s.repo.GetProfile(ctx, id)
+}
+
+// CreateUser заводит пользователя с новым ID, переданный ID игнорируется
+func (s *UserService) CreateUser(ctx context.Context, profile Profile) (Profile, error) {
+	id, err := s.idGenerator.Generate()
+	if err != nil {
+		return Profile{}, err
+	}
+	profile.ID = id
+	profile.UpdatedAt = time.Now()
+
+	if err := s.repo.CreateUser(ctx, profile); err != nil {
+		return Profile{}, err
+	}
+	return profile, nil
+}
@@ -0,0 +1,154 @@
+// Package authclient клиент сервиса авторизации: выдача, обновление и завершение пары токенов,
+// хранилище пары с единственным одновременным обновлением и http.RoundTripper,
+// который подставляет access токен и обновляет пару при 401.
+//
+//	client := authclient.New("http://auth:8080")
+//	pair, err := client.IssueTokens(ctx, userID)
+//	store := authclient.NewTokenStore(client, pair)
+//	api := authclient.NewHTTPClient(store, nil)
+package authclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultUserAgent сервер привязывает сессию к User-Agent и отзывает её при смене,
+// поэтому все запросы одной пары должны идти с одним значением
+const DefaultUserAgent = "medods-authclient/1"
+
+// maxResponseSize ограничение на тело ответа сервера
+const maxResponseSize = 1 << 20
+
+// DefaultTimeout ограничение запроса к сервису авторизации, если WithHTTPClient не задан
+const DefaultTimeout = 10 * time.Second
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// Client вызывает /auth/token, /auth/refresh и /auth/logout
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient клиент для запросов к сервису авторизации. Не должен использовать Transport этого пакета
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New baseURL адрес сервиса без завершающего слеша, например http://auth:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		userAgent:  DefaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// IssueTokens получает пару по user_id. Если у пользователя подключён TOTP, возвращается *Error
+// с кодом CodeMFARequired и промежуточным токеном в MFAToken
+func (c *Client) IssueTokens(ctx context.Context, userID string) (TokenPair, error) {
+	var resp tokenResponse
+	err := c.do(ctx, "/v1/auth/token", "", struct {
+		UserID string `json:"user_id"`
+	}{UserID: userID}, &resp)
+	return resp.pair(), err
+}
+
+// Refresh обновляет пару. Старая пара после успешного вызова недействительна
+func (c *Client) Refresh(ctx context.Context, pair TokenPair) (TokenPair, error) {
+	var resp tokenResponse
+	err := c.do(ctx, "/v1/auth/refresh", "", struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}{AccessToken: pair.AccessToken, RefreshToken: pair.RefreshToken}, &resp)
+	return resp.pair(), err
+}
+
+// Logout завершает сессию пары
+func (c *Client) Logout(ctx context.Context, pair TokenPair) error {
+	return c.do(ctx, "/v1/auth/logout", pair.AccessToken, struct {
+		RefreshToken string `json:"refresh_token"`
+	}{RefreshToken: pair.RefreshToken}, nil)
+}
+
+// tokenResponse /auth/token отдаёт поля в snake_case, а /auth/refresh — AccessToken и RefreshToken
+type tokenResponse struct {
+	AccessToken       string `json:"access_token"`
+	RefreshToken      string `json:"refresh_token"`
+	AccessTokenCamel  string `json:"AccessToken"`
+	RefreshTokenCamel string `json:"RefreshToken"`
+}
+
+func (r tokenResponse) pair() TokenPair {
+	pair := TokenPair{AccessToken: r.AccessToken, RefreshToken: r.RefreshToken}
+	if pair.AccessToken == "" {
+		pair.AccessToken = r.AccessTokenCamel
+	}
+	if pair.RefreshToken == "" {
+		pair.RefreshToken = r.RefreshTokenCamel
+	}
+	return pair
+}
+
+func (c *Client) do(ctx context.Context, path string, accessToken string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return newError(resp.StatusCode, resp.Header.Get("Content-Type"), data)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("auth: failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package authclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// Коды ошибок из поля code ответа application/problem+json. Если тела нет, код выводится из статуса
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeTokenInvalid     = "token_invalid"
+	CodeTokenExpired     = "token_expired"
+	CodeSessionNotFound  = "session_not_found"
+	CodeUserAgentChanged = "user_agent_changed"
+	CodeMFARequired      = "mfa_required"
+	CodeInternal         = "internal_error"
+)
+
+var (
+	ErrBadRequest       = errors.New("bad request")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrForbidden        = errors.New("forbidden")
+	ErrTokenInvalid     = errors.New("token invalid")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrUserAgentChanged = errors.New("user agent changed")
+	ErrMFARequired      = errors.New("mfa required")
+	ErrInternal         = errors.New("internal server error")
+)
+
+var codeErrors = map[string]error{
+	CodeBadRequest:       ErrBadRequest,
+	CodeUnauthorized:     ErrUnauthorized,
+	CodeForbidden:        ErrForbidden,
+	CodeTokenInvalid:     ErrTokenInvalid,
+	CodeTokenExpired:     ErrTokenExpired,
+	CodeSessionNotFound:  ErrSessionNotFound,
+	CodeUserAgentChanged: ErrUserAgentChanged,
+	CodeMFARequired:      ErrMFARequired,
+	CodeInternal:         ErrInternal,
+}
+
+// Error ответ сервера с ошибкой. Сравнивается через errors.Is с ErrTokenExpired, ErrUserAgentChanged и т.д.,
+// любой ответ 401 также совпадает с ErrUnauthorized
+type Error struct {
+	StatusCode int
+	Code       string
+	// Message detail из ответа, а если его нет — title
+	Message string
+	// MFAToken промежуточный токен для /auth/mfa/verify, заполнен при CodeMFARequired
+	MFAToken string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("auth: %s (status %d)", e.Code, e.StatusCode)
+	}
+	return fmt.Sprintf("auth: %s: %s (status %d)", e.Code, e.Message, e.StatusCode)
+}
+
+func (e *Error) Is(target error) bool {
+	if target == ErrUnauthorized && e.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	return codeErrors[e.Code] == target
+}
+
+// problem тело ответа RFC 7807
+type problem struct {
+	Title    string `json:"title"`
+	Detail   string `json:"detail"`
+	Code     string `json:"code"`
+	MFAToken string `json:"mfa_token"`
+}
+
+// newError тело может быть пустым или не problem+json, тогда код определяется только по статусу
+func newError(statusCode int, contentType string, body []byte) *Error {
+	var parsed problem
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/problem+json" {
+		_ = json.Unmarshal(body, &parsed)
+	}
+
+	e := &Error{StatusCode: statusCode, Code: parsed.Code, Message: parsed.Detail, MFAToken: parsed.MFAToken}
+	if e.Message == "" {
+		e.Message = parsed.Title
+	}
+	if e.Code == "" {
+		e.Code = codeFromStatus(statusCode)
+	}
+	return e
+}
+
+func codeFromStatus(statusCode int) string {
+	switch {
+	case statusCode == http.StatusBadRequest:
+		return CodeBadRequest
+	case statusCode == http.StatusUnauthorized:
+		return CodeUnauthorized
+	case statusCode == http.StatusForbidden:
+		return CodeForbidden
+	default:
+		return CodeInternal
+	}
+}
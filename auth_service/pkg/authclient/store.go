@@ -0,0 +1,118 @@
+package authclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshTimeout сколько длится фоновое обновление пары, которое не отменяется вместе с запросом
+const DefaultRefreshTimeout = 30 * time.Second
+
+// TokenStore хранит текущую пару и обновляет её не больше одного раза за раз:
+// запросы, получившие 401 с одной и той же парой, дожидаются общего обновления
+type TokenStore struct {
+	client         *Client
+	onRotate       func(TokenPair)
+	refreshTimeout time.Duration
+
+	mu      sync.Mutex
+	pair    TokenPair
+	pending *refreshCall
+}
+
+type refreshCall struct {
+	done chan struct{}
+	pair TokenPair
+	err  error
+}
+
+type StoreOption func(*TokenStore)
+
+// WithOnRotate вызывается после каждого обновления, например чтобы сохранить новую пару.
+// Вызывается под блокировкой хранилища и не должен обращаться к нему
+func WithOnRotate(fn func(TokenPair)) StoreOption {
+	return func(s *TokenStore) {
+		s.onRotate = fn
+	}
+}
+
+// WithRefreshTimeout ограничивает обновление пары. Без срока зависший сервер держал бы
+// все запросы с 401, а следующие обновления не начинались бы
+func WithRefreshTimeout(timeout time.Duration) StoreOption {
+	return func(s *TokenStore) {
+		s.refreshTimeout = timeout
+	}
+}
+
+func NewTokenStore(client *Client, pair TokenPair, opts ...StoreOption) *TokenStore {
+	s := &TokenStore{client: client, pair: pair, refreshTimeout: DefaultRefreshTimeout}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *TokenStore) Token() TokenPair {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pair
+}
+
+// Set заменяет пару, например после нового входа
+func (s *TokenStore) Set(pair TokenPair) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pair = pair
+}
+
+// Refresh обновляет пару stale, с которой запрос получил 401. Если пара уже сменилась, возвращается текущая
+// без обращения к серверу. Само обновление не прерывается отменой ctx: иначе сервер мог бы успеть
+// ротировать пару, а клиент — потерять новую. Отмена ctx прерывает только ожидание, обновление
+// ограничено WithRefreshTimeout
+func (s *TokenStore) Refresh(ctx context.Context, stale TokenPair) (TokenPair, error) {
+	s.mu.Lock()
+	if s.pair != stale {
+		pair := s.pair
+		s.mu.Unlock()
+		return pair, nil
+	}
+	call := s.pending
+	if call == nil {
+		call = &refreshCall{done: make(chan struct{})}
+		s.pending = call
+		go s.refresh(context.WithoutCancel(ctx), stale, call)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.pair, call.err
+	case <-ctx.Done():
+		return TokenPair{}, ctx.Err()
+	}
+}
+
+func (s *TokenStore) refresh(ctx context.Context, stale TokenPair, call *refreshCall) {
+	ctx, cancel := context.WithTimeout(ctx, s.refreshTimeout)
+	defer cancel()
+	pair, err := s.client.Refresh(ctx, stale)
+
+	s.mu.Lock()
+	if err == nil {
+		s.pair = pair
+		if s.onRotate != nil {
+			s.onRotate(pair)
+		}
+	}
+	s.pending = nil
+	s.mu.Unlock()
+
+	call.pair, call.err = pair, err
+	close(call.done)
+}
+
+// Logout завершает сессию текущей пары
+func (s *TokenStore) Logout(ctx context.Context) error {
+	return s.client.Logout(ctx, s.Token())
+}
@@ -0,0 +1,161 @@
+package authclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"medods_test/pkg/authclient"
+)
+
+var (
+	stalePair = authclient.TokenPair{AccessToken: "stale-access", RefreshToken: "stale-refresh"}
+	freshPair = authclient.TokenPair{AccessToken: "fresh-access", RefreshToken: "fresh-refresh"}
+)
+
+// authServer /v1/auth/refresh отдаёт freshPair, когда release закрыт, /api принимает только freshPair
+type authServer struct {
+	*httptest.Server
+	refreshes    atomic.Int32
+	unauthorized atomic.Int32
+	release      chan struct{}
+}
+
+func newAuthServer(t *testing.T) *authServer {
+	t.Helper()
+	s := &authServer{release: make(chan struct{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		s.refreshes.Add(1)
+		select {
+		case <-s.release:
+		case <-r.Context().Done():
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token":  freshPair.AccessToken,
+			"refresh_token": freshPair.RefreshToken,
+		})
+	})
+	mux.HandleFunc("GET /api", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+freshPair.AccessToken {
+			s.unauthorized.Add(1)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestConcurrentUnauthorizedRequestsRefreshOnce(t *testing.T) {
+	server := newAuthServer(t)
+	var rotations atomic.Int32
+	store := authclient.NewTokenStore(authclient.New(server.URL), stalePair,
+		authclient.WithOnRotate(func(authclient.TokenPair) { rotations.Add(1) }))
+	api := authclient.NewHTTPClient(store, nil)
+
+	const requests = 8
+	var wg sync.WaitGroup
+	statuses := make([]int, requests)
+	errs := make([]error, requests)
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := api.Get(server.URL + "/api")
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}()
+	}
+	// обновление отвечает, только когда все запросы получили 401 со старой парой
+	deadline := time.Now().Add(5 * time.Second)
+	for server.unauthorized.Load() < requests {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d requests got 401", server.unauthorized.Load(), requests)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(server.release)
+	wg.Wait()
+
+	for i := range requests {
+		if errs[i] != nil || statuses[i] != http.StatusOK {
+			t.Fatalf("request %d: status %d, err %v; want 200 after refresh", i, statuses[i], errs[i])
+		}
+	}
+	if n := server.refreshes.Load(); n != 1 {
+		t.Fatalf("refresh calls = %d, want 1", n)
+	}
+	if n := rotations.Load(); n != 1 {
+		t.Fatalf("rotations = %d, want 1", n)
+	}
+	if got := store.Token(); got != freshPair {
+		t.Fatalf("store pair = %+v, want fresh pair", got)
+	}
+}
+
+func TestRefreshIsBoundedByTimeout(t *testing.T) {
+	server := newAuthServer(t)
+	store := authclient.NewTokenStore(authclient.New(server.URL), stalePair, authclient.WithRefreshTimeout(20*time.Millisecond))
+
+	// ctx вызывающего без срока: обновление прерывает только WithRefreshTimeout
+	_, err := store.Refresh(context.Background(), stalePair)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Refresh: err = %v, want DeadlineExceeded", err)
+	}
+	if got := store.Token(); got != stalePair {
+		t.Fatalf("store pair = %+v, want unchanged after failed refresh", got)
+	}
+
+	// зависшее обновление не блокирует следующее
+	close(server.release)
+	pair, err := store.Refresh(context.Background(), stalePair)
+	if err != nil || pair != freshPair {
+		t.Fatalf("second Refresh = %+v, %v; want fresh pair", pair, err)
+	}
+	if n := server.refreshes.Load(); n != 2 {
+		t.Fatalf("refresh calls = %d, want 2", n)
+	}
+}
+
+func TestRefreshSurvivesCallerCancellation(t *testing.T) {
+	server := newAuthServer(t)
+	store := authclient.NewTokenStore(authclient.New(server.URL), stalePair)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := store.Refresh(ctx, stalePair)
+		done <- err
+	}()
+	for server.refreshes.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Refresh: err = %v, want Canceled", err)
+	}
+
+	// сервер уже ротировал пару, клиент её не теряет
+	close(server.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for store.Token() != freshPair {
+		if time.Now().After(deadline) {
+			t.Fatal("store did not receive the rotated pair after the caller was canceled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
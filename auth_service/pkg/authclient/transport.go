@@ -0,0 +1,67 @@
+package authclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Transport добавляет access токен из Store в запросы и при ответе 401 один раз обновляет пару и повторяет запрос.
+// Запрос с телом повторяется, только если у него есть GetBody (его заполняет http.NewRequest для типовых тел)
+type Transport struct {
+	Store *TokenStore
+	// Base по умолчанию http.DefaultTransport
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	pair := t.Store.Token()
+	resp, err := t.base().RoundTrip(withToken(req, pair.AccessToken))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	fresh, err := t.Store.Refresh(req.Context(), pair)
+	if err != nil {
+		// исходный 401 понятнее вызывающему коду, чем ошибка обновления, если сессия уже завершена
+		if _, ok := err.(*Error); ok {
+			return resp, nil
+		}
+		resp.Body.Close()
+		return nil, fmt.Errorf("auth: failed to refresh token pair: %w", err)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+	resp.Body.Close()
+
+	retry := withToken(req, fresh.AccessToken)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return t.base().RoundTrip(retry)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// withToken RoundTripper не должен менять исходный запрос
+func withToken(req *http.Request, accessToken string) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Authorization", "Bearer "+accessToken)
+	return clone
+}
+
+// NewHTTPClient клиент для вызова других сервисов от имени пары из store
+func NewHTTPClient(store *TokenStore, base http.RoundTripper) *http.Client {
+	return &http.Client{Transport: &Transport{Store: store, Base: base}}
+}
@@ -0,0 +1,308 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Handler обработчик события. Ошибка означает, что доставку нужно повторить
+type Handler[T any] func(ctx context.Context, event T) error
+
+// DeadLetter событие, которое подписчик не смог обработать за все попытки
+type DeadLetter[T any] struct {
+	Subscriber string
+	Event      T
+	// Attempts сколько раз вызывался обработчик. 0, если шина закрылась раньше, чем событие дошло до него
+	Attempts int
+	Err      error
+}
+
+type Options[T any] struct {
+	// Workers число горутин, обрабатывающих события всех подписчиков
+	Workers int
+	// QueueSize ёмкость очереди. Каждое событие занимает в ней по месту на подписчика
+	QueueSize int
+	// PublishTimeout сколько Publish ждёт места в заполненной очереди, прежде чем отбросить событие.
+	// Нулевой означает отбрасывать сразу, чтобы медленный подписчик не тормозил запросы
+	PublishTimeout time.Duration
+	// MaxAttempts попыток на подписчика, включая первую
+	MaxAttempts int
+	// RetryBase и RetryMax границы экспоненциальной задержки между попытками, задержка выбирается случайно от нуля до границы
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// OnDrop вызывается для события, не поместившегося в очередь или опубликованного после Close
+	OnDrop func(subscriber string, event T)
+	// OnDeadLetter вызывается, когда подписчик исчерпал попытки
+	OnDeadLetter func(letter DeadLetter[T])
+}
+
+type Stats struct {
+	Published  uint64
+	Delivered  uint64
+	Retried    uint64
+	Failed     uint64
+	Dropped    uint64
+	QueueDepth int
+}
+
+var ErrBusClosed = errors.New("event bus is closed")
+
+type job[T any] struct {
+	// ctx контекст издателя без отмены: запрос обычно завершается раньше, чем событие обработано
+	ctx        context.Context
+	subscriber subscriber[T]
+	event      T
+}
+
+type subscriber[T any] struct {
+	name    string
+	match   func(event T) bool
+	handler Handler[T]
+}
+
+// Subscriber модуль, который сам подписывает свои обработчики на шину
+type Subscriber[T any] interface {
+	Subscribe(bus *Bus[T])
+}
+
+// Bus шина с ограниченным пулом обработчиков. Каждый подписчик получает событие независимо:
+// ошибка одного не влияет на доставку остальным, неудачи повторяются с задержкой, а исчерпавшие
+// попытки уходят в OnDeadLetter
+type Bus[T any] struct {
+	opts Options[T]
+
+	mu          sync.RWMutex
+	subscribers []subscriber[T]
+	closed      bool
+
+	queue   chan job[T]
+	workers sync.WaitGroup
+	// ctx отменяется, если Close не дождался очереди, чтобы прервать обработчики и задержки
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	published atomic.Uint64
+	delivered atomic.Uint64
+	retried   atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func NewBus[T any](opts Options[T]) *Bus[T] {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize < 0 {
+		opts.QueueSize = 0
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.RetryBase <= 0 {
+		opts.RetryBase = 100 * time.Millisecond
+	}
+	if opts.RetryMax < opts.RetryBase {
+		opts.RetryMax = opts.RetryBase
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Bus[T]{
+		opts:   opts,
+		queue:  make(chan job[T], opts.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	b.workers.Add(opts.Workers)
+	for range opts.Workers {
+		go b.work()
+	}
+	return b
+}
+
+// Subscribe добавляет подписчика на все события, name попадает в DeadLetter и метрики
+func (b *Bus[T]) Subscribe(name string, handler Handler[T]) {
+	b.subscribe(subscriber[T]{name: name, handler: handler})
+}
+
+// On подписывает обработчик на события типа E из шины интерфейсов T.
+// События других типов не ставятся в очередь этого подписчика
+func On[E any, T any](b *Bus[T], name string, handler Handler[E]) {
+	b.subscribe(subscriber[T]{
+		name: name,
+		match: func(event T) bool {
+			_, ok := any(event).(E)
+			return ok
+		},
+		handler: func(ctx context.Context, event T) error {
+			return handler(ctx, any(event).(E))
+		},
+	})
+}
+
+func (b *Bus[T]) subscribe(s subscriber[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, s)
+}
+
+// Publish ставит событие в очередь для каждого подписчика. Если очередь заполнена дольше PublishTimeout
+// или шина закрыта, событие для подписчика отбрасывается и передаётся в OnDrop.
+// Обработчики получают значения ctx (трассу, логгер запроса), а отменяются только вместе с шиной
+func (b *Bus[T]) Publish(ctx context.Context, event T) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	b.published.Add(1)
+	for _, s := range b.subscribers {
+		if s.match != nil && !s.match(event) {
+			continue
+		}
+		if b.closed || !b.enqueue(job[T]{ctx: context.WithoutCancel(ctx), subscriber: s, event: event}) {
+			b.drop(s.name, event)
+		}
+	}
+}
+
+func (b *Bus[T]) enqueue(j job[T]) bool {
+	select {
+	case b.queue <- j:
+		return true
+	default:
+	}
+	if b.opts.PublishTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(b.opts.PublishTimeout)
+	defer timer.Stop()
+	select {
+	case b.queue <- j:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (b *Bus[T]) drop(subscriber string, event T) {
+	b.dropped.Add(1)
+	if b.opts.OnDrop != nil {
+		b.opts.OnDrop(subscriber, event)
+	}
+}
+
+func (b *Bus[T]) work() {
+	defer b.workers.Done()
+	for j := range b.queue {
+		b.deliver(j)
+	}
+}
+
+func (b *Bus[T]) deliver(j job[T]) {
+	ctx, cancel := context.WithCancel(j.ctx)
+	defer cancel()
+	stop := context.AfterFunc(b.ctx, cancel)
+	defer stop()
+
+	var err error
+	attempts := 0
+	for {
+		if err = b.stopped(ctx); err != nil {
+			break
+		}
+		attempts++
+		if err = j.subscriber.handler(ctx, j.event); err == nil {
+			b.delivered.Add(1)
+			return
+		}
+		// прерванный закрытием шины обработчик не повторяется
+		if attempts >= b.opts.MaxAttempts || b.stopped(ctx) != nil {
+			break
+		}
+		b.retried.Add(1)
+		select {
+		case <-time.After(b.backoff(attempts)):
+		case <-ctx.Done():
+		}
+	}
+
+	b.failed.Add(1)
+	if b.opts.OnDeadLetter != nil {
+		b.opts.OnDeadLetter(DeadLetter[T]{
+			Subscriber: j.subscriber.name,
+			Event:      j.event,
+			Attempts:   attempts,
+			Err:        err,
+		})
+	}
+}
+
+// stopped ошибка ctx обработки или самой шины: AfterFunc отменяет ctx асинхронно,
+// и без проверки b.ctx событие, взятое из очереди сразу после отмены, успело бы начать обработку
+func (b *Bus[T]) stopped(ctx context.Context) error {
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// backoff full jitter: случайная задержка до RetryBase*2^(attempt-1), но не больше RetryMax
+func (b *Bus[T]) backoff(attempt int) time.Duration {
+	limit := b.opts.RetryMax
+	if shift := attempt - 1; shift < 32 {
+		if d := b.opts.RetryBase << shift; d > 0 && d < limit {
+			limit = d
+		}
+	}
+	return rand.N(limit) + 1
+}
+
+// Close перестаёт принимать события и ждёт, пока очередь будет обработана.
+// Если ctx истёк раньше, обработчики и задержки прерываются, а недоставленные события уходят в OnDeadLetter
+func (b *Bus[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBusClosed
+	}
+	b.closed = true
+	close(b.queue)
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		b.cancel()
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Closed сообщает, что шина уже не принимает события
+func (b *Bus[T]) Closed() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.closed
+}
+
+func (b *Bus[T]) Stats() Stats {
+	return Stats{
+		Published:  b.published.Load(),
+		Delivered:  b.delivered.Load(),
+		Retried:    b.retried.Load(),
+		Failed:     b.failed.Load(),
+		Dropped:    b.dropped.Load(),
+		QueueDepth: len(b.queue),
+	}
+}
@@ -0,0 +1,284 @@
+package eventbus_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"medods_test/pkg/eventbus"
+)
+
+const subscriberName = "test"
+
+var errHandler = errors.New("handler failed")
+
+// recorder собирает события из OnDrop и OnDeadLetter, которые вызываются из разных горутин
+type recorder struct {
+	mu      sync.Mutex
+	dropped []int
+	letters []eventbus.DeadLetter[int]
+}
+
+func (r *recorder) options(opts eventbus.Options[int]) eventbus.Options[int] {
+	opts.OnDrop = func(subscriber string, event int) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if subscriber == subscriberName {
+			r.dropped = append(r.dropped, event)
+		}
+	}
+	opts.OnDeadLetter = func(letter eventbus.DeadLetter[int]) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.letters = append(r.letters, letter)
+	}
+	return opts
+}
+
+func (r *recorder) droppedEvents() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.dropped...)
+}
+
+func (r *recorder) deadLetters() []eventbus.DeadLetter[int] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]eventbus.DeadLetter[int](nil), r.letters...)
+}
+
+// blockingHandler держит событие, пока тест не закроет release или не отменится контекст обработчика
+type blockingHandler struct {
+	started  chan int
+	release  chan struct{}
+	canceled chan int
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{started: make(chan int, 16), release: make(chan struct{}), canceled: make(chan int, 16)}
+}
+
+func (h *blockingHandler) handle(ctx context.Context, event int) error {
+	h.started <- event
+	select {
+	case <-h.release:
+		return nil
+	case <-ctx.Done():
+		h.canceled <- event
+		return ctx.Err()
+	}
+}
+
+func waitFor(t *testing.T, ch <-chan int, what string) int {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		return 0
+	}
+}
+
+func TestPublishDropsWhenQueueIsFull(t *testing.T) {
+	rec := &recorder{}
+	bus := eventbus.NewBus(rec.options(eventbus.Options[int]{Workers: 1, QueueSize: 1}))
+	handler := newBlockingHandler()
+	bus.Subscribe(subscriberName, handler.handle)
+
+	bus.Publish(context.Background(), 1)
+	waitFor(t, handler.started, "first event")
+	// первое событие у обработчика, второе занимает очередь, третьему места нет
+	bus.Publish(context.Background(), 2)
+	bus.Publish(context.Background(), 3)
+
+	if got := rec.droppedEvents(); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("dropped = %v, want [3]", got)
+	}
+	stats := bus.Stats()
+	if stats.Published != 3 || stats.Dropped != 1 || stats.QueueDepth != 1 {
+		t.Fatalf("stats = %+v, want 3 published, 1 dropped, 1 queued", stats)
+	}
+
+	close(handler.release)
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if stats := bus.Stats(); stats.Delivered != 2 || stats.QueueDepth != 0 {
+		t.Fatalf("stats after Close = %+v, want 2 delivered", stats)
+	}
+}
+
+func TestPublishWaitsForQueueUpToTimeout(t *testing.T) {
+	rec := &recorder{}
+	bus := eventbus.NewBus(rec.options(eventbus.Options[int]{Workers: 1, QueueSize: 1, PublishTimeout: 20 * time.Millisecond}))
+	handler := newBlockingHandler()
+	bus.Subscribe(subscriberName, handler.handle)
+	defer bus.Close(context.Background())
+	defer close(handler.release)
+
+	bus.Publish(context.Background(), 1)
+	waitFor(t, handler.started, "first event")
+	bus.Publish(context.Background(), 2)
+
+	started := time.Now()
+	bus.Publish(context.Background(), 3)
+	if waited := time.Since(started); waited < 20*time.Millisecond {
+		t.Fatalf("Publish returned after %v, want to wait PublishTimeout", waited)
+	}
+	if got := rec.droppedEvents(); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("dropped = %v, want [3]", got)
+	}
+}
+
+func TestFailingHandlerIsRetriedThenDeadLettered(t *testing.T) {
+	rec := &recorder{}
+	bus := eventbus.NewBus(rec.options(eventbus.Options[int]{
+		Workers: 1, QueueSize: 4, MaxAttempts: 3, RetryBase: time.Millisecond, RetryMax: 5 * time.Millisecond,
+	}))
+	var mu sync.Mutex
+	calls := 0
+	bus.Subscribe(subscriberName, func(context.Context, int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return errHandler
+	})
+	// второй подписчик получает событие независимо от неудач первого
+	delivered := make(chan int, 1)
+	bus.Subscribe("healthy", func(_ context.Context, event int) error {
+		delivered <- event
+		return nil
+	})
+
+	bus.Publish(context.Background(), 42)
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("handler calls = %d, want MaxAttempts", calls)
+	}
+	if got := waitFor(t, delivered, "healthy subscriber"); got != 42 {
+		t.Fatalf("healthy subscriber got %d, want 42", got)
+	}
+	letters := rec.deadLetters()
+	if len(letters) != 1 {
+		t.Fatalf("dead letters = %+v, want 1", letters)
+	}
+	letter := letters[0]
+	if letter.Subscriber != subscriberName || letter.Event != 42 || letter.Attempts != 3 || !errors.Is(letter.Err, errHandler) {
+		t.Fatalf("dead letter = %+v, want subscriber %q, event 42, 3 attempts and the handler error", letter, subscriberName)
+	}
+	stats := bus.Stats()
+	if stats.Retried != 2 || stats.Failed != 1 || stats.Delivered != 1 {
+		t.Fatalf("stats = %+v, want 2 retries, 1 failed, 1 delivered", stats)
+	}
+}
+
+func TestRetrySucceedsBeforeMaxAttempts(t *testing.T) {
+	rec := &recorder{}
+	bus := eventbus.NewBus(rec.options(eventbus.Options[int]{
+		Workers: 1, QueueSize: 4, MaxAttempts: 3, RetryBase: time.Millisecond, RetryMax: 5 * time.Millisecond,
+	}))
+	calls := 0
+	bus.Subscribe(subscriberName, func(context.Context, int) error {
+		calls++
+		if calls < 2 {
+			return errHandler
+		}
+		return nil
+	})
+
+	bus.Publish(context.Background(), 1)
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if letters := rec.deadLetters(); len(letters) != 0 {
+		t.Fatalf("dead letters = %+v, want none", letters)
+	}
+	if stats := bus.Stats(); stats.Delivered != 1 || stats.Retried != 1 || stats.Failed != 0 {
+		t.Fatalf("stats = %+v, want 1 delivered after 1 retry", stats)
+	}
+}
+
+func TestCloseDrainsQueue(t *testing.T) {
+	rec := &recorder{}
+	bus := eventbus.NewBus(rec.options(eventbus.Options[int]{Workers: 2, QueueSize: 10}))
+	handler := newBlockingHandler()
+	bus.Subscribe(subscriberName, handler.handle)
+
+	for i := range 5 {
+		bus.Publish(context.Background(), i)
+	}
+	closed := make(chan error, 1)
+	go func() { closed <- bus.Close(context.Background()) }()
+
+	waitFor(t, handler.started, "first event")
+	close(handler.release)
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return after the queue was processed")
+	}
+
+	if stats := bus.Stats(); stats.Delivered != 5 || stats.Failed != 0 {
+		t.Fatalf("stats = %+v, want all 5 delivered", stats)
+	}
+	if !bus.Closed() {
+		t.Fatal("Closed() = false after Close")
+	}
+
+	bus.Publish(context.Background(), 6)
+	if got := rec.droppedEvents(); len(got) != 1 || got[0] != 6 {
+		t.Fatalf("dropped after Close = %v, want [6]", got)
+	}
+	if err := bus.Close(context.Background()); !errors.Is(err, eventbus.ErrBusClosed) {
+		t.Fatalf("second Close: err = %v, want ErrBusClosed", err)
+	}
+}
+
+func TestCloseCancelsHandlersOnDeadline(t *testing.T) {
+	rec := &recorder{}
+	bus := eventbus.NewBus(rec.options(eventbus.Options[int]{Workers: 1, QueueSize: 10, MaxAttempts: 5}))
+	handler := newBlockingHandler()
+	bus.Subscribe(subscriberName, handler.handle)
+
+	for i := range 3 {
+		bus.Publish(context.Background(), i)
+	}
+	waitFor(t, handler.started, "first event")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close: err = %v, want DeadlineExceeded", err)
+	}
+
+	// обработчик прерван, а события из очереди не запускались и сразу ушли в dead letter
+	if got := waitFor(t, handler.canceled, "handler cancellation"); got != 0 {
+		t.Fatalf("canceled event = %d, want 0", got)
+	}
+	letters := rec.deadLetters()
+	if len(letters) != 3 {
+		t.Fatalf("dead letters = %+v, want all 3 events", letters)
+	}
+	for _, letter := range letters {
+		want := 0
+		if letter.Event == 0 {
+			want = 1
+		}
+		if letter.Attempts != want || !errors.Is(letter.Err, context.Canceled) {
+			t.Fatalf("dead letter = %+v, want %d attempts canceled without retries", letter, want)
+		}
+	}
+	if stats := bus.Stats(); stats.Failed != 3 || stats.Delivered != 0 || stats.Retried != 0 {
+		t.Fatalf("stats = %+v, want 3 failed", stats)
+	}
+}
@@ -1,3 +1,4 @@
+// Package httperror ответы об ошибках в формате RFC 7807 (application/problem+json)
 package httperror
 
 import (
@@ -5,12 +6,52 @@ import (
 	"net/http"
 )
 
-type ErrorResponse struct {
-	Error string `json:"error" example:"bad request"`
+const ContentType = "application/problem+json"
+
+// Общие коды ошибок. Коды стабильны, клиенты должны опираться на code, а не на title и detail
+const (
+	CodeBadRequest           = "bad_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeConflict             = "conflict"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeBadGateway           = "bad_gateway"
+	CodeInternal             = "internal_error"
+)
+
+// Problem тело ответа об ошибке. Type всегда about:blank, смысл ошибки передаёт расширение code
+type Problem struct {
+	Type     string `json:"type" example:"about:blank"`
+	Title    string `json:"title" example:"Unauthorized"`
+	Status   int    `json:"status" example:"401"`
+	Code     string `json:"code" example:"token_expired"`
+	Detail   string `json:"detail,omitempty" example:"access token expired"`
+	Instance string `json:"instance,omitempty" example:"/v1/auth/logout"`
+}
+
+func New(r *http.Request, status int, code string, detail string) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Code:     code,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+// Write отвечает Problem с указанным статусом и кодом
+func Write(w http.ResponseWriter, r *http.Request, status int, code string, detail string) {
+	WriteProblem(w, status, New(r, status, code, detail))
 }
 
-func WriteJSONError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json")
+// WriteProblem пишет тело problem+json. body — Problem или структура, встраивающая Problem с дополнительными полями
+func WriteProblem(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", ContentType)
 	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+	_ = json.NewEncoder(w).Encode(body)
 }
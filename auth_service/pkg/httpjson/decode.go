@@ -0,0 +1,106 @@
+// Package httpjson чтение JSON тела запроса с ограничением размера, проверкой Content-Type и полей
+package httpjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"medods_test/pkg/httperror"
+	"medods_test/pkg/validate"
+)
+
+// DefaultMaxBytes ограничение тела по умолчанию, запросы API намного меньше
+const DefaultMaxBytes int64 = 1 << 20
+
+var (
+	ErrUnsupportedMediaType = errors.New("content type must be application/json")
+	ErrBodyTooLarge         = errors.New("request body too large")
+	ErrInvalidJSON          = errors.New("request body is not valid JSON")
+)
+
+// Validatable запрос, который проверяет свои поля. Ошибка validate.Errors попадает в ответ по полям
+type Validatable interface {
+	Validate() error
+}
+
+// ValidationProblem ответ 400 validation_failed со списком ошибок полей
+type ValidationProblem struct {
+	httperror.Problem
+	Errors validate.Errors `json:"errors"`
+}
+
+// Decode читает тело не больше maxBytes (0 — DefaultMaxBytes) в T. Content-Type должен быть application/json
+// или с суффиксом +json, неизвестные поля отклоняются. Если T реализует Validatable, после чтения вызывается Validate
+func Decode[T any](w http.ResponseWriter, r *http.Request, maxBytes int64) (T, error) {
+	var v T
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/json" && !hasJSONSuffix(mediaType)) {
+		return v, ErrUnsupportedMediaType
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&v); err != nil {
+		return v, decodeError(err)
+	}
+	// после объекта допускаются только пробелы
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		if err == nil {
+			err = errors.New("unexpected data after JSON value")
+		}
+		return v, decodeError(err)
+	}
+	if validatable, ok := any(v).(Validatable); ok {
+		return v, validatable.Validate()
+	}
+	return v, nil
+}
+
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return ErrBodyTooLarge
+	}
+	// ошибки конкретного поля отдаются так же, как ошибки Validate
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return validate.Errors{{Field: typeErr.Field, Code: validate.CodeInvalidType, Message: "must be " + typeErr.Type.String()}}
+	}
+	// у encoding/json нет отдельного типа для неизвестного поля
+	if field, ok := strings.CutPrefix(err.Error(), `json: unknown field "`); ok {
+		return validate.Errors{{Field: strings.TrimSuffix(field, `"`), Code: validate.CodeUnknownField, Message: "is not allowed"}}
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidJSON, err)
+}
+
+func hasJSONSuffix(mediaType string) bool {
+	return strings.HasSuffix(mediaType, "+json")
+}
+
+// WriteError отвечает problem+json на ошибку Decode: 415, 413 или 400, для ошибок полей — с их списком
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var fieldErrs validate.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		httperror.WriteProblem(w, http.StatusBadRequest, ValidationProblem{
+			Problem: httperror.New(r, http.StatusBadRequest, httperror.CodeValidationFailed, "request body has invalid fields"),
+			Errors:  fieldErrs,
+		})
+	case errors.Is(err, ErrUnsupportedMediaType):
+		httperror.Write(w, r, http.StatusUnsupportedMediaType, httperror.CodeUnsupportedMediaType, err.Error())
+	case errors.Is(err, ErrBodyTooLarge):
+		httperror.Write(w, r, http.StatusRequestEntityTooLarge, httperror.CodePayloadTooLarge, err.Error())
+	case errors.Is(err, ErrInvalidJSON):
+		httperror.Write(w, r, http.StatusBadRequest, httperror.CodeBadRequest, ErrInvalidJSON.Error())
+	default:
+		httperror.Write(w, r, http.StatusBadRequest, httperror.CodeBadRequest, err.Error())
+	}
+}
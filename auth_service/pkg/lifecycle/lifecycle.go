@@ -0,0 +1,90 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Hook компонент приложения. OnStart не должен блокироваться: долгую работу он запускает в горутине
+// и сообщает о её падении через Lifecycle.Fail. OnStop должен уложиться в срок ctx
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle запускает компоненты в порядке регистрации и останавливает в обратном
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started []Hook
+
+	failOnce sync.Once
+	failed   chan error
+}
+
+func New() *Lifecycle {
+	return &Lifecycle{failed: make(chan error, 1)}
+}
+
+func (l *Lifecycle) Append(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// Start запускает компоненты по очереди. Если один из них не запустился,
+// уже запущенные останавливаются и возвращается ошибка запуска
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := l.hooks
+	l.mu.Unlock()
+
+	for _, hook := range hooks {
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				startErr := fmt.Errorf("failed to start %s: %w", hook.Name, err)
+				return errors.Join(startErr, l.Stop(context.WithoutCancel(ctx)))
+			}
+		}
+		l.mu.Lock()
+		l.started = append(l.started, hook)
+		l.mu.Unlock()
+	}
+	return nil
+}
+
+// Stop останавливает запущенные компоненты в обратном порядке. Ошибка одного компонента
+// не прерывает остановку остальных, все ошибки возвращаются вместе
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	started := l.started
+	l.started = nil
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		hook := started[i]
+		if hook.OnStop == nil {
+			continue
+		}
+		if err := hook.OnStop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", hook.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Fail сообщает о падении компонента после запуска, учитывается только первая ошибка
+func (l *Lifecycle) Fail(err error) {
+	l.failOnce.Do(func() {
+		l.failed <- err
+	})
+}
+
+// Failed получает ошибку из Fail, после неё приложение нужно останавливать
+func (l *Lifecycle) Failed() <-chan error {
+	return l.failed
+}
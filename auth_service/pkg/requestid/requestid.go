@@ -0,0 +1,16 @@
+package requestid
+
+import "context"
+
+type contextKey struct{}
+
+// With кладёт идентификатор запроса в контекст, по нему связываются логи, события и ответы
+func With(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, requestID)
+}
+
+// FromContext возвращает пустую строку, если идентификатора нет
+func FromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(contextKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,114 @@
+// Package router регистрация маршрутов API поверх http.ServeMux: шаблоны с методом, префикс версии
+// и устаревшие пути без префикса
+package router
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"medods_test/pkg/httperror"
+)
+
+type Config struct {
+	// Prefix версия API, например /v1
+	Prefix string
+	// Legacy дублирует маршруты API без префикса с заголовками Deprecation и Link на новый путь
+	Legacy bool
+	// DeprecatedAt дата в заголовке Deprecation (RFC 9745)
+	DeprecatedAt time.Time
+	// Sunset дата отключения старых путей (RFC 8594), нулевая не отправляется
+	Sunset time.Time
+}
+
+// Router регистрирует маршруты только с методом. На остальные методы того же пути
+// отвечает 405 с заголовком Allow и телом problem+json
+type Router struct {
+	mux  *http.ServeMux
+	conf Config
+
+	mu      sync.RWMutex
+	methods map[string][]string
+}
+
+func New(mux *http.ServeMux, conf Config) *Router {
+	return &Router{mux: mux, conf: conf, methods: make(map[string][]string)}
+}
+
+// HandleFunc регистрирует маршрут API вида "POST /auth/token" под префиксом версии
+func (rt *Router) HandleFunc(pattern string, handler http.HandlerFunc) {
+	method, path := split(pattern)
+	rt.register(method, rt.conf.Prefix+path, handler)
+	if rt.conf.Legacy && rt.conf.Prefix != "" {
+		rt.register(method, path, rt.deprecated(handler))
+	}
+}
+
+// HandleRootFunc регистрирует маршрут вне версии API, например /.well-known/* или /healthz
+func (rt *Router) HandleRootFunc(pattern string, handler http.HandlerFunc) {
+	method, path := split(pattern)
+	rt.register(method, path, handler)
+}
+
+func (rt *Router) HandleRoot(pattern string, handler http.Handler) {
+	rt.HandleRootFunc(pattern, handler.ServeHTTP)
+}
+
+func (rt *Router) register(method, path string, handler http.HandlerFunc) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.mux.HandleFunc(method+" "+path, handler)
+	if _, ok := rt.methods[path]; !ok {
+		// шаблон без метода менее специфичен, поэтому срабатывает только для незарегистрированных методов
+		rt.mux.HandleFunc(path, rt.methodNotAllowed(path))
+	}
+	rt.methods[path] = append(rt.methods[path], method)
+}
+
+func (rt *Router) methodNotAllowed(path string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", rt.allow(path))
+		httperror.Write(w, r, http.StatusMethodNotAllowed, httperror.CodeMethodNotAllowed, "")
+	}
+}
+
+func (rt *Router) allow(path string) string {
+	rt.mu.RLock()
+	methods := slices.Clone(rt.methods[path])
+	rt.mu.RUnlock()
+	// шаблон GET в http.ServeMux принимает и HEAD
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	slices.Sort(methods)
+	return strings.Join(methods, ", ")
+}
+
+func (rt *Router) deprecated(handler http.HandlerFunc) http.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(rt.conf.DeprecatedAt.Unix(), 10)
+	var sunset string
+	if !rt.conf.Sunset.IsZero() {
+		sunset = rt.conf.Sunset.UTC().Format(http.TimeFormat)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", deprecation)
+		w.Header().Add("Link", "<"+rt.conf.Prefix+r.URL.Path+`>; rel="successor-version"`)
+		if sunset != "" {
+			w.Header().Set("Sunset", sunset)
+		}
+		handler(w, r)
+	}
+}
+
+// split разбирает "METHOD /path". Маршруты без метода не допускаются, чтобы 405 формировался автоматически
+func split(pattern string) (method string, path string) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok || method == "" || !strings.HasPrefix(path, "/") {
+		panic("router: pattern must be \"METHOD /path\": " + pattern)
+	}
+	return method, path
+}
@@ -0,0 +1,121 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"medods_test/pkg/router"
+)
+
+var (
+	deprecatedAt = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	sunset       = time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newRouter(conf router.Config) http.Handler {
+	mux := http.NewServeMux()
+	rt := router.New(mux, conf)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	rt.HandleFunc("GET /auth/sessions", ok)
+	rt.HandleFunc("DELETE /auth/sessions", ok)
+	rt.HandleFunc("POST /auth/token", ok)
+	rt.HandleFunc("DELETE /admin/api-keys/{id}", ok)
+	rt.HandleRootFunc("GET /healthz", ok)
+	return mux
+}
+
+func serve(handler http.Handler, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	handler := newRouter(router.Config{Prefix: "/v1", Legacy: true, DeprecatedAt: deprecatedAt})
+	tests := []struct {
+		name, method, path, allow string
+	}{
+		{"GET route allows HEAD", http.MethodPost, "/v1/auth/sessions", "DELETE, GET, HEAD"},
+		{"POST only route", http.MethodGet, "/v1/auth/token", "POST"},
+		{"path with wildcard", http.MethodGet, "/v1/admin/api-keys/123", "DELETE"},
+		{"legacy alias", http.MethodPut, "/auth/token", "POST"},
+		{"root route", http.MethodPost, "/healthz", "GET, HEAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(handler, tt.method, tt.path)
+			if rec.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status = %d, want 405", rec.Code)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.allow {
+				t.Fatalf("Allow = %q, want %q", got, tt.allow)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+				t.Fatalf("Content-Type = %q, want application/problem+json", got)
+			}
+		})
+	}
+
+	if rec := serve(handler, http.MethodHead, "/v1/auth/sessions"); rec.Code != http.StatusOK {
+		t.Fatalf("HEAD on GET route: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestLegacyAliasHeaders(t *testing.T) {
+	handler := newRouter(router.Config{Prefix: "/v1", Legacy: true, DeprecatedAt: deprecatedAt, Sunset: sunset})
+
+	rec := serve(handler, http.MethodDelete, "/admin/api-keys/123")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got, want := rec.Header().Get("Deprecation"), "@1792368000"; got != want {
+		t.Errorf("Deprecation = %q, want %q", got, want)
+	}
+	if got, want := rec.Header().Get("Link"), `</v1/admin/api-keys/123>; rel="successor-version"`; got != want {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+	if got, want := rec.Header().Get("Sunset"), "Thu, 01 Apr 2027 00:00:00 GMT"; got != want {
+		t.Errorf("Sunset = %q, want %q", got, want)
+	}
+}
+
+func TestVersionedRoutesAreNotDeprecated(t *testing.T) {
+	handler := newRouter(router.Config{Prefix: "/v1", Legacy: true, DeprecatedAt: deprecatedAt, Sunset: sunset})
+
+	for _, path := range []string{"/v1/auth/sessions", "/healthz"} {
+		rec := serve(handler, http.MethodGet, path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", path, rec.Code)
+		}
+		for _, header := range []string{"Deprecation", "Link", "Sunset"} {
+			if got := rec.Header().Get(header); got != "" {
+				t.Errorf("%s: %s = %q, want none", path, header, got)
+			}
+		}
+	}
+}
+
+func TestLegacyAliasesDisabled(t *testing.T) {
+	handler := newRouter(router.Config{Prefix: "/v1", DeprecatedAt: deprecatedAt})
+
+	if rec := serve(handler, http.MethodPost, "/auth/token"); rec.Code != http.StatusNotFound {
+		t.Fatalf("legacy path: status = %d, want 404", rec.Code)
+	}
+	if rec := serve(handler, http.MethodGet, "/auth/token/"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown path: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestSunsetOmittedWhenUnset(t *testing.T) {
+	handler := newRouter(router.Config{Prefix: "/v1", Legacy: true, DeprecatedAt: deprecatedAt})
+
+	rec := serve(handler, http.MethodPost, "/auth/token")
+	if rec.Header().Get("Deprecation") == "" {
+		t.Fatal("legacy alias without Deprecation header")
+	}
+	if got := rec.Header().Get("Sunset"); got != "" {
+		t.Fatalf("Sunset = %q, want none", got)
+	}
+}
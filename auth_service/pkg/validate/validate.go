@@ -0,0 +1,108 @@
+// Package validate проверка полей входящих запросов с ошибками по каждому полю
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Коды ошибок полей, стабильны как и коды httperror
+const (
+	CodeRequired     = "required"
+	CodeInvalidUUID  = "invalid_uuid"
+	CodeInvalidCode  = "invalid_code"
+	CodeTooLong      = "too_long"
+	CodeTooMany      = "too_many"
+	CodeOutOfRange   = "out_of_range"
+	CodeInvalidType  = "invalid_type"
+	CodeUnknownField = "unknown_field"
+)
+
+// FieldError ошибка одного поля. Field — имя поля в JSON, вложенные через точку
+type FieldError struct {
+	Field   string `json:"field" example:"user_id"`
+	Code    string `json:"code" example:"invalid_uuid"`
+	Message string `json:"message" example:"must be a UUID"`
+}
+
+// Errors ошибки всех полей запроса
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		parts = append(parts, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Validator копит ошибки полей, чтобы клиент получил их все за один ответ
+type Validator struct {
+	errs Errors
+}
+
+func (v *Validator) Add(field, code, message string) {
+	v.errs = append(v.errs, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Required value не пустое
+func (v *Validator) Required(field, value string) bool {
+	if value == "" {
+		v.Add(field, CodeRequired, "is required")
+		return false
+	}
+	return true
+}
+
+// UUID обязательное значение в каноническом виде xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx,
+// другие формы uuid.Parse Postgres принимает не все
+func (v *Validator) UUID(field, value string) {
+	if !v.Required(field, value) {
+		return
+	}
+	if len(value) != 36 || uuid.Validate(value) != nil {
+		v.Add(field, CodeInvalidUUID, "must be a UUID")
+	}
+}
+
+// MaxLength не больше max символов
+func (v *Validator) MaxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.Add(field, CodeTooLong, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+// Digits обязательный код ровно из n цифр
+func (v *Validator) Digits(field, value string, n int) {
+	if !v.Required(field, value) {
+		return
+	}
+	if len(value) != n || strings.Trim(value, "0123456789") != "" {
+		v.Add(field, CodeInvalidCode, fmt.Sprintf("must be %d digits", n))
+	}
+}
+
+// List не больше maxItems непустых элементов не длиннее maxLength. Ошибки элементов получают поле вида scopes.0
+func (v *Validator) List(field string, values []string, maxItems, maxLength int) {
+	if len(values) > maxItems {
+		v.Add(field, CodeTooMany, fmt.Sprintf("must have at most %d items", maxItems))
+		return
+	}
+	for i, value := range values {
+		item := fmt.Sprintf("%s.%d", field, i)
+		if v.Required(item, value) {
+			v.MaxLength(item, value, maxLength)
+		}
+	}
+}
+
+// Err Errors или nil, если ошибок нет
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
@@ -0,0 +1,39 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor проверяет метаданные authorization: Bearer <token> и кладёт Principal в контекст.
+// Методы из skip вызываются без проверки, например health
+func (v *Verifier) UnaryServerInterceptor(skip ...string) grpc.UnaryServerInterceptor {
+	skipped := make(map[string]bool, len(skip))
+	for _, method := range skip {
+		skipped[method] = true
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if skipped[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		var header string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				header = values[0]
+			}
+		}
+		p, err := v.Verify(ctx, bearerToken(header))
+		if err != nil {
+			if errors.Is(err, ErrUnavailable) {
+				return nil, status.Error(codes.Unavailable, "token verification unavailable")
+			}
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return handler(WithPrincipal(ctx, p), req)
+	}
+}
@@ -0,0 +1,108 @@
+package verifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IntrospectionConfig проверка отзыва через /oauth/introspect (RFC 7662). Нужен конфиденциальный OAuth клиент
+type IntrospectionConfig struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	// CacheTTL сколько помнить ответ для токена, по умолчанию 30 секунд. Столько отозванный токен может ещё приниматься
+	CacheTTL time.Duration
+}
+
+type introspector struct {
+	conf   IntrospectionConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]introspectResult
+}
+
+type introspectResult struct {
+	active  bool
+	expires time.Time
+}
+
+func newIntrospector(conf IntrospectionConfig, client *http.Client) *introspector {
+	if conf.CacheTTL <= 0 {
+		conf.CacheTTL = 30 * time.Second
+	}
+	return &introspector{conf: conf, client: client, cache: make(map[[sha256.Size]byte]introspectResult)}
+}
+
+// check ответы кешируются по хешу токена не дольше срока жизни токена
+func (i *introspector) check(ctx context.Context, token string, tokenExpires time.Time) error {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	i.mu.Lock()
+	cached, ok := i.cache[key]
+	i.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return activeError(cached.active)
+	}
+
+	active, err := i.request(ctx, token)
+	if err != nil {
+		return err
+	}
+	expires := now.Add(i.conf.CacheTTL)
+	if !tokenExpires.IsZero() && tokenExpires.Before(expires) {
+		expires = tokenExpires
+	}
+
+	i.mu.Lock()
+	for k, result := range i.cache {
+		if now.After(result.expires) {
+			delete(i.cache, k)
+		}
+	}
+	i.cache[key] = introspectResult{active: active, expires: expires}
+	i.mu.Unlock()
+	return activeError(active)
+}
+
+func activeError(active bool) error {
+	if !active {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+func (i *introspector) request(ctx context.Context, token string) (bool, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.conf.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(i.conf.ClientID), url.QueryEscape(i.conf.ClientSecret))
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("%w: introspection failed: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("%w: introspection responded with status %d", ErrUnavailable, resp.StatusCode)
+	}
+	var body struct {
+		Active bool `json:"active"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return false, fmt.Errorf("%w: failed to decode introspection response: %w", ErrUnavailable, err)
+	}
+	return body.Active, nil
+}
@@ -0,0 +1,155 @@
+package verifier
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// keySet кеш RSA ключей из JWKS. Одновременно идёт не больше одной загрузки
+type keySet struct {
+	url        string
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	// fetching закрывается по окончании текущей загрузки
+	fetching chan struct{}
+	fetchErr error
+}
+
+func newKeySet(url string, client *http.Client, ttl, minRefresh time.Duration) *keySet {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	if minRefresh <= 0 {
+		minRefresh = time.Minute
+	}
+	return &keySet{url: url, client: client, ttl: ttl, minRefresh: minRefresh}
+}
+
+// key ключ по kid. Устаревший набор или незнакомый kid вызывают загрузку,
+// но при недоступном JWKS продолжают работать уже загруженные ключи
+func (s *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	key, known := s.keys[kid]
+	age := time.Since(s.fetchedAt)
+	needFetch := s.keys == nil || age > s.ttl || (!known && age > s.minRefresh)
+	s.mu.Unlock()
+
+	if needFetch {
+		if err := s.refresh(ctx); err != nil && !known {
+			return nil, err
+		}
+		s.mu.Lock()
+		key, known = s.keys[kid]
+		s.mu.Unlock()
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+func (s *keySet) refresh(ctx context.Context) error {
+	s.mu.Lock()
+	fetching := s.fetching
+	if fetching == nil {
+		fetching = make(chan struct{})
+		s.fetching = fetching
+		go s.fetch(context.WithoutCancel(ctx), fetching)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-fetching:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetchErr
+}
+
+func (s *keySet) fetch(ctx context.Context, done chan struct{}) {
+	keys, err := s.load(ctx)
+
+	s.mu.Lock()
+	// неудачная попытка тоже сдвигает fetchedAt, чтобы недоступный JWKS не запрашивался на каждый токен
+	s.fetchedAt = time.Now()
+	s.fetchErr = err
+	if err == nil {
+		s.keys = keys
+	}
+	s.fetching = nil
+	s.mu.Unlock()
+	close(done)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (s *keySet) load(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to fetch JWKS: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: JWKS responded with status %d", ErrUnavailable, resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode JWKS: %w", ErrUnavailable, err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := rsaKey(k)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
@@ -0,0 +1,194 @@
+// Package verifier проверка access токенов сервиса авторизации в других сервисах без копирования его кода.
+// Токен проверяется общим секретом (HS512) или ключами из JWKS (RS256, JWT_ACCESS_ALG=RS256 на сервере),
+// затем проверяются exp, iss и aud и, если настроено, не отозван ли токен (через /oauth/introspect).
+//
+//	v, err := verifier.New(verifier.Config{
+//		JWKSURL:  "http://auth:8080/.well-known/jwks.json",
+//		Issuer:   "http://auth:8080",
+//		Audience: "orders",
+//	})
+//	mux.Handle("/orders", v.Middleware(ordersHandler))
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrTokenMissing = errors.New("token missing")
+	ErrTokenInvalid = errors.New("token invalid")
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenRevoked = errors.New("token revoked")
+	// ErrUnavailable не удалось получить ключи или ответ introspection, токен при этом может быть действителен
+	ErrUnavailable = errors.New("token verification unavailable")
+)
+
+// Principal владелец проверенного токена. UserID пустой у токенов client_credentials
+type Principal struct {
+	UserID      string
+	TokenPairID string
+	ClientID    string
+	Role        string
+	// Scopes nil, если в токене нет claim scope: такие токены дают права роли, их знает только сервис авторизации
+	Scopes        []string
+	AMR           []string
+	AuthTime      time.Time
+	MFAVerifiedAt time.Time
+	ExpiresAt     time.Time
+}
+
+// HasScopes все ли перечисленные scope выданы токену
+func (p Principal) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(p.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+type Config struct {
+	// Secret общий секрет HS512 (JWT_ACCESS_SECRET). Задаётся либо он, либо JWKSURL
+	Secret []byte
+	// JWKSURL адрес /.well-known/jwks.json для токенов RS256
+	JWKSURL string
+	// JWKSCacheTTL как долго ключи считаются свежими, по умолчанию час.
+	// Токен с незнакомым kid вызывает обновление раньше, но не чаще JWKSMinRefresh (по умолчанию минута)
+	JWKSCacheTTL   time.Duration
+	JWKSMinRefresh time.Duration
+	// Issuer ожидаемый iss, пустой не проверяется
+	Issuer string
+	// Audience ожидаемое значение в aud, пустое не проверяется
+	Audience string
+	// Leeway допуск расхождения часов при проверке exp
+	Leeway time.Duration
+	// Introspection проверка отзыва, nil выключает её
+	Introspection *IntrospectionConfig
+	// HTTPClient для JWKS и introspection, по умолчанию клиент с таймаутом 10 секунд
+	HTTPClient *http.Client
+}
+
+type Verifier struct {
+	conf       Config
+	keys       *keySet
+	introspect *introspector
+	parser     *jwt.Parser
+}
+
+func New(conf Config) (*Verifier, error) {
+	if (len(conf.Secret) == 0) == (conf.JWKSURL == "") {
+		return nil, errors.New("verifier: exactly one of Secret and JWKSURL must be set")
+	}
+	if conf.HTTPClient == nil {
+		conf.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired(), jwt.WithLeeway(conf.Leeway)}
+	if conf.Secret != nil {
+		opts = append(opts, jwt.WithValidMethods([]string{jwt.SigningMethodHS512.Alg()}))
+	} else {
+		opts = append(opts, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	}
+	if conf.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(conf.Issuer))
+	}
+	if conf.Audience != "" {
+		opts = append(opts, jwt.WithAudience(conf.Audience))
+	}
+
+	v := &Verifier{conf: conf, parser: jwt.NewParser(opts...)}
+	if conf.JWKSURL != "" {
+		v.keys = newKeySet(conf.JWKSURL, conf.HTTPClient, conf.JWKSCacheTTL, conf.JWKSMinRefresh)
+	}
+	if conf.Introspection != nil {
+		v.introspect = newIntrospector(*conf.Introspection, conf.HTTPClient)
+	}
+	return v, nil
+}
+
+// Verify проверяет токен без префикса Bearer. Ошибки сравниваются через errors.Is с ErrTokenInvalid,
+// ErrTokenExpired, ErrTokenRevoked и ErrUnavailable
+func (v *Verifier) Verify(ctx context.Context, token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, ErrTokenMissing
+	}
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		if v.keys == nil {
+			return v.conf.Secret, nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.key(ctx, kid)
+	})
+	switch {
+	case errors.Is(err, ErrUnavailable):
+		return Principal{}, err
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return Principal{}, ErrTokenExpired
+	case err != nil:
+		return Principal{}, fmt.Errorf("%w: %w", ErrTokenInvalid, err)
+	}
+	// HS512 токены без typ выпущены до появления промежуточных MFA токенов и считаются access.
+	// Ключом из JWKS подписываются и ID токены, поэтому для него typ обязателен
+	if typ, _ := claims["typ"].(string); typ != "access" && (typ != "" || v.keys != nil) {
+		return Principal{}, fmt.Errorf("%w: unexpected token type %q", ErrTokenInvalid, typ)
+	}
+
+	principal := principalFromClaims(claims)
+	if v.introspect != nil {
+		if err := v.introspect.check(ctx, token, principal.ExpiresAt); err != nil {
+			return Principal{}, err
+		}
+	}
+	return principal, nil
+}
+
+// VerifyFunc Verify в виде функции для кода, который не передаёт контекст
+func (v *Verifier) VerifyFunc() func(token string) (Principal, error) {
+	return func(token string) (Principal, error) {
+		return v.Verify(context.Background(), token)
+	}
+}
+
+func principalFromClaims(claims jwt.MapClaims) Principal {
+	p := Principal{
+		UserID:        stringClaim(claims, "user_id"),
+		TokenPairID:   stringClaim(claims, "token_pair_id"),
+		ClientID:      stringClaim(claims, "client_id"),
+		Role:          stringClaim(claims, "role"),
+		AuthTime:      timeClaim(claims, "auth_time"),
+		MFAVerifiedAt: timeClaim(claims, "mfa_at"),
+		ExpiresAt:     timeClaim(claims, "exp"),
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	}
+	if amr, ok := claims["amr"].([]any); ok {
+		for _, value := range amr {
+			if s, ok := value.(string); ok {
+				p.AMR = append(p.AMR, s)
+			}
+		}
+	}
+	return p
+}
+
+func stringClaim(claims jwt.MapClaims, key string) string {
+	s, _ := claims[key].(string)
+	return s
+}
+
+func timeClaim(claims jwt.MapClaims, key string) time.Time {
+	if v, ok := claims[key].(float64); ok {
+		return time.Unix(int64(v), 0)
+	}
+	return time.Time{}
+}
@@ -0,0 +1,295 @@
+package verifier_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"medods_test/pkg/verifier"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "orders"
+)
+
+// jwksServer отдаёт публичные части текущих ключей и считает загрузки
+type jwksServer struct {
+	*httptest.Server
+	fetches atomic.Int32
+	failing atomic.Bool
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PrivateKey
+}
+
+func newJWKSServer(t *testing.T, keys map[string]*rsa.PrivateKey) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		if s.failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		set := struct {
+			Keys []map[string]string `json:"keys"`
+		}{}
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, map[string]string{
+				"kty": "RSA",
+				"use": "sig",
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys map[string]*rsa.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key
+}
+
+// accessClaims claims действующего access токена, overrides заменяют или удаляют (nil) отдельные поля
+func accessClaims(overrides jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"typ":           "access",
+		"iss":           testIssuer,
+		"aud":           []string{testAudience},
+		"exp":           time.Now().Add(time.Minute).Unix(),
+		"user_id":       "123e4567-e89b-12d3-a456-426614174000",
+		"token_pair_id": "pair-1",
+		"scope":         "profile:read orders:write",
+	}
+	for name, value := range overrides {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func newJWKSVerifier(t *testing.T, url string, ttl, minRefresh time.Duration) *verifier.Verifier {
+	t.Helper()
+	v, err := verifier.New(verifier.Config{
+		JWKSURL:        url,
+		JWKSCacheTTL:   ttl,
+		JWKSMinRefresh: minRefresh,
+		Issuer:         testIssuer,
+		Audience:       testAudience,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return v
+}
+
+func TestVerifyJWKSToken(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PrivateKey{"k1": key})
+	v := newJWKSVerifier(t, server.URL, time.Hour, time.Hour)
+
+	token := signRS256(t, key, "k1", accessClaims(nil))
+	for range 3 {
+		p, err := v.Verify(context.Background(), token)
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if p.UserID != "123e4567-e89b-12d3-a456-426614174000" || p.TokenPairID != "pair-1" || !p.HasScopes("profile:read", "orders:write") {
+			t.Fatalf("principal = %+v", p)
+		}
+	}
+	if n := server.fetches.Load(); n != 1 {
+		t.Fatalf("JWKS fetches = %d, want 1 while the cache is fresh", n)
+	}
+}
+
+func TestVerifyRejectsTokens(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PrivateKey{"k1": key})
+	v := newJWKSVerifier(t, server.URL, time.Hour, time.Hour)
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"expired", signRS256(t, key, "k1", accessClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})), verifier.ErrTokenExpired},
+		{"without exp", signRS256(t, key, "k1", accessClaims(jwt.MapClaims{"exp": nil})), verifier.ErrTokenInvalid},
+		{"foreign issuer", signRS256(t, key, "k1", accessClaims(jwt.MapClaims{"iss": "https://evil.example.com"})), verifier.ErrTokenInvalid},
+		{"foreign audience", signRS256(t, key, "k1", accessClaims(jwt.MapClaims{"aud": []string{"billing"}})), verifier.ErrTokenInvalid},
+		{"id token", signRS256(t, key, "k1", accessClaims(jwt.MapClaims{"typ": "id"})), verifier.ErrTokenInvalid},
+		{"untyped token", signRS256(t, key, "k1", accessClaims(jwt.MapClaims{"typ": nil})), verifier.ErrTokenInvalid},
+		{"mfa pending token", signRS256(t, key, "k1", accessClaims(jwt.MapClaims{"typ": "mfa_pending"})), verifier.ErrTokenInvalid},
+		{"foreign key", signRS256(t, newRSAKey(t), "k1", accessClaims(nil)), verifier.ErrTokenInvalid},
+		{"malformed", "not-a-jwt", verifier.ErrTokenInvalid},
+		{"empty", "", verifier.ErrTokenMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify: err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyHS512AcceptsUntypedAccessTokens(t *testing.T) {
+	secret := []byte("shared-secret")
+	v, err := verifier.New(verifier.Config{Secret: secret, Issuer: testIssuer})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	sign := func(claims jwt.MapClaims) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString(secret)
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return signed
+	}
+
+	if _, err := v.Verify(context.Background(), sign(accessClaims(jwt.MapClaims{"typ": nil}))); err != nil {
+		t.Fatalf("Verify untyped: %v", err)
+	}
+	if _, err := v.Verify(context.Background(), sign(accessClaims(jwt.MapClaims{"typ": "mfa_pending"}))); !errors.Is(err, verifier.ErrTokenInvalid) {
+		t.Fatalf("Verify mfa_pending: err = %v, want ErrTokenInvalid", err)
+	}
+	// RS256 токен не принимается верификатором с общим секретом
+	rs := signRS256(t, newRSAKey(t), "k1", accessClaims(nil))
+	if _, err := v.Verify(context.Background(), rs); !errors.Is(err, verifier.ErrTokenInvalid) {
+		t.Fatalf("Verify RS256: err = %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestJWKSCacheExpires(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PrivateKey{"k1": key})
+	v := newJWKSVerifier(t, server.URL, 50*time.Millisecond, time.Hour)
+	token := signRS256(t, key, "k1", accessClaims(nil))
+
+	for range 2 {
+		if _, err := v.Verify(context.Background(), token); err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+	}
+	if n := server.fetches.Load(); n != 1 {
+		t.Fatalf("JWKS fetches = %d, want 1 before TTL", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify after TTL: %v", err)
+	}
+	if n := server.fetches.Load(); n != 2 {
+		t.Fatalf("JWKS fetches = %d, want 2 after TTL", n)
+	}
+}
+
+func TestUnknownKidRefreshIsThrottled(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PrivateKey{"old": oldKey})
+	v := newJWKSVerifier(t, server.URL, time.Hour, 100*time.Millisecond)
+
+	if _, err := v.Verify(context.Background(), signRS256(t, oldKey, "old", accessClaims(nil))); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	// сервер ротировал ключ, но набор загружен только что: токены с новым kid не вызывают загрузок
+	server.setKeys(map[string]*rsa.PrivateKey{"old": oldKey, "new": newKey})
+	fresh := signRS256(t, newKey, "new", accessClaims(nil))
+	for range 3 {
+		if _, err := v.Verify(context.Background(), fresh); !errors.Is(err, verifier.ErrTokenInvalid) {
+			t.Fatalf("Verify within JWKSMinRefresh: err = %v, want ErrTokenInvalid", err)
+		}
+	}
+	if n := server.fetches.Load(); n != 1 {
+		t.Fatalf("JWKS fetches = %d, want 1 within JWKSMinRefresh", n)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	if _, err := v.Verify(context.Background(), fresh); err != nil {
+		t.Fatalf("Verify after JWKSMinRefresh: %v", err)
+	}
+	if n := server.fetches.Load(); n != 2 {
+		t.Fatalf("JWKS fetches = %d, want 2 after JWKSMinRefresh", n)
+	}
+}
+
+func TestJWKSFailureKeepsLoadedKeys(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PrivateKey{"k1": key})
+	v := newJWKSVerifier(t, server.URL, 30*time.Millisecond, time.Hour)
+	token := signRS256(t, key, "k1", accessClaims(nil))
+
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	server.failing.Store(true)
+	time.Sleep(40 * time.Millisecond)
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify with JWKS down: %v, want the cached key to be used", err)
+	}
+	if n := server.fetches.Load(); n != 2 {
+		t.Fatalf("JWKS fetches = %d, want a refresh attempt after TTL", n)
+	}
+	// неудачная попытка сдвигает срок, следующий токен не идёт в JWKS
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if n := server.fetches.Load(); n != 2 {
+		t.Fatalf("JWKS fetches = %d, want no retry right after a failure", n)
+	}
+}
+
+func TestJWKSUnavailableWithoutKeys(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PrivateKey{"k1": key})
+	server.failing.Store(true)
+	v := newJWKSVerifier(t, server.URL, time.Hour, time.Hour)
+
+	_, err := v.Verify(context.Background(), signRS256(t, key, "k1", accessClaims(nil)))
+	if !errors.Is(err, verifier.ErrUnavailable) {
+		t.Fatalf("Verify: err = %v, want ErrUnavailable", err)
+	}
+}
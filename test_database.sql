@@ -6,3 +6,187 @@ CREATE TABLE IF NOT EXISTS sls_auth_sessions (
     ip            inet      NOT NULL,
     PRIMARY KEY (user_id, refresh_hash)
 );
+
+CREATE TABLE IF NOT EXISTS sls_mfa_totp (
+    user_id         UUID        PRIMARY KEY,
+    secret          TEXT        NOT NULL,
+    confirmed       BOOLEAN     NOT NULL DEFAULT FALSE,
+    last_used_step  BIGINT      NOT NULL DEFAULT 0,
+    failed_attempts INT         NOT NULL DEFAULT 0,
+    locked_until    TIMESTAMPTZ NULL
+);
+
+CREATE TABLE IF NOT EXISTS sls_mfa_recovery_codes (
+    user_id   UUID NOT NULL,
+    code_hash TEXT NOT NULL,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS sls_passkey_credentials (
+    credential_id    BYTEA       PRIMARY KEY,
+    user_id          UUID        NOT NULL,
+    public_key       BYTEA       NOT NULL,
+    attestation_type TEXT        NOT NULL,
+    aaguid           BYTEA       NOT NULL,
+    sign_count       BIGINT      NOT NULL DEFAULT 0,
+    transports       TEXT[]      NOT NULL DEFAULT '{}',
+    flags            SMALLINT    NOT NULL DEFAULT 0,
+    attachment       TEXT        NOT NULL DEFAULT '',
+    clone_detected   BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at     TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS sls_passkey_credentials_user_id_idx ON sls_passkey_credentials (user_id);
+
+CREATE TABLE IF NOT EXISTS sls_webauthn_ceremonies (
+    id         UUID        PRIMARY KEY,
+    user_id    UUID        NULL,
+    data       BYTEA       NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE sls_auth_sessions ADD COLUMN IF NOT EXISTS client_id TEXT NULL;
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    client_id      TEXT        PRIMARY KEY,
+    name           TEXT        NOT NULL DEFAULT '',
+    secret_hash    TEXT        NOT NULL DEFAULT '',
+    redirect_uris  TEXT[]      NOT NULL DEFAULT '{}',
+    allowed_scopes TEXT[]      NOT NULL DEFAULT '{}',
+    grant_types    TEXT[]      NOT NULL DEFAULT '{}',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash             TEXT        PRIMARY KEY,
+    client_id             TEXT        NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    user_id               UUID        NOT NULL,
+    redirect_uri          TEXT        NOT NULL,
+    redirect_uri_provided BOOLEAN     NOT NULL,
+    scopes                TEXT[]      NOT NULL DEFAULT '{}',
+    code_challenge        TEXT        NOT NULL,
+    expires_at            TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE sls_auth_sessions ADD COLUMN IF NOT EXISTS session_id UUID NULL;
+ALTER TABLE sls_auth_sessions ADD COLUMN IF NOT EXISTS auth_time TIMESTAMPTZ NULL;
+ALTER TABLE sls_auth_sessions ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS users (
+    id             UUID        PRIMARY KEY,
+    email          TEXT        NOT NULL DEFAULT '',
+    email_verified BOOLEAN     NOT NULL DEFAULT FALSE,
+    name           TEXT        NOT NULL DEFAULT '',
+    given_name     TEXT        NOT NULL DEFAULT '',
+    family_name    TEXT        NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS post_logout_redirect_uris TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS nonce TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS auth_time TIMESTAMPTZ NULL;
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS federation_states (
+    state_hash    TEXT        PRIMARY KEY,
+    provider      TEXT        NOT NULL,
+    nonce         TEXT        NOT NULL,
+    code_verifier TEXT        NOT NULL,
+    link_user_id  UUID        NULL,
+    expires_at    TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE federation_states ADD COLUMN IF NOT EXISTS binding_hash TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS user_external_identities (
+    provider   TEXT        NOT NULL,
+    subject    TEXT        NOT NULL,
+    user_id    UUID        NOT NULL,
+    email      TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_external_identities_user_id_idx ON user_external_identities (user_id);
+
+-- NULL у сессий, созданных до появления scope
+ALTER TABLE sls_auth_sessions ADD COLUMN IF NOT EXISTS scopes TEXT[] NULL;
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           UUID        PRIMARY KEY,
+    name         TEXT        NOT NULL,
+    prefix       TEXT        NOT NULL UNIQUE,
+    secret_hash  TEXT        NOT NULL,
+    scopes       TEXT[]      NOT NULL DEFAULT '{}',
+    created_by   TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    revoked_at   TIMESTAMPTZ NULL,
+    last_used_at TIMESTAMPTZ NULL
+);
+
+CREATE TABLE IF NOT EXISTS auth_audit_log (
+    seq         BIGINT      PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    action      TEXT        NOT NULL,
+    actor       TEXT        NOT NULL DEFAULT '',
+    subject     TEXT        NOT NULL DEFAULT '',
+    ip          TEXT        NOT NULL DEFAULT '',
+    user_agent  TEXT        NOT NULL DEFAULT '',
+    outcome     TEXT        NOT NULL,
+    reason      TEXT        NOT NULL DEFAULT '',
+    prev_hash   TEXT        NOT NULL,
+    hash        TEXT        NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS auth_audit_log_subject_idx ON auth_audit_log (subject, seq);
+CREATE INDEX IF NOT EXISTS auth_audit_log_actor_idx ON auth_audit_log (actor, seq);
+CREATE INDEX IF NOT EXISTS auth_audit_log_occurred_at_idx ON auth_audit_log (occurred_at);
+
+-- журнал только дописывается, изменение и удаление записей запрещены на уровне базы
+CREATE OR REPLACE FUNCTION auth_audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'auth_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS auth_audit_log_append_only ON auth_audit_log;
+CREATE TRIGGER auth_audit_log_append_only
+    BEFORE UPDATE OR DELETE ON auth_audit_log
+    FOR EACH ROW EXECUTE FUNCTION auth_audit_log_append_only();
+
+DROP TRIGGER IF EXISTS auth_audit_log_no_truncate ON auth_audit_log;
+CREATE TRIGGER auth_audit_log_no_truncate
+    BEFORE TRUNCATE ON auth_audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION auth_audit_log_append_only();
+
+-- исходящие события для брокера, их отправляет relay сервиса. Тело хранится байтами:
+-- JSONB переупорядочивает ключи, а потребитель должен получить ровно то, что закодировал сервис
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id              TEXT PRIMARY KEY,
+    subject         TEXT        NOT NULL,
+    payload         BYTEA       NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    attempts        INT         NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error      TEXT,
+    sent_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS event_outbox_pending_idx ON event_outbox (next_attempt_at, created_at) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS event_outbox_sent_idx ON event_outbox (sent_at) WHERE sent_at IS NOT NULL;
+
+-- заголовки сообщения (атрибуты ce-* в binary режиме CloudEvents)
+ALTER TABLE event_outbox ADD COLUMN IF NOT EXISTS header JSONB NOT NULL DEFAULT '{}';
+
+-- версия схемы, её проверяет /readyz. Увеличивается вместе с изменениями этого файла
+CREATE TABLE IF NOT EXISTS schema_version (
+    id      BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    version INT     NOT NULL
+);
+
+INSERT INTO schema_version (version) VALUES (3)
+ON CONFLICT (id) DO UPDATE SET version = GREATEST(schema_version.version, EXCLUDED.version);